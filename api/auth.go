@@ -0,0 +1,117 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strings"
+)
+
+// Options configure where the API servers listen and who they let in. A request is
+// let in if it has the bearer token or, when a username is set, the username and
+// password using basic auth. If neither a token nor a username is set there's no auth.
+type Options struct {
+	Host     string
+	Token    string
+	Username string
+	Password string
+
+	// CertFile and KeyFile turn on TLS if they're set.
+	CertFile string
+	KeyFile  string
+}
+
+func (o Options) authEnabled() bool {
+	return o.Token != "" || o.Username != ""
+}
+
+func (o Options) useTLS() bool {
+	return o.CertFile != "" && o.KeyFile != ""
+}
+
+// authorized checks the value of an Authorization header.
+func (o Options) authorized(header string) bool {
+	if !o.authEnabled() {
+		return true
+	}
+	switch {
+	case strings.HasPrefix(header, "Bearer "):
+		return o.validToken(strings.TrimPrefix(header, "Bearer "))
+	case strings.HasPrefix(header, "Basic "):
+		if o.Username == "" {
+			return false
+		}
+		b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "Basic "))
+		if err != nil {
+			return false
+		}
+		creds := strings.SplitN(string(b), ":", 2)
+		if len(creds) != 2 {
+			return false
+		}
+		userOK := subtle.ConstantTimeCompare([]byte(creds[0]), []byte(o.Username)) == 1
+		passOK := subtle.ConstantTimeCompare([]byte(creds[1]), []byte(o.Password)) == 1
+		return userOK && passOK
+	default:
+		return false
+	}
+}
+
+func (o Options) validToken(token string) bool {
+	return o.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(o.Token)) == 1
+}
+
+// authHandler rejects requests which aren't authorized with a 401. Browsers can't set
+// headers on a websocket so the token can also be passed in the token query parameter
+// when opening one.
+func (o Options) authHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if o.authorized(r.Header.Get("Authorization")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.URL.Path == "/ws" && o.validToken(r.URL.Query().Get("token")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if o.Username != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="atomicswap"`)
+		}
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "unauthorized", nil)
+	})
+}
+
+// grpcAuth returns an Unauthenticated error if the authorization metadata is missing or wrong.
+func (o Options) grpcAuth(ctx context.Context) error {
+	if !o.authEnabled() {
+		return nil
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		for _, header := range md["authorization"] {
+			if o.authorized(header) {
+				return nil
+			}
+		}
+	}
+	return status.Error(codes.Unauthenticated, "unauthorized")
+}
+
+func (o Options) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := o.grpcAuth(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (o Options) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := o.grpcAuth(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func get(t *testing.T, url string, setAuth func(r *http.Request)) *http.Response {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if setAuth != nil {
+		setAuth(req)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func bearer(token string) func(r *http.Request) {
+	return func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func basic(username, password string) func(r *http.Request) {
+	return func(r *http.Request) {
+		r.SetBasicAuth(username, password)
+	}
+}
+
+func TestAuthRejected(t *testing.T) {
+	ts, cleanup := newTestServer(t, Options{Token: "secret", Username: "alice", Password: "hunter2"})
+	defer cleanup()
+
+	tests := []struct {
+		name    string
+		path    string
+		setAuth func(r *http.Request)
+	}{
+		{"no credentials", "/orderbook", nil},
+		{"wrong token", "/orderbook", bearer("guess")},
+		{"empty token", "/orderbook", bearer("")},
+		{"wrong password", "/orderbook", basic("alice", "guess")},
+		{"wrong username", "/orderbook", basic("bob", "hunter2")},
+		{"unknown scheme", "/orderbook", func(r *http.Request) { r.Header.Set("Authorization", "Digest secret") }},
+		// Only the websocket takes the token as a query parameter
+		{"token in the query", "/orderbook?token=secret", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := get(t, ts.URL+test.path, test.setAuth)
+			if h := resp.Header.Get("WWW-Authenticate"); h != `Basic realm="atomicswap"` {
+				t.Errorf("got WWW-Authenticate %q", h)
+			}
+			checkError(t, resp, http.StatusUnauthorized, CodeUnauthorized, nil)
+		})
+	}
+
+	// The error is the same for routes which don't exist so they can't be probed
+	resp := get(t, ts.URL+"/nothing/here", nil)
+	checkError(t, resp, http.StatusUnauthorized, CodeUnauthorized, nil)
+}
+
+func TestAuthAccepted(t *testing.T) {
+	ts, cleanup := newTestServer(t, Options{Token: "secret", Username: "alice", Password: "hunter2"})
+	defer cleanup()
+
+	for _, setAuth := range []func(r *http.Request){bearer("secret"), basic("alice", "hunter2")} {
+		resp := get(t, ts.URL+"/orderbook", setAuth)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+	}
+}
+
+func TestAuthTokenOnly(t *testing.T) {
+	ts, cleanup := newTestServer(t, Options{Token: "secret"})
+	defer cleanup()
+
+	// Without a username basic auth is never accepted and browsers aren't asked for it
+	resp := get(t, ts.URL+"/orderbook", basic("", ""))
+	if h := resp.Header.Get("WWW-Authenticate"); h != "" {
+		t.Errorf("got WWW-Authenticate %q without a username", h)
+	}
+	checkError(t, resp, http.StatusUnauthorized, CodeUnauthorized, nil)
+
+	resp = get(t, ts.URL+"/orderbook", bearer("secret"))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestAuthDisabled(t *testing.T) {
+	ts, cleanup := newTestServer(t, Options{})
+	defer cleanup()
+
+	resp := get(t, ts.URL+"/orderbook", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestWebsocketToken(t *testing.T) {
+	ts, cleanup := newTestServer(t, Options{Token: "secret", Username: "alice", Password: "hunter2"})
+	defer cleanup()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?token=secret", nil)
+	if err != nil {
+		t.Fatalf("websocket with the token in the query rejected: %s", err)
+	}
+	conn.Close()
+
+	header := make(http.Header)
+	header.Set("Authorization", "Bearer secret")
+	conn, _, err = websocket.DefaultDialer.Dial(wsURL, header)
+	if err != nil {
+		t.Fatalf("websocket with the token in the header rejected: %s", err)
+	}
+	conn.Close()
+
+	for _, query := range []string{"", "?token=guess", "?token="} {
+		_, resp, err := websocket.DefaultDialer.Dial(wsURL+query, nil)
+		if err == nil {
+			t.Errorf("websocket opened with query %q", query)
+			continue
+		}
+		if resp == nil {
+			t.Fatal(err)
+		}
+		checkError(t, resp, http.StatusUnauthorized, CodeUnauthorized, nil)
+	}
+
+	// A bad events list is reported before the upgrade
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL+"?token=secret&events=nonsense", nil)
+	if err == nil {
+		t.Fatal("websocket opened with an unknown event type")
+	}
+	checkError(t, resp, http.StatusBadRequest, CodeInvalidRequest, nil)
+}
+
+func TestGRPCAuth(t *testing.T) {
+	opts := Options{Token: "secret", Username: "alice", Password: "hunter2"}
+	withAuth := func(header string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", header))
+	}
+
+	rejected := []context.Context{
+		context.Background(),
+		withAuth("Bearer guess"),
+		withAuth("Basic YWxpY2U6Z3Vlc3M="), // alice:guess
+	}
+	for i, ctx := range rejected {
+		err := opts.grpcAuth(ctx)
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%d: got error %v, want Unauthenticated", i, err)
+		}
+	}
+
+	accepted := []context.Context{
+		withAuth("Bearer secret"),
+		withAuth("Basic YWxpY2U6aHVudGVyMg=="), // alice:hunter2
+	}
+	for i, ctx := range accepted {
+		if err := opts.grpcAuth(ctx); err != nil {
+			t.Errorf("%d: got error %v", i, err)
+		}
+	}
+
+	if err := (Options{}).grpcAuth(context.Background()); err != nil {
+		t.Errorf("got error %v with auth turned off", err)
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/cpacia/atomicswap/core"
+	ob "github.com/cpacia/atomicswap/orderbook"
+	"net/http"
+)
+
+// Error codes returned in ErrorResponse. They let clients tell errors apart without
+// matching on the message.
+const (
+	CodeInvalidRequest = "invalid_request"
+	CodeInvalidOrder   = "invalid_order"
+	CodeInvalidFill    = "invalid_fill"
+	CodeNotFound       = "not_found"
+	CodeNotFilled      = "not_filled"
+	CodeConflict       = "conflict"
+	CodeForbidden      = "forbidden"
+	CodeUnauthorized   = "unauthorized"
+	CodeInternal       = "internal_error"
+)
+
+// ErrorResponse is the body of every error returned by the JSON API. Details holds
+// extra information about the error, such as which request fields were invalid.
+type ErrorResponse struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// writeJSON writes v as the response body with the status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	ser, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error(), nil)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(ser)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string, details map[string]string) {
+	ser, _ := json.MarshalIndent(ErrorResponse{Code: code, Message: message, Details: details}, "", "    ")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(ser)
+}
+
+// writeNodeError picks the status code and error code for an error returned by the node.
+// Errors we don't know about are internal errors.
+func writeNodeError(w http.ResponseWriter, err error) {
+	switch err.(type) {
+	case ob.FillError:
+		writeError(w, http.StatusBadRequest, CodeInvalidFill, err.Error(), nil)
+		return
+	}
+	switch err {
+	case ob.ErrOrderNotFound:
+		writeError(w, http.StatusNotFound, CodeNotFound, err.Error(), nil)
+	case core.ErrNotMyOrder, core.ErrOwnOrder:
+		writeError(w, http.StatusForbidden, CodeForbidden, err.Error(), nil)
+	case ob.ErrOrderTooSmall, core.ErrInvalidExpiry:
+		writeError(w, http.StatusBadRequest, CodeInvalidOrder, err.Error(), nil)
+	case core.ErrNotFilled:
+		writeError(w, http.StatusConflict, CodeNotFilled, err.Error(), nil)
+	case core.ErrOrderBusy:
+		writeError(w, http.StatusConflict, CodeConflict, err.Error(), nil)
+	default:
+		log.Error(err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error(), nil)
+	}
+}
+
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, CodeNotFound, "no such endpoint "+r.URL.Path, nil)
+}
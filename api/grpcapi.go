@@ -0,0 +1,322 @@
+package api
+
+import (
+	"fmt"
+	"github.com/cpacia/atomicswap/core"
+	ob "github.com/cpacia/atomicswap/orderbook"
+	"github.com/cpacia/atomicswap/pb"
+	"github.com/cpacia/atomicswap/swap"
+	"github.com/golang/protobuf/ptypes"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"net"
+	"strconv"
+	"time"
+)
+
+// GRPCServer serves the gRPC control API. It's a typed alternative to the JSON API.
+type GRPCServer struct {
+	node   *core.AtomicSwapNode
+	server *grpc.Server
+	opts   Options
+}
+
+// NewGRPCServer returns a gRPC server using the same auth and TLS settings as the JSON
+// API. Clients put the bearer token or basic auth in the authorization metadata.
+func NewGRPCServer(node *core.AtomicSwapNode, opts Options) (*GRPCServer, error) {
+	serverOpts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(opts.unaryInterceptor),
+		grpc.StreamInterceptor(opts.streamInterceptor),
+	}
+	if opts.useTLS() {
+		creds, err := credentials.NewServerTLSFromFile(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		serverOpts = append(serverOpts, grpc.Creds(creds))
+	}
+	g := &GRPCServer{
+		node:   node,
+		server: grpc.NewServer(serverOpts...),
+		opts:   opts,
+	}
+	pb.RegisterAtomicSwapServer(g.server, g)
+	return g, nil
+}
+
+// Serve listens on the port and serves requests until the server is stopped.
+func (g *GRPCServer) Serve(port int) error {
+	lis, err := net.Listen("tcp", net.JoinHostPort(g.opts.Host, strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	return g.server.Serve(lis)
+}
+
+func (g *GRPCServer) PlaceOrder(ctx context.Context, req *pb.PlaceOrderRequest) (*pb.PlaceOrderResponse, error) {
+	order := core.OrderRequest{
+		Quantity:    req.Quantity,
+		Price:       req.Price,
+		BuyBTC:      req.BuyBTC,
+		MinQuantity: req.MinQuantity,
+	}
+	switch req.TimeInForce {
+	case pb.TimeInForce_GTC:
+		order.TimeInForce = core.GoodTilCancelled
+	case pb.TimeInForce_GTT:
+		order.TimeInForce = core.GoodTilTime
+	case pb.TimeInForce_IOC:
+		order.TimeInForce = core.ImmediateOrCancel
+	case pb.TimeInForce_FOK:
+		order.TimeInForce = core.FillOrKill
+	default:
+		return nil, status.Error(codes.InvalidArgument, "unknown time in force")
+	}
+	if req.Expiry != nil {
+		expiry, err := ptypes.Timestamp(req.Expiry)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		order.Expiry = expiry
+	}
+	result, err := g.node.PlaceOrder(order)
+	if result == nil {
+		return nil, grpcError(err)
+	}
+	// An immediate order may have started some swaps before failing. Report them
+	// rather than the error since they can't be undone.
+	if err != nil {
+		log.Error(err)
+	}
+	resp := &pb.PlaceOrderResponse{OrderID: result.OrderID}
+	if result.Order != nil {
+		resp.Order = g.orderInfo(*result.Order)
+	}
+	for _, s := range result.Swaps {
+		resp.Swaps = append(resp.Swaps, swapInfoPB(swapInfo(s)))
+	}
+	return resp, nil
+}
+
+func (g *GRPCServer) AmendOrder(ctx context.Context, req *pb.AmendOrderRequest) (*pb.AmendOrderResponse, error) {
+	var expiry time.Time
+	if req.Expiry != nil {
+		var err error
+		expiry, err = ptypes.Timestamp(req.Expiry)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	orderID, order, err := g.node.ReplaceOrder(req.OrderID, req.Quantity, req.Price, req.MinQuantity, expiry)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &pb.AmendOrderResponse{OrderID: orderID, Order: g.orderInfo(order)}, nil
+}
+
+func (g *GRPCServer) CloseOrder(ctx context.Context, req *pb.CloseOrderRequest) (*pb.CloseOrderResponse, error) {
+	if err := g.node.CloseOrder(req.OrderID); err != nil {
+		return nil, grpcError(err)
+	}
+	return &pb.CloseOrderResponse{}, nil
+}
+
+func (g *GRPCServer) ListOrders(ctx context.Context, req *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
+	resp := new(pb.ListOrdersResponse)
+	if req.Mine {
+		for _, o := range g.node.OrderBook().MyOrders() {
+			resp.Orders = append(resp.Orders, g.orderInfo(o))
+		}
+		return resp, nil
+	}
+	for _, o := range g.node.OrderBook().OpenOrders() {
+		resp.Orders = append(resp.Orders, g.orderInfo(o))
+	}
+	return resp, nil
+}
+
+func (g *GRPCServer) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.OrderInfo, error) {
+	order, _, err := g.node.OrderBook().GetOrder(req.OrderID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	order.OrderID = req.OrderID
+	return g.orderInfo(order), nil
+}
+
+func (g *GRPCServer) TakeOrder(ctx context.Context, req *pb.TakeOrderRequest) (*pb.SwapInfo, error) {
+	s, err := g.node.TakeOrder(req.OrderID, req.Quantity)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return swapInfoPB(swapInfo(s)), nil
+}
+
+func (g *GRPCServer) ListSwaps(ctx context.Context, req *pb.ListSwapsRequest) (*pb.ListSwapsResponse, error) {
+	resp := new(pb.ListSwapsResponse)
+	for _, s := range g.node.Swaps() {
+		resp.Swaps = append(resp.Swaps, swapInfoPB(swapInfo(s)))
+	}
+	return resp, nil
+}
+
+func (g *GRPCServer) WalletBalance(ctx context.Context, req *pb.WalletBalanceRequest) (*pb.WalletBalanceResponse, error) {
+	coins := swap.Coins
+	if req.Coin != "" {
+		coin, err := swap.ParseCoin(req.Coin)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("unknown coin %s", req.Coin))
+		}
+		coins = []swap.Coin{coin}
+	}
+	resp := new(pb.WalletBalanceResponse)
+	for _, coin := range coins {
+		confirmed, unconfirmed, err := g.node.Balance(coin)
+		if err != nil {
+			// Only an error if the caller asked for this coin
+			if req.Coin != "" {
+				return nil, status.Error(codes.FailedPrecondition, err.Error())
+			}
+			continue
+		}
+		resp.Balances = append(resp.Balances, &pb.WalletBalanceResponse_Balance{
+			Coin:        coin.String(),
+			Confirmed:   confirmed,
+			Unconfirmed: unconfirmed,
+		})
+	}
+	return resp, nil
+}
+
+func (g *GRPCServer) ListPeers(ctx context.Context, req *pb.ListPeersRequest) (*pb.ListPeersResponse, error) {
+	resp := new(pb.ListPeersResponse)
+	for _, p := range g.node.Peers() {
+		resp.Peers = append(resp.Peers, &pb.PeerInfo{
+			PeerID: p.Pretty(),
+			Score:  int32(g.node.Scorer().Score(p)),
+		})
+	}
+	return resp, nil
+}
+
+func (g *GRPCServer) SubscribeOrderBook(req *pb.SubscribeOrderBookRequest, stream pb.AtomicSwap_SubscribeOrderBookServer) error {
+	// Subscribe before taking the snapshot so we don't miss anything in between
+	events, unsubscribe := g.node.SubscribeEvents()
+	defer unsubscribe()
+
+	if req.Snapshot {
+		for _, o := range g.node.OrderBook().OpenOrders() {
+			if err := stream.Send(&pb.OrderBookUpdate{Type: pb.OrderBookUpdate_Added, Order: g.orderInfo(o)}); err != nil {
+				return err
+			}
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			var t pb.OrderBookUpdate_Type
+			switch e.Type {
+			case core.EventOrderAdded:
+				t = pb.OrderBookUpdate_Added
+			case core.EventOrderClosed:
+				t = pb.OrderBookUpdate_Closed
+			case core.EventOrderFilled:
+				t = pb.OrderBookUpdate_Filled
+			case core.EventOrderAmended:
+				t = pb.OrderBookUpdate_Amended
+			default:
+				continue
+			}
+			if err := stream.Send(&pb.OrderBookUpdate{Type: t, Order: g.orderInfo(*e.Order)}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (g *GRPCServer) SubscribeSwaps(req *pb.SubscribeSwapsRequest, stream pb.AtomicSwap_SubscribeSwapsServer) error {
+	events, unsubscribe := g.node.SubscribeEvents()
+	defer unsubscribe()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if e.Type != core.EventSwapState {
+				continue
+			}
+			s := swapInfo(e.Swap)
+			s.State = e.State.String()
+			if err := stream.Send(swapInfoPB(s)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (g *GRPCServer) orderInfo(o ob.LimitOrder) *pb.OrderInfo {
+	id := o.OrderID
+	if id == "" {
+		if cid, err := o.ID(); err == nil {
+			id = cid.String()
+		}
+	}
+	return &pb.OrderInfo{
+		OrderID:     id,
+		PeerID:      o.PeerID,
+		BuyBTC:      o.BuyBTC,
+		Quantity:    o.Quantity,
+		Remaining:   o.Remaining,
+		MinQuantity: o.MinQuantity,
+		Price:       o.Price,
+		Expiry:      o.Expiry,
+		Mine:        o.PeerID == g.node.PeerID().Pretty(),
+	}
+}
+
+func swapInfoPB(s SwapResponse) *pb.SwapInfo {
+	return &pb.SwapInfo{
+		SwapID:        s.SwapID,
+		OrderID:       s.OrderID,
+		Role:          s.Role,
+		State:         s.State,
+		Counterparty:  s.Counterparty,
+		SendCoin:      s.SendCoin,
+		SendAmount:    s.SendAmount,
+		ReceiveCoin:   s.ReceiveCoin,
+		ReceiveAmount: s.ReceiveAmount,
+		RefundTx:      s.RefundTx,
+		RefundError:   s.RefundError,
+	}
+}
+
+// grpcError gives an error returned by the node the matching gRPC status code, the
+// same way writeNodeError picks an HTTP status.
+func grpcError(err error) error {
+	if _, ok := err.(ob.FillError); ok {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	switch err {
+	case ob.ErrOrderNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case core.ErrNotMyOrder, core.ErrOwnOrder:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case ob.ErrOrderTooSmall, core.ErrInvalidExpiry:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case core.ErrNotFilled, core.ErrOrderBusy:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return err
+	}
+}
@@ -4,12 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/cpacia/atomicswap/core"
+	"github.com/cpacia/atomicswap/net/score"
 	ob "github.com/cpacia/atomicswap/orderbook"
+	"github.com/cpacia/atomicswap/swap"
 	"github.com/gorilla/mux"
+	"github.com/libp2p/go-libp2p-peer"
+	"github.com/op/go-logging"
+	"net"
 	"net/http"
-	"strconv"
 	"path"
-	"github.com/op/go-logging"
+	"strconv"
+	"time"
 )
 
 var log = logging.MustGetLogger("jsonapi")
@@ -17,55 +22,192 @@ var log = logging.MustGetLogger("jsonapi")
 type APIServer struct {
 	node   *core.AtomicSwapNode
 	router *mux.Router
+	opts   Options
 }
 
-func NewAPIServer(node *core.AtomicSwapNode) *APIServer {
+func NewAPIServer(node *core.AtomicSwapNode, opts Options) *APIServer {
 	s := &APIServer{
 		node:   node,
 		router: mux.NewRouter(),
+		opts:   opts,
 	}
 	s.router.HandleFunc("/limitorder", s.handleLimitOrder).Methods("POST")
 	s.router.PathPrefix("/closeorder").Methods("POST").Handler(http.HandlerFunc(s.handleCloseOrder))
+	s.router.HandleFunc("/amendorder/{id}", s.handleAmendOrder).Methods("POST")
 	s.router.HandleFunc("/orderbook", s.handleOrderBook).Methods("GET")
+	s.router.HandleFunc("/orderbook/stats", s.handleOrderBookStats).Methods("GET")
+	s.router.HandleFunc("/orders/mine", s.handleMyOrders).Methods("GET")
+	s.router.HandleFunc("/order/{id}", s.handleGetOrder).Methods("GET")
+	s.router.PathPrefix("/takeorder").Methods("POST").Handler(http.HandlerFunc(s.handleTakeOrder))
+	s.router.HandleFunc("/swaps", s.handleSwaps).Methods("GET")
+	s.router.HandleFunc("/ws", s.handleWebsocket).Methods("GET")
+	s.router.HandleFunc("/peers", s.handlePeers).Methods("GET")
+	s.router.HandleFunc("/balance", s.handleBalance).Methods("GET")
+	s.router.HandleFunc("/bans", s.handleBans).Methods("GET")
+	s.router.PathPrefix("/unban").Methods("POST").Handler(http.HandlerFunc(s.handleUnban))
+	s.router.NotFoundHandler = http.HandlerFunc(notFound)
 	return s
 }
 
-func (a *APIServer) Serve(port int) {
-	http.ListenAndServe(":"+strconv.Itoa(port), a.router)
+// Serve listens on the host and port from the options. Every request has to be authorized.
+func (a *APIServer) Serve(port int) error {
+	addr := net.JoinHostPort(a.opts.Host, strconv.Itoa(port))
+	handler := a.opts.authHandler(a.router)
+	if a.opts.useTLS() {
+		return http.ListenAndServeTLS(addr, a.opts.CertFile, a.opts.KeyFile, handler)
+	}
+	return http.ListenAndServe(addr, handler)
+}
+
+// OrderResponse is returned when an order is placed, amended, closed or looked up. The
+// order is left out when it's closed. Immediate or cancel and fill or kill orders
+// never rest in the book so they have no ID, only the swaps they started.
+type OrderResponse struct {
+	OrderID  string         `json:"orderID,omitempty"`
+	Mine     bool           `json:"mine,omitempty"`
+	Order    *ob.LimitOrder `json:"order,omitempty"`
+	Replaced string         `json:"replaced,omitempty"`
+	Swaps    []SwapResponse `json:"swaps,omitempty"`
 }
 
 func (a *APIServer) handleLimitOrder(w http.ResponseWriter, r *http.Request) {
+	// Pointers so we can tell a missing field from a zero
 	type order struct {
-		Quantity uint64 `json:"quantity"`
-		Price    uint64 `json:"price"`
-		BuyBTC   bool   `json:"buyBTC"`
+		Quantity    *uint64    `json:"quantity"`
+		Price       *uint64    `json:"price"`
+		BuyBTC      *bool      `json:"buyBTC"`
+		MinQuantity uint64     `json:"minQuantity"`
+		TimeInForce string     `json:"timeInForce"`
+		Expiry      *time.Time `json:"expiry"`
 	}
 	var o order
 	decoder := json.NewDecoder(r.Body)
 	err := decoder.Decode(&o)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid JSON: "+err.Error(), nil)
 		return
 	}
-	err = a.node.PublishLimitOrder(o.Quantity, o.Price, o.BuyBTC)
+	details := make(map[string]string)
+	tif, err := core.ParseTimeInForce(o.TimeInForce)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		details["timeInForce"] = "must be GTC, GTT, IOC or FOK"
+	}
+	resting := tif == core.GoodTilCancelled || tif == core.GoodTilTime
+	a.validateOrder(o.Quantity, o.Price, o.MinQuantity, resting, details)
+	if o.BuyBTC == nil {
+		details["buyBTC"] = "missing"
+	}
+	if tif == core.GoodTilTime && o.Expiry == nil {
+		details["expiry"] = "missing"
+	} else if tif != core.GoodTilTime && o.Expiry != nil {
+		details["expiry"] = "only used with GTT"
+	}
+	if len(details) > 0 {
+		writeError(w, http.StatusBadRequest, CodeInvalidOrder, "invalid order", details)
+		return
+	}
+	req := core.OrderRequest{
+		Quantity:    *o.Quantity,
+		Price:       *o.Price,
+		BuyBTC:      *o.BuyBTC,
+		MinQuantity: o.MinQuantity,
+		TimeInForce: tif,
+	}
+	if o.Expiry != nil {
+		req.Expiry = *o.Expiry
+	}
+	result, err := a.node.PlaceOrder(req)
+	if result == nil {
+		writeNodeError(w, err)
 		return
 	}
+	// An immediate order may have started some swaps before failing. Report them
+	// rather than the error since they can't be undone.
+	if err != nil {
+		log.Error(err)
+	}
+	resp := OrderResponse{OrderID: result.OrderID, Mine: true, Order: result.Order}
+	for _, s := range result.Swaps {
+		resp.Swaps = append(resp.Swaps, swapInfo(s))
+	}
+	status := http.StatusOK
+	if resp.OrderID != "" {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, resp)
+}
+
+// handleAmendOrder replaces one of our orders with one at a new quantity or price. The
+// side stays the same. Without an expiry the new order gets the longest one allowed.
+func (a *APIServer) handleAmendOrder(w http.ResponseWriter, r *http.Request) {
+	orderID := mux.Vars(r)["id"]
+	type amend struct {
+		Quantity    *uint64    `json:"quantity"`
+		Price       *uint64    `json:"price"`
+		MinQuantity uint64     `json:"minQuantity"`
+		Expiry      *time.Time `json:"expiry"`
+	}
+	var o amend
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&o); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid JSON: "+err.Error(), nil)
+		return
+	}
+	details := make(map[string]string)
+	a.validateOrder(o.Quantity, o.Price, o.MinQuantity, true, details)
+	if len(details) > 0 {
+		writeError(w, http.StatusBadRequest, CodeInvalidOrder, "invalid order", details)
+		return
+	}
+	var expiry time.Time
+	if o.Expiry != nil {
+		expiry = *o.Expiry
+	}
+	newID, lo, err := a.node.ReplaceOrder(orderID, *o.Quantity, *o.Price, o.MinQuantity, expiry)
+	if err != nil {
+		writeNodeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, OrderResponse{OrderID: newID, Mine: true, Order: &lo, Replaced: orderID})
+}
+
+// validateOrder adds an entry to details for each invalid field. Orders which will
+// rest in the book have to meet the book's minimum quantity.
+func (a *APIServer) validateOrder(quantity, price *uint64, minQuantity uint64, resting bool, details map[string]string) {
+	minimum := a.node.OrderBook().Limits().MinQuantity
+	switch {
+	case quantity == nil:
+		details["quantity"] = "missing"
+	case *quantity == 0:
+		details["quantity"] = "must be greater than zero"
+	case resting && *quantity < minimum:
+		details["quantity"] = fmt.Sprintf("must be at least %d", minimum)
+	case minQuantity > *quantity:
+		details["minQuantity"] = "must not be more than quantity"
+	}
+	if price == nil {
+		details["price"] = "missing"
+	} else if *price == 0 {
+		details["price"] = "must be greater than zero"
+	}
 }
 
 func (a *APIServer) handleCloseOrder(w http.ResponseWriter, r *http.Request) {
 	_, orderID := path.Split(r.URL.Path)
+	if orderID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "missing order ID", nil)
+		return
+	}
 	err := a.node.CloseOrder(orderID)
 	if err != nil {
-		log.Error(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeNodeError(w, err)
 		return
 	}
+	writeJSON(w, http.StatusOK, OrderResponse{OrderID: orderID})
 }
 
 func (a *APIServer) handleOrderBook(w http.ResponseWriter, r *http.Request) {
-	var orders []ob.LimitOrder
+	orders := []ob.LimitOrder{}
 	for _, o := range a.node.OrderBook().OpenOrders() {
 		id, err := o.ID()
 		if err != nil {
@@ -74,10 +216,155 @@ func (a *APIServer) handleOrderBook(w http.ResponseWriter, r *http.Request) {
 		o.OrderID = id.String()
 		orders = append(orders, o)
 	}
-	ser, err := json.MarshalIndent(orders, "", "    ")
+	writeJSON(w, http.StatusOK, orders)
+}
+
+// handleMyOrders returns our own open orders.
+func (a *APIServer) handleMyOrders(w http.ResponseWriter, r *http.Request) {
+	orders := []ob.LimitOrder{}
+	for _, o := range a.node.OrderBook().MyOrders() {
+		id, err := o.ID()
+		if err != nil {
+			continue
+		}
+		o.OrderID = id.String()
+		orders = append(orders, o)
+	}
+	writeJSON(w, http.StatusOK, orders)
+}
+
+// handleGetOrder returns an order from the book, ours or another peer's.
+func (a *APIServer) handleGetOrder(w http.ResponseWriter, r *http.Request) {
+	orderID := mux.Vars(r)["id"]
+	order, mine, err := a.node.OrderBook().GetOrder(orderID)
+	if err != nil {
+		writeNodeError(w, err)
+		return
+	}
+	order.OrderID = orderID
+	writeJSON(w, http.StatusOK, OrderResponse{OrderID: orderID, Mine: mine, Order: &order})
+}
+
+func (a *APIServer) handleOrderBookStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.node.OrderBook().Stats())
+}
+
+func (a *APIServer) handleTakeOrder(w http.ResponseWriter, r *http.Request) {
+	_, orderID := path.Split(r.URL.Path)
+	if orderID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "missing order ID", nil)
+		return
+	}
+	var quantity uint64
+	if q := r.URL.Query().Get("quantity"); q != "" {
+		var err error
+		quantity, err = strconv.ParseUint(q, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid quantity",
+				map[string]string{"quantity": "must be a whole number of satoshis"})
+			return
+		}
+	}
+	s, err := a.node.TakeOrder(orderID, quantity)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeNodeError(w, err)
 		return
 	}
-	fmt.Fprint(w, string(ser))
+	writeJSON(w, http.StatusOK, swapInfo(s))
+}
+
+func (a *APIServer) handleSwaps(w http.ResponseWriter, r *http.Request) {
+	swaps := []SwapResponse{}
+	for _, s := range a.node.Swaps() {
+		swaps = append(swaps, swapInfo(s))
+	}
+	writeJSON(w, http.StatusOK, swaps)
+}
+
+type BalanceResponse struct {
+	Coin        string `json:"coin"`
+	Confirmed   int64  `json:"confirmed"`
+	Unconfirmed int64  `json:"unconfirmed"`
+}
+
+// handleBalance returns the balance of each of our wallets.
+func (a *APIServer) handleBalance(w http.ResponseWriter, r *http.Request) {
+	balances := []BalanceResponse{}
+	for _, coin := range swap.Coins {
+		confirmed, unconfirmed, err := a.node.Balance(coin)
+		if err != nil {
+			continue
+		}
+		balances = append(balances, BalanceResponse{coin.String(), confirmed, unconfirmed})
+	}
+	writeJSON(w, http.StatusOK, balances)
+}
+
+type PeerResponse struct {
+	PeerID string `json:"peerID"`
+	Score  int    `json:"score"`
+}
+
+func (a *APIServer) handlePeers(w http.ResponseWriter, r *http.Request) {
+	peers := []PeerResponse{}
+	for _, p := range a.node.Peers() {
+		peers = append(peers, PeerResponse{PeerID: p.Pretty(), Score: a.node.Scorer().Score(p)})
+	}
+	writeJSON(w, http.StatusOK, peers)
+}
+
+func (a *APIServer) handleBans(w http.ResponseWriter, r *http.Request) {
+	bans := a.node.Scorer().Bans()
+	if bans == nil {
+		bans = []score.Ban{}
+	}
+	writeJSON(w, http.StatusOK, bans)
+}
+
+func (a *APIServer) handleUnban(w http.ResponseWriter, r *http.Request) {
+	_, peerID := path.Split(r.URL.Path)
+	p, err := peer.IDB58Decode(peerID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid peer ID",
+			map[string]string{"peerID": err.Error()})
+		return
+	}
+	if err := a.node.Scorer().Unban(p); err != nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, err.Error(), nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, PeerResponse{PeerID: p.Pretty(), Score: a.node.Scorer().Score(p)})
+}
+
+type SwapResponse struct {
+	SwapID        string `json:"swapID"`
+	OrderID       string `json:"orderID"`
+	Role          string `json:"role"`
+	State         string `json:"state"`
+	Counterparty  string `json:"counterparty"`
+	SendCoin      string `json:"sendCoin"`
+	SendAmount    int64  `json:"sendAmount"`
+	ReceiveCoin   string `json:"receiveCoin"`
+	ReceiveAmount int64  `json:"receiveAmount"`
+	RefundTx      string `json:"refundTx,omitempty"`
+	RefundError   string `json:"refundError,omitempty"`
+}
+
+func swapInfo(s *core.Swap) SwapResponse {
+	resp := SwapResponse{
+		SwapID:        s.ID,
+		OrderID:       s.OrderID,
+		Role:          s.Role.String(),
+		State:         s.State.String(),
+		Counterparty:  s.Counterparty.Pretty(),
+		SendCoin:      s.SendCoin.String(),
+		SendAmount:    s.SendAmount,
+		ReceiveCoin:   s.ReceiveCoin.String(),
+		ReceiveAmount: s.ReceiveAmount,
+		RefundError:   s.RefundError,
+	}
+	if s.RefundTx != nil {
+		resp.RefundTx = s.RefundTx.TxHash().String()
+	}
+	return resp
 }
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/cpacia/atomicswap/core"
+	"github.com/cpacia/atomicswap/repo"
+	"github.com/cpacia/atomicswap/swap"
+	"github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	"github.com/libp2p/go-libp2p-peer"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testHost implements just enough of a host to create a node. The API tests never
+// talk to other peers.
+type testHost struct {
+	host.Host
+	id peer.ID
+}
+
+func (h *testHost) ID() peer.ID {
+	return h.id
+}
+
+func (h *testHost) Network() inet.Network {
+	return testNetwork{}
+}
+
+type testNetwork struct {
+	inet.Network
+}
+
+func (testNetwork) Notify(inet.Notifiee) {}
+
+// newTestServer starts the JSON API for a fresh node, wrapped in the same auth
+// handler Serve uses.
+func newTestServer(t *testing.T, opts Options) (*httptest.Server, func()) {
+	dir, err := ioutil.TempDir("", "atomicswap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := repo.NewRepo(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPrivateKey(r.PrivKey())
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	node := core.NewAtomicSwapNode(r, &testHost{id: id}, nil, nil, swap.RegTest)
+	s := NewAPIServer(node, opts)
+	ts := httptest.NewServer(s.opts.authHandler(s.router))
+	return ts, func() {
+		ts.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// checkError checks the status code and the body of an error response.
+func checkError(t *testing.T, resp *http.Response, status int, code string, details map[string]string) {
+	defer resp.Body.Close()
+	if resp.StatusCode != status {
+		t.Errorf("got status %d, want %d", resp.StatusCode, status)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("got content type %q, want application/json", ct)
+	}
+	var e ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		t.Fatalf("error response isn't JSON: %s", err)
+	}
+	if e.Code != code {
+		t.Errorf("got error code %q, want %q", e.Code, code)
+	}
+	if e.Message == "" {
+		t.Error("error response has no message")
+	}
+	if !reflect.DeepEqual(e.Details, details) {
+		t.Errorf("got details %v, want %v", e.Details, details)
+	}
+}
+
+func post(t *testing.T, url, body string) *http.Response {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestLimitOrderValidation(t *testing.T) {
+	ts, cleanup := newTestServer(t, Options{})
+	defer cleanup()
+
+	tests := []struct {
+		name    string
+		body    string
+		details map[string]string
+	}{
+		{
+			name: "empty",
+			body: `{}`,
+			details: map[string]string{
+				"quantity": "missing",
+				"price":    "missing",
+				"buyBTC":   "missing",
+			},
+		},
+		{
+			name: "zeros",
+			body: `{"quantity": 0, "price": 0, "buyBTC": true}`,
+			details: map[string]string{
+				"quantity": "must be greater than zero",
+				"price":    "must be greater than zero",
+			},
+		},
+		{
+			name: "below the book minimum",
+			body: `{"quantity": 1, "price": 1000000000, "buyBTC": true}`,
+			details: map[string]string{
+				"quantity": "must be at least 10000",
+			},
+		},
+		{
+			name: "min quantity above quantity",
+			body: `{"quantity": 1000000, "price": 1000000000, "buyBTC": true, "minQuantity": 2000000}`,
+			details: map[string]string{
+				"minQuantity": "must not be more than quantity",
+			},
+		},
+		{
+			name: "unknown time in force",
+			body: `{"quantity": 1000000, "price": 1000000000, "buyBTC": true, "timeInForce": "DAY"}`,
+			details: map[string]string{
+				"timeInForce": "must be GTC, GTT, IOC or FOK",
+			},
+		},
+		{
+			name: "GTT without an expiry",
+			body: `{"quantity": 1000000, "price": 1000000000, "buyBTC": true, "timeInForce": "GTT"}`,
+			details: map[string]string{
+				"expiry": "missing",
+			},
+		},
+		{
+			name: "expiry without GTT",
+			body: `{"quantity": 1000000, "price": 1000000000, "buyBTC": true, "expiry": "2030-01-01T00:00:00Z"}`,
+			details: map[string]string{
+				"expiry": "only used with GTT",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkError(t, post(t, ts.URL+"/limitorder", test.body), http.StatusBadRequest, CodeInvalidOrder, test.details)
+		})
+	}
+
+	// Immediate orders don't rest in the book so the minimum doesn't apply. The book
+	// is empty so it isn't filled.
+	checkError(t, post(t, ts.URL+"/limitorder", `{"quantity": 1, "price": 1000000000, "buyBTC": true, "timeInForce": "IOC"}`),
+		http.StatusConflict, CodeNotFilled, nil)
+
+	checkError(t, post(t, ts.URL+"/limitorder", `{"quantity": `), http.StatusBadRequest, CodeInvalidRequest, nil)
+}
+
+func TestNotFound(t *testing.T) {
+	ts, cleanup := newTestServer(t, Options{})
+	defer cleanup()
+
+	checkError(t, post(t, ts.URL+"/closeorder/QmUnknownOrder", ""), http.StatusNotFound, CodeNotFound, nil)
+	checkError(t, post(t, ts.URL+"/closeorder/", ""), http.StatusBadRequest, CodeInvalidRequest, nil)
+	checkError(t, post(t, ts.URL+"/amendorder/QmUnknownOrder", `{"quantity": 1000000, "price": 1000000000}`),
+		http.StatusNotFound, CodeNotFound, nil)
+	checkError(t, post(t, ts.URL+"/takeorder/QmUnknownOrder", ""), http.StatusNotFound, CodeNotFound, nil)
+	checkError(t, post(t, ts.URL+"/takeorder/QmUnknownOrder?quantity=lots", ""), http.StatusBadRequest, CodeInvalidRequest,
+		map[string]string{"quantity": "must be a whole number of satoshis"})
+
+	resp, err := http.Get(ts.URL + "/order/QmUnknownOrder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkError(t, resp, http.StatusNotFound, CodeNotFound, nil)
+
+	resp, err = http.Get(ts.URL + "/nothing/here")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkError(t, resp, http.StatusNotFound, CodeNotFound, nil)
+}
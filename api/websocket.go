@@ -0,0 +1,187 @@
+package api
+
+import (
+	"fmt"
+	"github.com/cpacia/atomicswap/core"
+	ob "github.com/cpacia/atomicswap/orderbook"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// How long we'll wait for a write to the client to finish.
+	wsWriteWait = time.Second * 10
+
+	// The client has to answer our pings within this long or we drop it.
+	wsPongWait = time.Minute
+
+	// How often we ping the client. It has to be less than wsPongWait.
+	wsPingPeriod = wsPongWait * 9 / 10
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// eventResponse is the JSON sent to websocket clients for each event.
+type eventResponse struct {
+	Type   core.EventType `json:"type"`
+	Time   time.Time      `json:"time"`
+	Order  *ob.LimitOrder `json:"order,omitempty"`
+	PeerID string         `json:"peerID,omitempty"`
+	Swap   *SwapResponse  `json:"swap,omitempty"`
+}
+
+// wsRequest is sent by clients to change which events they get.
+type wsRequest struct {
+	Subscribe   []core.EventType `json:"subscribe"`
+	Unsubscribe []core.EventType `json:"unsubscribe"`
+}
+
+// eventFilter is the set of event types a client is subscribed to.
+type eventFilter struct {
+	types map[core.EventType]bool
+	lock  sync.Mutex
+}
+
+func newEventFilter(types []core.EventType) *eventFilter {
+	f := &eventFilter{types: make(map[core.EventType]bool)}
+	f.update(types, nil)
+	return f
+}
+
+func (f *eventFilter) update(subscribe, unsubscribe []core.EventType) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, t := range subscribe {
+		f.types[t] = true
+	}
+	for _, t := range unsubscribe {
+		delete(f.types, t)
+	}
+}
+
+func (f *eventFilter) match(t core.EventType) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.types[t]
+}
+
+// parseEventTypes parses a comma separated list of event types.
+func parseEventTypes(s string) ([]core.EventType, error) {
+	var types []core.EventType
+	for _, name := range strings.Split(s, ",") {
+		if name == "" {
+			continue
+		}
+		t, err := eventType(name)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+func eventType(name string) (core.EventType, error) {
+	for _, t := range core.EventTypes {
+		if string(t) == name {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown event type %s", name)
+}
+
+// handleWebsocket streams the node's events to the client. The events query parameter
+// is a comma separated list of the event types to send, all of them if it's left out.
+// The client can change its subscription by sending
+// {"subscribe": ["orderAdded"], "unsubscribe": ["swapState"]}.
+func (a *APIServer) handleWebsocket(w http.ResponseWriter, r *http.Request) {
+	types := core.EventTypes
+	if q := r.URL.Query().Get("events"); q != "" {
+		var err error
+		types, err = parseEventTypes(q)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error(), nil)
+			return
+		}
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	filter := newEventFilter(types)
+	events, unsubscribe := a.node.SubscribeEvents()
+	defer unsubscribe()
+
+	// Read subscription changes from the client until it goes away
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			conn.SetReadDeadline(time.Now().Add(wsPongWait))
+			return nil
+		})
+		for {
+			var req wsRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+					log.Debugf("Websocket read error: %s", err)
+				}
+				return
+			}
+			filter.update(req.Subscribe, req.Unsubscribe)
+		}
+	}()
+
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if !filter.match(e.Type) {
+				continue
+			}
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteJSON(eventInfo(e)); err != nil {
+				log.Debugf("Websocket write error: %s", err)
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func eventInfo(e core.Event) eventResponse {
+	resp := eventResponse{
+		Type:  e.Type,
+		Time:  e.Time,
+		Order: e.Order,
+	}
+	if e.Peer != "" {
+		resp.PeerID = e.Peer.Pretty()
+	}
+	if e.Swap != nil {
+		s := swapInfo(e.Swap)
+		s.State = e.State.String()
+		resp.Swap = &s
+	}
+	return resp
+}
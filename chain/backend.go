@@ -0,0 +1,186 @@
+package chain
+
+import (
+	"bytes"
+	"errors"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/op/go-logging"
+	"sync"
+	"time"
+)
+
+var log = logging.MustGetLogger("chain")
+
+var (
+	ErrTxNotFound = errors.New("transaction not found")
+	ErrTxOutSpent = errors.New("output spent or not found")
+)
+
+// ChainBackend is the interface the swap engine uses to talk to a blockchain.
+// Bitcoin and bitcoin cash transactions serialize the same so the same interface
+// is used for both chains.
+type ChainBackend interface {
+	// Broadcast sends the transaction to the network.
+	Broadcast(tx *wire.MsgTx) error
+
+	// GetTransaction returns the transaction with the given ID along with its
+	// number of confirmations. Unconfirmed transactions have zero confirmations.
+	GetTransaction(txid chainhash.Hash) (*wire.MsgTx, int32, error)
+
+	// GetTxOut returns the output if it's unspent, including by the mempool, along
+	// with its number of confirmations. It returns ErrTxOutSpent if the output is
+	// spent or doesn't exist.
+	GetTxOut(op wire.OutPoint) (*wire.TxOut, int32, error)
+
+	// WatchScript notifies the subscription of every transaction which pays
+	// to the output script, first when it's seen and again when it confirms.
+	// It shouldn't block on the network as wallets call it with their lock held.
+	WatchScript(pkScript []byte) (*Subscription, error)
+
+	// WatchOutpoint notifies the subscription of any transaction which spends
+	// the outpoint, first when it's seen and again when it confirms.
+	WatchOutpoint(op wire.OutPoint) (*Subscription, error)
+
+	// BestHeight returns the height of the chain tip.
+	BestHeight() (int32, error)
+
+	// MedianTime returns the median time of the last eleven blocks. This is the
+	// time locktimes are checked against (BIP113).
+	MedianTime() (time.Time, error)
+
+	// EstimateFee returns the fee rate in satoshis per byte needed to get a
+	// transaction confirmed in a reasonable amount of time.
+	EstimateFee() (int64, error)
+}
+
+// TxNotification is sent to a subscription when a matching transaction is seen.
+// Height is zero until the transaction confirms.
+type TxNotification struct {
+	Tx     *wire.MsgTx
+	Height int32
+}
+
+// Subscription delivers notifications for a watched script or outpoint. Close it
+// when you are no longer interested so the backend can stop watching.
+type Subscription struct {
+	C <-chan TxNotification
+
+	c       chan TxNotification
+	done    chan struct{}
+	once    sync.Once
+	onClose func()
+}
+
+func newSubscription(onClose func()) *Subscription {
+	c := make(chan TxNotification)
+	return &Subscription{
+		C:       c,
+		c:       c,
+		done:    make(chan struct{}),
+		onClose: onClose,
+	}
+}
+
+// notify delivers the notification without blocking the caller. Notifications
+// for a closed subscription are dropped.
+func (s *Subscription) notify(n TxNotification) {
+	go func() {
+		select {
+		case s.c <- n:
+		case <-s.done:
+		}
+	}()
+}
+
+// Close stops the subscription.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		if s.onClose != nil {
+			s.onClose()
+		}
+	})
+}
+
+// watchList tracks the scripts and outpoints being watched by a backend and
+// matches transactions against them.
+type watchList struct {
+	scripts   map[*Subscription][]byte
+	outpoints map[*Subscription]wire.OutPoint
+	lock      sync.Mutex
+}
+
+func newWatchList() *watchList {
+	return &watchList{
+		scripts:   make(map[*Subscription][]byte),
+		outpoints: make(map[*Subscription]wire.OutPoint),
+	}
+}
+
+func (w *watchList) watchScript(pkScript []byte) *Subscription {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	var sub *Subscription
+	sub = newSubscription(func() { w.remove(sub) })
+	w.scripts[sub] = pkScript
+	return sub
+}
+
+func (w *watchList) watchOutpoint(op wire.OutPoint) *Subscription {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	var sub *Subscription
+	sub = newSubscription(func() { w.remove(sub) })
+	w.outpoints[sub] = op
+	return sub
+}
+
+func (w *watchList) remove(sub *Subscription) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	delete(w.scripts, sub)
+	delete(w.outpoints, sub)
+}
+
+func (w *watchList) empty() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return len(w.scripts) == 0 && len(w.outpoints) == 0
+}
+
+// notify sends the transaction to every subscription watching one of its
+// outputs or inputs.
+func (w *watchList) notify(tx *wire.MsgTx, height int32) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	n := TxNotification{Tx: tx, Height: height}
+	for sub, pkScript := range w.scripts {
+		if paysTo(tx, pkScript) {
+			sub.notify(n)
+		}
+	}
+	for sub, op := range w.outpoints {
+		if spends(tx, op) {
+			sub.notify(n)
+		}
+	}
+}
+
+func paysTo(tx *wire.MsgTx, pkScript []byte) bool {
+	for _, txOut := range tx.TxOut {
+		if bytes.Equal(txOut.PkScript, pkScript) {
+			return true
+		}
+	}
+	return false
+}
+
+func spends(tx *wire.MsgTx, op wire.OutPoint) bool {
+	for _, txIn := range tx.TxIn {
+		if txIn.PreviousOutPoint == op {
+			return true
+		}
+	}
+	return false
+}
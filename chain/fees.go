@@ -0,0 +1,41 @@
+package chain
+
+// FeePolicy bounds the fee rates, in satoshis per byte, we pay on our transactions.
+// Default is used when the backend has no estimate. A zero Min or Max leaves that
+// bound off.
+type FeePolicy struct {
+	Default int64
+	Min     int64
+	Max     int64
+}
+
+// apply keeps the estimate within the policy's bounds.
+func (p FeePolicy) apply(fee int64, err error) (int64, error) {
+	if err != nil || fee <= 0 {
+		if p.Default <= 0 {
+			return fee, err
+		}
+		fee = p.Default
+	}
+	if p.Min > 0 && fee < p.Min {
+		fee = p.Min
+	}
+	if p.Max > 0 && fee > p.Max {
+		fee = p.Max
+	}
+	return fee, nil
+}
+
+type feePolicyBackend struct {
+	ChainBackend
+	policy FeePolicy
+}
+
+// WithFeePolicy wraps the backend so its fee estimates follow the policy.
+func WithFeePolicy(backend ChainBackend, policy FeePolicy) ChainBackend {
+	return &feePolicyBackend{backend, policy}
+}
+
+func (b *feePolicyBackend) EstimateFee() (int64, error) {
+	return b.policy.apply(b.ChainBackend.EstimateFee())
+}
@@ -0,0 +1,597 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// RPCPollInterval is how often the RPC backend checks for new blocks and
+	// mempool transactions when something is being watched.
+	RPCPollInterval = 30 * time.Second
+
+	// feeEstimateBlocks is the confirmation target used for fee estimates.
+	feeEstimateBlocks = 6
+
+	// maxReorgDepth is how many of the blocks it has scanned the poller remembers
+	// so it can tell when they're reorged out.
+	maxReorgDepth = 100
+
+	// scanBatchDelay is how long we wait for more scripts to be watched before
+	// scanning the utxo set so they can share a scan.
+	scanBatchDelay = 100 * time.Millisecond
+
+	// Error codes returned by bitcoind and Bitcoin ABC.
+	rpcErrInvalidAddressOrKey = -5
+	rpcErrAlreadyInChain      = -27
+)
+
+// RPCBackend is a ChainBackend which talks to a bitcoind or Bitcoin ABC full node
+// over JSON-RPC. Both nodes expose the same calls for everything we need.
+//
+// Watching is done by polling the node for new blocks and mempool transactions.
+// Outputs which confirmed before a watch was added are found with scantxoutset.
+type RPCBackend struct {
+	url      string
+	user     string
+	password string
+	client   *http.Client
+	id       uint64
+
+	watches      *watchList
+	pollInterval time.Duration
+	pollOnce     sync.Once
+
+	// The poll state is only used by the poller goroutine.
+	lastHeight  int32
+	blockHashes map[int32]chainhash.Hash
+	mempool     map[string]bool
+
+	// scans are the watched scripts waiting for the utxo set to be scanned.
+	scans      []scanRequest
+	scanSignal chan struct{}
+	scanLock   sync.Mutex
+}
+
+type scanRequest struct {
+	pkScript []byte
+	sub      *Subscription
+}
+
+// NewRPCBackend returns a backend for the node's JSON-RPC server at host. If the
+// host doesn't include a scheme http is assumed.
+func NewRPCBackend(host, user, password string) *RPCBackend {
+	if !strings.HasPrefix(host, "http://") && !strings.HasPrefix(host, "https://") {
+		host = "http://" + host
+	}
+	return &RPCBackend{
+		url:          host,
+		user:         user,
+		password:     password,
+		client:       &http.Client{Timeout: time.Minute},
+		watches:      newWatchList(),
+		pollInterval: RPCPollInterval,
+		blockHashes:  make(map[int32]chainhash.Hash),
+		mempool:      make(map[string]bool),
+		scanSignal:   make(chan struct{}, 1),
+	}
+}
+
+// RPCError is an error returned by the node.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+	ID     uint64          `json:"id"`
+}
+
+// call makes the RPC call and unmarshals the result into result, which may be
+// nil if we don't care about it.
+func (b *RPCBackend) call(method string, result interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	ser, err := json.Marshal(&rpcRequest{
+		JSONRPC: "1.0",
+		ID:      atomic.AddUint64(&b.id, 1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", b.url, bytes.NewReader(ser))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(b.user, b.password)
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New("rpc authentication failed")
+	}
+
+	// The node returns a 500 or 404 along with a normal response body on RPC errors
+	// so we try to decode the body whatever the status code.
+	var r rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("%s: %s", resp.Status, err)
+	}
+	if r.Error != nil {
+		return r.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(r.Result, result)
+}
+
+func isRPCError(err error, code int) bool {
+	rerr, ok := err.(*RPCError)
+	return ok && rerr.Code == code
+}
+
+func (b *RPCBackend) Broadcast(tx *wire.MsgTx) error {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return err
+	}
+	err := b.call("sendrawtransaction", nil, hex.EncodeToString(buf.Bytes()))
+	if isRPCError(err, rpcErrAlreadyInChain) {
+		// Rebroadcasting is fine
+		return nil
+	}
+	return err
+}
+
+type rawTransaction struct {
+	Hex           string `json:"hex"`
+	BlockHash     string `json:"blockhash"`
+	Confirmations int32  `json:"confirmations"`
+}
+
+func (b *RPCBackend) GetTransaction(txid chainhash.Hash) (*wire.MsgTx, int32, error) {
+	var raw rawTransaction
+	err := b.call("getrawtransaction", &raw, txid.String(), true)
+	if isRPCError(err, rpcErrInvalidAddressOrKey) {
+		return nil, 0, ErrTxNotFound
+	} else if err != nil {
+		return nil, 0, err
+	}
+	tx, err := decodeTx(raw.Hex)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tx, raw.Confirmations, nil
+}
+
+func (b *RPCBackend) GetTxOut(op wire.OutPoint) (*wire.TxOut, int32, error) {
+	var txOut *struct {
+		Value         float64 `json:"value"`
+		Confirmations int32   `json:"confirmations"`
+		ScriptPubKey  struct {
+			Hex string `json:"hex"`
+		} `json:"scriptPubKey"`
+	}
+	if err := b.call("gettxout", &txOut, op.Hash.String(), op.Index, true); err != nil {
+		return nil, 0, err
+	}
+	if txOut == nil {
+		return nil, 0, ErrTxOutSpent
+	}
+	value, err := btcutil.NewAmount(txOut.Value)
+	if err != nil {
+		return nil, 0, err
+	}
+	pkScript, err := hex.DecodeString(txOut.ScriptPubKey.Hex)
+	if err != nil {
+		return nil, 0, err
+	}
+	return wire.NewTxOut(int64(value), pkScript), txOut.Confirmations, nil
+}
+
+func (b *RPCBackend) WatchScript(pkScript []byte) (*Subscription, error) {
+	sub := b.watches.watchScript(pkScript)
+	b.pollOnce.Do(b.startPolling)
+
+	// New transactions will be picked up by the poller. Anything which already
+	// confirmed and is still unspent we can find in the utxo set. Scanning it is
+	// slow so it's done in the background for a batch of scripts at a time.
+	b.scanLock.Lock()
+	b.scans = append(b.scans, scanRequest{pkScript: pkScript, sub: sub})
+	b.scanLock.Unlock()
+	b.signalScan()
+	return sub, nil
+}
+
+func (b *RPCBackend) signalScan() {
+	select {
+	case b.scanSignal <- struct{}{}:
+	default:
+	}
+}
+
+// scanUTXOs scans the utxo set for the scripts waiting on a scan. Scans which
+// fail are retried on the next poll.
+func (b *RPCBackend) scanUTXOs() {
+	for range b.scanSignal {
+		time.Sleep(scanBatchDelay)
+		b.scanLock.Lock()
+		scans := b.scans
+		b.scans = nil
+		b.scanLock.Unlock()
+		if len(scans) == 0 {
+			continue
+		}
+		if err := b.scanScripts(scans); err != nil {
+			log.Errorf("Error scanning %s utxo set: %s", b.url, err)
+			b.scanLock.Lock()
+			b.scans = append(scans, b.scans...)
+			b.scanLock.Unlock()
+		}
+	}
+}
+
+// scanScripts looks up the unspent outputs paying to the scripts with a single
+// scantxoutset call and notifies the subscriptions of the transactions. If we
+// can't fetch one of the transactions the scans waiting on it are queued again
+// and retried on the next poll.
+func (b *RPCBackend) scanScripts(scans []scanRequest) error {
+	var descs []interface{}
+	for _, scan := range scans {
+		descs = append(descs, map[string]string{"desc": "raw(" + hex.EncodeToString(scan.pkScript) + ")"})
+	}
+	var result struct {
+		Unspents []struct {
+			TxID         string `json:"txid"`
+			ScriptPubKey string `json:"scriptPubKey"`
+			Height       int32  `json:"height"`
+		} `json:"unspents"`
+	}
+	if err := b.call("scantxoutset", &result, "start", descs); err != nil {
+		return err
+	}
+	txs := make(map[string]*wire.MsgTx)
+	notified := make(map[*Subscription]map[string]bool)
+	var retry []scanRequest
+	retrying := make(map[*Subscription]bool)
+	for _, u := range result.Unspents {
+		pkScript, err := hex.DecodeString(u.ScriptPubKey)
+		if err != nil {
+			continue
+		}
+		for _, scan := range scans {
+			if !bytes.Equal(scan.pkScript, pkScript) || notified[scan.sub][u.TxID] {
+				continue
+			}
+			tx, ok := txs[u.TxID]
+			if !ok {
+				tx, err = b.getScannedTx(u.TxID)
+				if err != nil {
+					log.Errorf("Error getting %s transaction %s: %s", b.url, u.TxID, err)
+					if !retrying[scan.sub] {
+						retry = append(retry, scan)
+						retrying[scan.sub] = true
+					}
+					continue
+				}
+				txs[u.TxID] = tx
+			}
+			if notified[scan.sub] == nil {
+				notified[scan.sub] = make(map[string]bool)
+			}
+			notified[scan.sub][u.TxID] = true
+			scan.sub.notify(TxNotification{Tx: tx, Height: u.Height})
+		}
+	}
+	if len(retry) > 0 {
+		b.scanLock.Lock()
+		b.scans = append(b.scans, retry...)
+		b.scanLock.Unlock()
+	}
+	return nil
+}
+
+func (b *RPCBackend) getScannedTx(txid string) (*wire.MsgTx, error) {
+	hash, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		return nil, err
+	}
+	tx, _, err := b.GetTransaction(*hash)
+	return tx, err
+}
+
+func (b *RPCBackend) WatchOutpoint(op wire.OutPoint) (*Subscription, error) {
+	sub := b.watches.watchOutpoint(op)
+	b.pollOnce.Do(b.startPolling)
+
+	// If the output is still unspent (including by the mempool) the poller will
+	// find the spend. Otherwise we have to go looking for it in the blocks since
+	// the funding transaction confirmed.
+	_, _, err := b.GetTxOut(op)
+	if err == nil {
+		return sub, nil
+	} else if err != ErrTxOutSpent {
+		sub.Close()
+		return nil, err
+	}
+	var raw rawTransaction
+	if err := b.call("getrawtransaction", &raw, op.Hash.String(), true); err != nil || raw.BlockHash == "" {
+		// Either we don't know about the funding transaction or it's unconfirmed.
+		// Either way the poller will pick up the spend if it happens.
+		return sub, nil
+	}
+	var header struct {
+		Height int32 `json:"height"`
+	}
+	if err := b.call("getblockheader", &header, raw.BlockHash, true); err != nil {
+		return sub, nil
+	}
+	// The scan only notifies this subscription. It doesn't touch the poller's
+	// state so it's safe to run alongside it.
+	go func() {
+		best, err := b.BestHeight()
+		if err != nil {
+			return
+		}
+		b.scanBlocks(header.Height, best, func(height int32, _ chainhash.Hash, block *wire.MsgBlock) {
+			for _, tx := range block.Transactions {
+				if spends(tx, op) {
+					sub.notify(TxNotification{Tx: tx, Height: height})
+				}
+			}
+		})
+	}()
+	return sub, nil
+}
+
+func (b *RPCBackend) BestHeight() (int32, error) {
+	var height int32
+	if err := b.call("getblockcount", &height); err != nil {
+		return 0, err
+	}
+	return height, nil
+}
+
+func (b *RPCBackend) MedianTime() (time.Time, error) {
+	var info struct {
+		MedianTime int64 `json:"mediantime"`
+	}
+	if err := b.call("getblockchaininfo", &info); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(info.MedianTime, 0), nil
+}
+
+// EstimateFee asks the node for a fee estimate. Bitcoin ABC dropped estimatesmartfee
+// so we fall back to estimatefee if it fails. Both return a rate in coins per kilobyte.
+func (b *RPCBackend) EstimateFee() (int64, error) {
+	var smart struct {
+		FeeRate float64 `json:"feerate"`
+	}
+	feeRate := 0.0
+	if err := b.call("estimatesmartfee", &smart, feeEstimateBlocks); err == nil {
+		feeRate = smart.FeeRate
+	} else if err := b.call("estimatefee", &feeRate); err != nil {
+		return 0, err
+	}
+	if feeRate <= 0 {
+		return 0, errors.New("node has no fee estimate")
+	}
+	perByte := int64(feeRate * 1e8 / 1000)
+	if perByte < 1 {
+		perByte = 1
+	}
+	return perByte, nil
+}
+
+func (b *RPCBackend) startPolling() {
+	height, err := b.BestHeight()
+	if err != nil {
+		log.Errorf("Error getting best height from %s: %s", b.url, err)
+	}
+	b.lastHeight = height
+	go b.poll()
+	go b.scanUTXOs()
+}
+
+// poll checks for new blocks and mempool transactions and passes them on to the
+// watchers. It runs for the life of the backend.
+func (b *RPCBackend) poll() {
+	ticker := time.NewTicker(b.pollInterval)
+	for range ticker.C {
+		b.pollChain()
+	}
+}
+
+// pollChain scans any blocks mined since the last poll and the mempool. If blocks
+// we've already scanned were reorged out we go back to where the chains forked and
+// scan the new blocks from there. Transactions from the old blocks are passed on
+// again when they show up in the mempool or a new block.
+//
+// Watchers only get told about transactions, they must look the transaction up
+// before acting on it as it may have since been reorged out.
+func (b *RPCBackend) pollChain() {
+	best, err := b.BestHeight()
+	if err != nil {
+		log.Errorf("Error polling %s: %s", b.url, err)
+		return
+	}
+	if b.watches.empty() {
+		b.lastHeight = best
+		b.blockHashes = make(map[int32]chainhash.Hash)
+		return
+	}
+	fork, err := b.findFork(best)
+	if err != nil {
+		log.Errorf("Error polling %s: %s", b.url, err)
+		return
+	}
+	if fork < b.lastHeight {
+		log.Warningf("Blocks %d to %d on %s were reorged out, rescanning", fork+1, b.lastHeight, b.url)
+		for height := fork + 1; height <= b.lastHeight; height++ {
+			delete(b.blockHashes, height)
+		}
+		b.lastHeight = fork
+		b.mempool = make(map[string]bool)
+	}
+	if best > b.lastHeight {
+		b.lastHeight = b.scanBlocks(b.lastHeight+1, best, func(height int32, hash chainhash.Hash, block *wire.MsgBlock) {
+			for _, tx := range block.Transactions {
+				b.watches.notify(tx, height)
+			}
+			b.blockHashes[height] = hash
+			delete(b.blockHashes, height-maxReorgDepth)
+		})
+	}
+	if err := b.pollMempool(); err != nil {
+		log.Errorf("Error polling %s mempool: %s", b.url, err)
+	}
+
+	b.scanLock.Lock()
+	retry := len(b.scans) > 0
+	b.scanLock.Unlock()
+	if retry {
+		b.signalScan()
+	}
+}
+
+// findFork returns the height of the last block we scanned which is still in the
+// best chain. Without a reorg that's the last block we scanned.
+func (b *RPCBackend) findFork(best int32) (int32, error) {
+	height := b.lastHeight
+	if best < height {
+		height = best
+	}
+	for ; height > 0 && height > b.lastHeight-maxReorgDepth; height-- {
+		known, ok := b.blockHashes[height]
+		if !ok {
+			// We never scanned this block so there's nothing to roll back
+			return height, nil
+		}
+		hash, err := b.getBlockHash(height)
+		if err != nil {
+			return 0, err
+		}
+		if hash == known {
+			return height, nil
+		}
+	}
+	return height, nil
+}
+
+// scanBlocks passes each block from start to end inclusive to fn. It returns the
+// height of the last block scanned.
+func (b *RPCBackend) scanBlocks(start, end int32, fn func(height int32, hash chainhash.Hash, block *wire.MsgBlock)) int32 {
+	for height := start; height <= end; height++ {
+		hash, block, err := b.getBlock(height)
+		if err != nil {
+			log.Errorf("Error getting block %d from %s: %s", height, b.url, err)
+			return height - 1
+		}
+		fn(height, hash, block)
+	}
+	return end
+}
+
+func (b *RPCBackend) getBlockHash(height int32) (chainhash.Hash, error) {
+	var hashStr string
+	if err := b.call("getblockhash", &hashStr, height); err != nil {
+		return chainhash.Hash{}, err
+	}
+	hash, err := chainhash.NewHashFromStr(hashStr)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	return *hash, nil
+}
+
+func (b *RPCBackend) getBlock(height int32) (chainhash.Hash, *wire.MsgBlock, error) {
+	hash, err := b.getBlockHash(height)
+	if err != nil {
+		return chainhash.Hash{}, nil, err
+	}
+	var blockHex string
+	if err := b.call("getblock", &blockHex, hash.String(), false); err != nil {
+		return chainhash.Hash{}, nil, err
+	}
+	ser, err := hex.DecodeString(blockHex)
+	if err != nil {
+		return chainhash.Hash{}, nil, err
+	}
+	block := new(wire.MsgBlock)
+	if err := block.Deserialize(bytes.NewReader(ser)); err != nil {
+		return chainhash.Hash{}, nil, err
+	}
+	return hash, block, nil
+}
+
+// pollMempool passes any transactions which entered the mempool since the last
+// poll to the watchers.
+func (b *RPCBackend) pollMempool() error {
+	var txids []string
+	if err := b.call("getrawmempool", &txids); err != nil {
+		return err
+	}
+	current := make(map[string]bool)
+	for _, txid := range txids {
+		current[txid] = true
+		if b.mempool[txid] {
+			continue
+		}
+		var txHex string
+		if err := b.call("getrawtransaction", &txHex, txid, false); err != nil {
+			// It may have just been mined or evicted
+			continue
+		}
+		tx, err := decodeTx(txHex)
+		if err != nil {
+			continue
+		}
+		b.watches.notify(tx, 0)
+	}
+	b.mempool = current
+	return nil
+}
+
+func decodeTx(txHex string) (*wire.MsgTx, error) {
+	ser, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, err
+	}
+	tx := new(wire.MsgTx)
+	if err := tx.Deserialize(bytes.NewReader(ser)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
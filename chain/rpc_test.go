@@ -0,0 +1,410 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testRPCUser     = "user"
+	testRPCPassword = "pass"
+)
+
+// stubNode stands in for bitcoind's JSON-RPC server. Calls with a response in
+// responses get it replayed as is. The chain calls are answered from the stub's
+// blocks and mempool so tests can mine blocks and reorg them out.
+type stubNode struct {
+	responses map[string]string
+	blocks    []*wire.MsgBlock
+	mempool   []*wire.MsgTx
+	calls     map[string]int
+	lock      sync.Mutex
+}
+
+func newStubNode(height int) *stubNode {
+	n := &stubNode{
+		responses: make(map[string]string),
+		calls:     make(map[string]int),
+	}
+	for i := 0; i <= height; i++ {
+		n.mine(uint32(i))
+	}
+	return n
+}
+
+func (n *stubNode) serve() (*httptest.Server, *RPCBackend) {
+	srv := httptest.NewServer(n)
+	return srv, NewRPCBackend(srv.URL, testRPCUser, testRPCPassword)
+}
+
+// mine adds a block containing the mempool to the chain. The nonce makes blocks
+// at the same height after a reorg different.
+func (n *stubNode) mine(nonce uint32) *wire.MsgBlock {
+	prevHash := chainhash.Hash{}
+	if len(n.blocks) > 0 {
+		prevHash = n.blocks[len(n.blocks)-1].BlockHash()
+	}
+	block := wire.NewMsgBlock(wire.NewBlockHeader(1, &prevHash, &chainhash.Hash{}, 0, nonce))
+	for _, tx := range n.mempool {
+		block.AddTransaction(tx)
+	}
+	n.mempool = nil
+	n.blocks = append(n.blocks, block)
+	return block
+}
+
+// reorg replaces the blocks above height with ones built on top of it. Their
+// transactions go back to the mempool.
+func (n *stubNode) reorg(height int) {
+	for _, block := range n.blocks[height+1:] {
+		n.mempool = append(n.mempool, block.Transactions...)
+	}
+	n.blocks = n.blocks[:height+1]
+}
+
+func (n *stubNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, password, ok := r.BasicAuth()
+	if !ok || user != testRPCUser || password != testRPCPassword {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.calls[req.Method]++
+	if resp, ok := n.responses[req.Method]; ok {
+		// bitcoind sends errors with a 500 status
+		if !strings.Contains(resp, `"error":null`) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		fmt.Fprint(w, resp)
+		return
+	}
+	result, err := n.handle(req.Method, req.Params)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": nil, "error": err, "id": 1})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"result": result, "error": nil, "id": 1})
+}
+
+func (n *stubNode) handle(method string, params []json.RawMessage) (interface{}, *RPCError) {
+	switch method {
+	case "getblockcount":
+		return len(n.blocks) - 1, nil
+	case "getblockhash":
+		var height int
+		json.Unmarshal(params[0], &height)
+		if height < 0 || height >= len(n.blocks) {
+			return nil, &RPCError{Code: -8, Message: "Block height out of range"}
+		}
+		return n.blocks[height].BlockHash().String(), nil
+	case "getblock":
+		var hash string
+		json.Unmarshal(params[0], &hash)
+		for _, block := range n.blocks {
+			if block.BlockHash().String() == hash {
+				var buf bytes.Buffer
+				block.Serialize(&buf)
+				return hex.EncodeToString(buf.Bytes()), nil
+			}
+		}
+		return nil, &RPCError{Code: -5, Message: "Block not found"}
+	case "getrawmempool":
+		txids := []string{}
+		for _, tx := range n.mempool {
+			txids = append(txids, tx.TxHash().String())
+		}
+		return txids, nil
+	case "getrawtransaction":
+		var txid string
+		json.Unmarshal(params[0], &txid)
+		for _, tx := range n.allTxs() {
+			if tx.TxHash().String() == txid {
+				var buf bytes.Buffer
+				tx.Serialize(&buf)
+				txHex := hex.EncodeToString(buf.Bytes())
+				if len(params) > 1 && string(params[1]) == "true" {
+					return map[string]interface{}{"hex": txHex}, nil
+				}
+				return txHex, nil
+			}
+		}
+		return nil, &RPCError{Code: -5, Message: "No such mempool or blockchain transaction. Use gettransaction for wallet transactions."}
+	case "scantxoutset":
+		var descs []struct {
+			Desc string `json:"desc"`
+		}
+		json.Unmarshal(params[1], &descs)
+		unspents := []map[string]interface{}{}
+		for height, block := range n.blocks {
+			for _, tx := range block.Transactions {
+				for i, out := range tx.TxOut {
+					script := hex.EncodeToString(out.PkScript)
+					for _, d := range descs {
+						if d.Desc == "raw("+script+")" {
+							unspents = append(unspents, map[string]interface{}{
+								"txid":         tx.TxHash().String(),
+								"vout":         i,
+								"scriptPubKey": script,
+								"desc":         d.Desc,
+								"amount":       float64(out.Value) / 1e8,
+								"height":       height,
+							})
+						}
+					}
+				}
+			}
+		}
+		return map[string]interface{}{"success": true, "searched_items": 1000, "unspents": unspents}, nil
+	}
+	return nil, &RPCError{Code: -32601, Message: "Method not found"}
+}
+
+func (n *stubNode) allTxs() []*wire.MsgTx {
+	txs := append([]*wire.MsgTx(nil), n.mempool...)
+	for _, block := range n.blocks {
+		txs = append(txs, block.Transactions...)
+	}
+	return txs
+}
+
+func (n *stubNode) numCalls(method string) int {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.calls[method]
+}
+
+func testTx(pkScript []byte, nonce byte) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{nonce}, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(100000, pkScript))
+	return tx
+}
+
+func nextNotification(t *testing.T, sub *Subscription) TxNotification {
+	select {
+	case n := <-sub.C:
+		return n
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for notification")
+	}
+	return TxNotification{}
+}
+
+func TestRPCGetTxOut(t *testing.T) {
+	node := newStubNode(0)
+	srv, b := node.serve()
+	defer srv.Close()
+
+	node.responses["gettxout"] = `{"result":{"bestblock":"3d4a1fd7ec4d1b7e1b3b5a8e8b6f0c4f0e1b0d7f9d1a0b6c6c8f9e2e1c0d8a7b","confirmations":3,"value":0.50000000,"scriptPubKey":{"asm":"OP_DUP OP_HASH160 2b4b6b1b9d5f1e0ad9b1a1c1d8c8e7f5b0e4a1d2 OP_EQUALVERIFY OP_CHECKSIG","hex":"76a9142b4b6b1b9d5f1e0ad9b1a1c1d8c8e7f5b0e4a1d288ac","reqSigs":1,"type":"pubkeyhash"},"coinbase":false},"error":null,"id":1}`
+	out, confirmations, err := b.GetTxOut(wire.OutPoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Value != 50000000 || confirmations != 3 || hex.EncodeToString(out.PkScript) != "76a9142b4b6b1b9d5f1e0ad9b1a1c1d8c8e7f5b0e4a1d288ac" {
+		t.Errorf("got output %d %x with %d confirmations", out.Value, out.PkScript, confirmations)
+	}
+
+	node.responses["gettxout"] = `{"result":null,"error":null,"id":1}`
+	if _, _, err := b.GetTxOut(wire.OutPoint{}); err != ErrTxOutSpent {
+		t.Errorf("got error %v, want ErrTxOutSpent", err)
+	}
+}
+
+func TestRPCErrors(t *testing.T) {
+	node := newStubNode(0)
+	srv, b := node.serve()
+	defer srv.Close()
+
+	node.responses["getrawtransaction"] = `{"result":null,"error":{"code":-5,"message":"No such mempool or blockchain transaction. Use gettransaction for wallet transactions."},"id":1}`
+	if _, _, err := b.GetTransaction(chainhash.Hash{}); err != ErrTxNotFound {
+		t.Errorf("got error %v, want ErrTxNotFound", err)
+	}
+
+	tx := testTx([]byte{0x51}, 1)
+	node.responses["sendrawtransaction"] = `{"result":null,"error":{"code":-27,"message":"Transaction already in block chain"},"id":1}`
+	if err := b.Broadcast(tx); err != nil {
+		t.Errorf("rebroadcast failed: %s", err)
+	}
+	node.responses["sendrawtransaction"] = `{"result":null,"error":{"code":-26,"message":"258: txn-mempool-conflict"},"id":1}`
+	if err := b.Broadcast(tx); err == nil || !isRPCError(err, -26) {
+		t.Errorf("got error %v, want the node's rejection", err)
+	}
+
+	bad := NewRPCBackend(srv.URL, testRPCUser, "wrong")
+	if _, err := bad.BestHeight(); err == nil || err.Error() != "rpc authentication failed" {
+		t.Errorf("got error %v, want authentication failure", err)
+	}
+}
+
+func TestRPCEstimateFee(t *testing.T) {
+	node := newStubNode(0)
+	srv, b := node.serve()
+	defer srv.Close()
+
+	node.responses["estimatesmartfee"] = `{"result":{"feerate":0.00012000,"blocks":6},"error":null,"id":1}`
+	if fee, err := b.EstimateFee(); err != nil || fee != 12 {
+		t.Errorf("got fee %d, %v, want 12", fee, err)
+	}
+
+	// Bitcoin ABC only has estimatefee
+	node.responses["estimatesmartfee"] = `{"result":null,"error":{"code":-32601,"message":"Method not found"},"id":1}`
+	node.responses["estimatefee"] = `{"result":0.00001000,"error":null,"id":1}`
+	if fee, err := b.EstimateFee(); err != nil || fee != 1 {
+		t.Errorf("got fee %d, %v, want 1", fee, err)
+	}
+
+	node.responses["estimatefee"] = `{"result":-1,"error":null,"id":1}`
+	if _, err := b.EstimateFee(); err == nil {
+		t.Error("expected an error without an estimate")
+	}
+}
+
+func TestRPCWatchScriptBatchesScans(t *testing.T) {
+	node := newStubNode(10)
+	scriptA, scriptB := []byte{0x51}, []byte{0x52}
+	txA, txB := testTx(scriptA, 1), testTx(scriptB, 2)
+	node.mempool = []*wire.MsgTx{txA, txB}
+	node.mine(11)
+	srv, b := node.serve()
+	defer srv.Close()
+	b.pollInterval = time.Hour
+
+	subA, err := b.WatchScript(scriptA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subA.Close()
+	subB, err := b.WatchScript(scriptB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subB.Close()
+
+	if n := nextNotification(t, subA); n.Tx.TxHash() != txA.TxHash() || n.Height != 11 {
+		t.Errorf("got %s at height %d, want %s at 11", n.Tx.TxHash(), n.Height, txA.TxHash())
+	}
+	if n := nextNotification(t, subB); n.Tx.TxHash() != txB.TxHash() || n.Height != 11 {
+		t.Errorf("got %s at height %d, want %s at 11", n.Tx.TxHash(), n.Height, txB.TxHash())
+	}
+	if calls := node.numCalls("scantxoutset"); calls != 1 {
+		t.Errorf("scanned the utxo set %d times, want once", calls)
+	}
+}
+
+func TestRPCReorg(t *testing.T) {
+	node := newStubNode(10)
+	srv, b := node.serve()
+	defer srv.Close()
+	b.pollInterval = time.Hour
+
+	script := []byte{0x51}
+	sub, err := b.WatchScript(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	tx := testTx(script, 1)
+
+	// Let the utxo set scan for the script finish first so it doesn't notify
+	// us of the transaction too.
+	for node.numCalls("scantxoutset") == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	poll := func(wantHeight int32) {
+		b.pollChain()
+		n := nextNotification(t, sub)
+		if n.Tx.TxHash() != tx.TxHash() || n.Height != wantHeight {
+			t.Fatalf("got %s at height %d, want %s at %d", n.Tx.TxHash(), n.Height, tx.TxHash(), wantHeight)
+		}
+	}
+
+	node.lock.Lock()
+	node.mempool = []*wire.MsgTx{tx}
+	node.mine(11)
+	node.lock.Unlock()
+	poll(11)
+
+	// The block with the transaction is replaced by one with the transaction at
+	// the same height and another on top.
+	node.lock.Lock()
+	node.reorg(10)
+	node.mine(111)
+	node.mine(112)
+	node.lock.Unlock()
+	poll(11)
+
+	// The transaction is reorged out and goes back to the mempool until it's
+	// mined again.
+	node.lock.Lock()
+	node.reorg(10)
+	mempool := node.mempool
+	node.mempool = nil
+	for i := 0; i < 3; i++ {
+		node.mine(uint32(211 + i))
+	}
+	node.mempool = mempool
+	node.lock.Unlock()
+	poll(0)
+
+	node.lock.Lock()
+	node.mine(214)
+	node.lock.Unlock()
+	poll(14)
+}
+
+func TestRPCScanRetriesFailedLookups(t *testing.T) {
+	node := newStubNode(10)
+	script := []byte{0x51}
+	tx := testTx(script, 1)
+	node.mempool = []*wire.MsgTx{tx}
+	node.mine(11)
+	node.responses["getrawtransaction"] = `{"result":null,"error":{"code":-1,"message":"busy"},"id":1}`
+	srv, b := node.serve()
+	defer srv.Close()
+	b.pollInterval = time.Hour
+
+	sub, err := b.WatchScript(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	for node.numCalls("getrawtransaction") == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case n := <-sub.C:
+		t.Fatalf("notified of %s without the transaction lookup", n.Tx.TxHash())
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The next poll scans for the script again
+	node.lock.Lock()
+	delete(node.responses, "getrawtransaction")
+	node.lock.Unlock()
+	b.pollChain()
+	if n := nextNotification(t, sub); n.Tx.TxHash() != tx.TxHash() || n.Height != 11 {
+		t.Errorf("got %s at height %d, want %s at 11", n.Tx.TxHash(), n.Height, tx.TxHash())
+	}
+}
@@ -0,0 +1,334 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/cpacia/atomicswap/swap"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// SimBlockInterval is the time between blocks mined by the simulated chain.
+	SimBlockInterval = 10 * time.Minute
+
+	// SimFeePerByte is the fee rate returned by the simulated chain's fee estimate.
+	SimFeePerByte = 10
+
+	// medianTimeBlocks is the number of blocks used to compute the median time.
+	medianTimeBlocks = 11
+
+	// lockTimeThreshold is the value below which a locktime is a block height
+	// rather than a unix timestamp.
+	lockTimeThreshold = 500000000
+)
+
+// SimChain is an in-memory regtest style chain. Blocks are only mined when
+// MineBlocks is called which lets you step through a swap, including the
+// locktimes expiring, without running a node or touching the network.
+//
+// Transactions are checked for missing inputs, double spends, overspending and
+// locktime finality, and every input script is run against the output it spends
+// with the coin's script engine.
+type SimChain struct {
+	coin swap.Coin
+
+	blocks  []*wire.MsgBlock
+	mempool []*wire.MsgTx
+
+	txs   map[chainhash.Hash]*simTx
+	utxos map[wire.OutPoint]*wire.TxOut
+	spent map[wire.OutPoint]chainhash.Hash
+
+	// timeOffset is added to the time of the next block mined.
+	timeOffset time.Duration
+	fundings   uint64
+
+	watches *watchList
+
+	lock sync.Mutex
+}
+
+type simTx struct {
+	tx     *wire.MsgTx
+	height int32
+}
+
+// NewSimChain returns a simulated chain for the coin with enough blocks for a
+// median time to be computed. The tip's timestamp is the current time.
+func NewSimChain(coin swap.Coin) *SimChain {
+	s := &SimChain{
+		coin:    coin,
+		txs:     make(map[chainhash.Hash]*simTx),
+		utxos:   make(map[wire.OutPoint]*wire.TxOut),
+		spent:   make(map[wire.OutPoint]chainhash.Hash),
+		watches: newWatchList(),
+	}
+	start := time.Now().Add(-SimBlockInterval * (medianTimeBlocks - 1))
+	genesis := wire.NewMsgBlock(wire.NewBlockHeader(1, &chainhash.Hash{}, &chainhash.Hash{}, 0, 0))
+	genesis.Header.Timestamp = time.Unix(start.Unix(), 0)
+	s.blocks = append(s.blocks, genesis)
+	s.mine(medianTimeBlocks - 1)
+	return s
+}
+
+// Fund creates a transaction out of thin air paying value to the output script
+// and adds it to the mempool. Use it to give wallets coins to swap with.
+func (s *SimChain) Fund(pkScript []byte, value int64) *wire.MsgTx {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Every funding transaction needs a unique ID so we put a counter in the
+	// coinbase style input.
+	s.fundings++
+	sigScript := make([]byte, 8)
+	binary.BigEndian.PutUint64(sigScript, s.fundings)
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), sigScript, nil))
+	tx.AddTxOut(wire.NewTxOut(value, pkScript))
+	s.accept(tx)
+	return tx
+}
+
+// MineBlocks mines n blocks. The first block includes everything in the mempool.
+// It returns the mined blocks.
+func (s *SimChain) MineBlocks(n int) []*wire.MsgBlock {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.mine(n)
+}
+
+// AdvanceTime moves the clock forward so the next block is mined d later than
+// it otherwise would be. Combined with MineBlocks this is used to expire locktimes.
+func (s *SimChain) AdvanceTime(d time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.timeOffset += d
+}
+
+// Mempool returns the transactions waiting to be mined.
+func (s *SimChain) Mempool() []*wire.MsgTx {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]*wire.MsgTx(nil), s.mempool...)
+}
+
+// UnspentOutputs returns all the unspent outputs, including unconfirmed ones,
+// paying to the output script.
+func (s *SimChain) UnspentOutputs(pkScript []byte) map[wire.OutPoint]*wire.TxOut {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ret := make(map[wire.OutPoint]*wire.TxOut)
+	for op, out := range s.utxos {
+		if bytes.Equal(out.PkScript, pkScript) {
+			ret[op] = out
+		}
+	}
+	return ret
+}
+
+func (s *SimChain) Broadcast(tx *wire.MsgTx) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	txid := tx.TxHash()
+	if _, ok := s.txs[txid]; ok {
+		// Rebroadcasting is fine
+		return nil
+	}
+	if len(tx.TxIn) == 0 || len(tx.TxOut) == 0 {
+		return errors.New("transaction has no inputs or outputs")
+	}
+	var in int64
+	for _, txIn := range tx.TxIn {
+		prev, ok := s.utxos[txIn.PreviousOutPoint]
+		if !ok {
+			if spender, ok := s.spent[txIn.PreviousOutPoint]; ok {
+				return fmt.Errorf("input %s already spent by %s", txIn.PreviousOutPoint, spender)
+			}
+			return fmt.Errorf("missing input %s", txIn.PreviousOutPoint)
+		}
+		in += prev.Value
+	}
+	for i, txIn := range tx.TxIn {
+		prev := s.utxos[txIn.PreviousOutPoint]
+		if err := swap.VerifyInput(s.coin, tx, i, prev.PkScript, prev.Value); err != nil {
+			return fmt.Errorf("input %d failed script verification: %s", i, err)
+		}
+	}
+	var out int64
+	for _, txOut := range tx.TxOut {
+		if txOut.Value < 0 {
+			return errors.New("negative output value")
+		}
+		out += txOut.Value
+	}
+	if out > in {
+		return fmt.Errorf("transaction spends %d but only has %d in inputs", out, in)
+	}
+	if !s.isFinal(tx) {
+		return errors.New("transaction is not final")
+	}
+	s.accept(tx)
+	return nil
+}
+
+func (s *SimChain) GetTransaction(txid chainhash.Hash) (*wire.MsgTx, int32, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stx, ok := s.txs[txid]
+	if !ok {
+		return nil, 0, ErrTxNotFound
+	}
+	return stx.tx, s.confirmations(stx.height), nil
+}
+
+func (s *SimChain) GetTxOut(op wire.OutPoint) (*wire.TxOut, int32, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	out, ok := s.utxos[op]
+	if !ok {
+		return nil, 0, ErrTxOutSpent
+	}
+	return out, s.confirmations(s.txs[op.Hash].height), nil
+}
+
+func (s *SimChain) WatchScript(pkScript []byte) (*Subscription, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sub := s.watches.watchScript(pkScript)
+
+	// Let the caller know about any transactions we've already seen.
+	for _, stx := range s.txs {
+		if paysTo(stx.tx, pkScript) {
+			sub.notify(TxNotification{Tx: stx.tx, Height: stx.height})
+		}
+	}
+	return sub, nil
+}
+
+func (s *SimChain) WatchOutpoint(op wire.OutPoint) (*Subscription, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sub := s.watches.watchOutpoint(op)
+
+	// If the outpoint is already spent let the caller know right away.
+	if spender, ok := s.spent[op]; ok {
+		stx := s.txs[spender]
+		sub.notify(TxNotification{Tx: stx.tx, Height: stx.height})
+	}
+	return sub, nil
+}
+
+func (s *SimChain) BestHeight() (int32, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.height(), nil
+}
+
+func (s *SimChain) MedianTime() (time.Time, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.medianTime(), nil
+}
+
+// Coin returns the coin the chain simulates.
+func (s *SimChain) Coin() swap.Coin {
+	return s.coin
+}
+
+func (s *SimChain) EstimateFee() (int64, error) {
+	return SimFeePerByte, nil
+}
+
+func (s *SimChain) height() int32 {
+	return int32(len(s.blocks) - 1)
+}
+
+func (s *SimChain) confirmations(height int32) int32 {
+	if height == 0 {
+		return 0
+	}
+	return s.height() - height + 1
+}
+
+func (s *SimChain) medianTime() time.Time {
+	var timestamps []int64
+	for i := len(s.blocks) - 1; i >= 0 && len(timestamps) < medianTimeBlocks; i-- {
+		timestamps = append(timestamps, s.blocks[i].Header.Timestamp.Unix())
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	return time.Unix(timestamps[len(timestamps)/2], 0)
+}
+
+// isFinal checks the transaction's locktime against the next block the same
+// way a real node would.
+func (s *SimChain) isFinal(tx *wire.MsgTx) bool {
+	if tx.LockTime == 0 {
+		return true
+	}
+	var cutoff int64
+	if tx.LockTime < lockTimeThreshold {
+		cutoff = int64(s.height() + 1)
+	} else {
+		cutoff = s.medianTime().Unix()
+	}
+	if int64(tx.LockTime) < cutoff {
+		return true
+	}
+	for _, txIn := range tx.TxIn {
+		if txIn.Sequence != wire.MaxTxInSequenceNum {
+			return false
+		}
+	}
+	return true
+}
+
+// accept adds the transaction to the mempool, updates the utxo set and notifies
+// any watchers. The caller must hold the lock.
+func (s *SimChain) accept(tx *wire.MsgTx) {
+	txid := tx.TxHash()
+	for _, txIn := range tx.TxIn {
+		if _, ok := s.utxos[txIn.PreviousOutPoint]; ok {
+			delete(s.utxos, txIn.PreviousOutPoint)
+			s.spent[txIn.PreviousOutPoint] = txid
+		}
+	}
+	for i, txOut := range tx.TxOut {
+		s.utxos[*wire.NewOutPoint(&txid, uint32(i))] = txOut
+	}
+	s.txs[txid] = &simTx{tx: tx}
+	s.mempool = append(s.mempool, tx)
+	s.watches.notify(tx, 0)
+}
+
+func (s *SimChain) mine(n int) []*wire.MsgBlock {
+	var mined []*wire.MsgBlock
+	for i := 0; i < n; i++ {
+		prev := s.blocks[len(s.blocks)-1]
+		prevHash := prev.BlockHash()
+		block := wire.NewMsgBlock(wire.NewBlockHeader(1, &prevHash, &chainhash.Hash{}, 0, uint32(len(s.blocks))))
+		block.Header.Timestamp = prev.Header.Timestamp.Add(SimBlockInterval + s.timeOffset)
+		s.timeOffset = 0
+
+		height := int32(len(s.blocks))
+		for _, tx := range s.mempool {
+			block.AddTransaction(tx)
+			s.txs[tx.TxHash()].height = height
+		}
+		s.mempool = nil
+		s.blocks = append(s.blocks, block)
+
+		for _, tx := range block.Transactions {
+			s.watches.notify(tx, height)
+		}
+		mined = append(mined, block)
+	}
+	return mined
+}
@@ -0,0 +1,133 @@
+package chain
+
+import (
+	"crypto/sha256"
+	"github.com/btcsuite/btcutil"
+	"github.com/cpacia/atomicswap/swap"
+	"testing"
+	"time"
+)
+
+func TestSimWalletBalance(t *testing.T) {
+	c := NewSimChain(swap.BTC)
+	w := NewSimWallet(c)
+	if _, err := w.Deposit(100000); err != nil {
+		t.Fatal(err)
+	}
+	if confirmed, unconfirmed := w.Balance(); confirmed != 0 || unconfirmed != 100000 {
+		t.Errorf("got balance %d/%d, want 0/100000", confirmed, unconfirmed)
+	}
+	c.MineBlocks(1)
+	if confirmed, unconfirmed := w.Balance(); confirmed != 100000 || unconfirmed != 0 {
+		t.Errorf("got balance %d/%d, want 100000/0", confirmed, unconfirmed)
+	}
+}
+
+func TestSimChainVerifiesSignatures(t *testing.T) {
+	c := NewSimChain(swap.BTC)
+	w := NewSimWallet(c)
+	if _, err := w.Deposit(100000); err != nil {
+		t.Fatal(err)
+	}
+	key, err := w.NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkScript, err := swap.P2PKHScript(btcutil.Hash160(key.PubKey().SerializeCompressed()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := w.FundContract(pkScript, 50000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing an output invalidates the signature
+	tampered := tx.Copy()
+	tampered.TxOut[0].Value++
+	if err := c.Broadcast(tampered); err == nil {
+		t.Error("accepted a transaction with an invalid signature")
+	}
+	if err := c.Broadcast(tx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSimChainVerifiesContractSpends(t *testing.T) {
+	c := NewSimChain(swap.BTC)
+	w := NewSimWallet(c)
+	if _, err := w.Deposit(100000); err != nil {
+		t.Fatal(err)
+	}
+	redeemKey, err := w.NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	refundKey, err := w.NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, secretHash, err := swap.NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contract := &swap.Contract{
+		SecretHash: secretHash,
+		LockTime:   time.Now().Add(time.Hour).Unix(),
+	}
+	copy(contract.RecipientHash[:], btcutil.Hash160(redeemKey.PubKey().SerializeCompressed()))
+	copy(contract.RefundHash[:], btcutil.Hash160(refundKey.PubKey().SerializeCompressed()))
+	script, err := contract.Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkScript, err := contract.PkScript()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contractTx, err := w.FundContract(pkScript, 50000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Broadcast(contractTx); err != nil {
+		t.Fatal(err)
+	}
+	c.MineBlocks(1)
+
+	wrongSecret := sha256.Sum256(secret[:])
+	redeemTx, err := swap.NewRedeemTx(swap.BTC, contractTx, script, wrongSecret[:], redeemKey, SimFeePerByte)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Broadcast(redeemTx); err == nil {
+		t.Error("accepted a redeem with the wrong secret")
+	}
+	redeemTx, err = swap.NewRedeemTx(swap.BTC, contractTx, script, secret[:], refundKey, SimFeePerByte)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Broadcast(redeemTx); err == nil {
+		t.Error("accepted a redeem signed with the refund key")
+	}
+
+	// The refund isn't valid until the locktime passes
+	refundTx, err := swap.NewRefundTx(swap.BTC, contractTx, script, refundKey, SimFeePerByte)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Broadcast(refundTx); err == nil {
+		t.Error("accepted a refund before the locktime")
+	}
+	c.AdvanceTime(2 * time.Hour)
+	c.MineBlocks(medianTimeBlocks)
+	badRefundTx, err := swap.NewRefundTx(swap.BTC, contractTx, script, redeemKey, SimFeePerByte)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Broadcast(badRefundTx); err == nil {
+		t.Error("accepted a refund signed with the redeem key")
+	}
+	if err := c.Broadcast(refundTx); err != nil {
+		t.Errorf("refund rejected: %s", err)
+	}
+}
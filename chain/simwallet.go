@@ -0,0 +1,211 @@
+package chain
+
+import (
+	"errors"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/cpacia/atomicswap/swap"
+	"sync"
+)
+
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// SimWallet is a minimal wallet holding coins on a SimChain. It implements the
+// swap engine's wallet interface so two nodes can swap against simulated chains.
+// Inputs are signed with the signature hash of the chain's coin.
+type SimWallet struct {
+	chain    *SimChain
+	keys     map[string]*btcec.PrivateKey
+	reserved map[wire.OutPoint]bool
+	locked   map[wire.OutPoint]bool
+	lock     sync.Mutex
+}
+
+func NewSimWallet(chain *SimChain) *SimWallet {
+	return &SimWallet{
+		chain:    chain,
+		keys:     make(map[string]*btcec.PrivateKey),
+		reserved: make(map[wire.OutPoint]bool),
+		locked:   make(map[wire.OutPoint]bool),
+	}
+}
+
+// NewKey returns a new key controlled by the wallet.
+func (w *SimWallet) NewKey() (*btcec.PrivateKey, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.newKey()
+}
+
+// Deposit funds the wallet with a new output of the given value on the chain.
+func (w *SimWallet) Deposit(value int64) (*wire.MsgTx, error) {
+	w.lock.Lock()
+	key, err := w.newKey()
+	w.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := p2pkhScript(key)
+	if err != nil {
+		return nil, err
+	}
+	return w.chain.Fund(pkScript, value), nil
+}
+
+// Balance returns the total value of the wallet's confirmed and unconfirmed
+// unspent outputs.
+func (w *SimWallet) Balance() (confirmed, unconfirmed int64) {
+	for op, out := range w.unspent() {
+		_, confs, err := w.chain.GetTxOut(op)
+		if err != nil {
+			continue
+		}
+		if confs > 0 {
+			confirmed += out.Value
+		} else {
+			unconfirmed += out.Value
+		}
+	}
+	return confirmed, unconfirmed
+}
+
+// FundContract builds and signs a transaction paying value to the output script
+// with any change going to a new key. The inputs used are reserved so they won't
+// be selected again before the transaction is broadcast.
+func (w *SimWallet) FundContract(pkScript []byte, value int64) (*wire.MsgTx, error) {
+	utxos := w.unspent()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	fee, _ := w.chain.EstimateFee()
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxOut(wire.NewTxOut(value, pkScript))
+
+	// Assume a P2PKH change output. 148 bytes per input and 34 bytes per output.
+	size := int64(10 + 34*2)
+	var selected []*wire.TxOut
+	var total int64
+	for op, out := range utxos {
+		if w.reserved[op] || w.locked[op] {
+			continue
+		}
+		outpoint := op
+		tx.AddTxIn(wire.NewTxIn(&outpoint, nil, nil))
+		selected = append(selected, out)
+		total += out.Value
+		size += 148
+		if total >= value+size*fee {
+			break
+		}
+	}
+	if total < value+size*fee {
+		return nil, ErrInsufficientFunds
+	}
+	if change := total - value - size*fee; change > 0 {
+		key, err := w.newKey()
+		if err != nil {
+			return nil, err
+		}
+		changeScript, err := p2pkhScript(key)
+		if err != nil {
+			return nil, err
+		}
+		tx.AddTxOut(wire.NewTxOut(change, changeScript))
+	}
+	for i, out := range selected {
+		key, err := w.keyForScript(out.PkScript)
+		if err != nil {
+			return nil, err
+		}
+		if err := swap.SignP2PKHInput(w.chain.Coin(), tx, i, out.PkScript, out.Value, key); err != nil {
+			return nil, err
+		}
+	}
+	for _, txIn := range tx.TxIn {
+		w.reserved[txIn.PreviousOutPoint] = true
+	}
+	return tx, nil
+}
+
+// Unreserve releases the inputs of a transaction which wasn't broadcast.
+func (w *SimWallet) Unreserve(tx *wire.MsgTx) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, txIn := range tx.TxIn {
+		delete(w.reserved, txIn.PreviousOutPoint)
+	}
+}
+
+// UTXO returns an unspent output worth at least value and its key.
+func (w *SimWallet) UTXO(value int64) (wire.OutPoint, *btcec.PrivateKey, error) {
+	utxos := w.unspent()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for op, out := range utxos {
+		if w.reserved[op] || w.locked[op] || out.Value < value {
+			continue
+		}
+		key, err := w.keyForScript(out.PkScript)
+		if err != nil {
+			return wire.OutPoint{}, nil, err
+		}
+		return op, key, nil
+	}
+	return wire.OutPoint{}, nil, ErrInsufficientFunds
+}
+
+// LockUTXOs replaces the set of outputs FundContract and UTXO skip over.
+func (w *SimWallet) LockUTXOs(ops []wire.OutPoint) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.locked = make(map[wire.OutPoint]bool)
+	for _, op := range ops {
+		w.locked[op] = true
+	}
+}
+
+func (w *SimWallet) newKey() (*btcec.PrivateKey, error) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := p2pkhScript(key)
+	if err != nil {
+		return nil, err
+	}
+	w.keys[string(pkScript)] = key
+	return key, nil
+}
+
+func (w *SimWallet) keyForScript(pkScript []byte) (*btcec.PrivateKey, error) {
+	key, ok := w.keys[string(pkScript)]
+	if !ok {
+		return nil, errors.New("no key for script")
+	}
+	return key, nil
+}
+
+// unspent returns the wallet's unspent outputs on the chain.
+func (w *SimWallet) unspent() map[wire.OutPoint]*wire.TxOut {
+	w.lock.Lock()
+	var scripts [][]byte
+	for script := range w.keys {
+		scripts = append(scripts, []byte(script))
+	}
+	w.lock.Unlock()
+
+	utxos := make(map[wire.OutPoint]*wire.TxOut)
+	for _, script := range scripts {
+		for op, out := range w.chain.UnspentOutputs(script) {
+			utxos[op] = out
+		}
+	}
+	return utxos
+}
+
+func p2pkhScript(key *btcec.PrivateKey) ([]byte, error) {
+	return swap.P2PKHScript(btcutil.Hash160(key.PubKey().SerializeCompressed()))
+}
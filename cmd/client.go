@@ -0,0 +1,378 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cpacia/atomicswap/api"
+	ob "github.com/cpacia/atomicswap/orderbook"
+	r "github.com/cpacia/atomicswap/repo"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// clientOptions are shared by the commands which talk to a running node over its API.
+// Unless a username and password are given the node's cookie token is read from the
+// data directory.
+type clientOptions struct {
+	DataDir string `short:"d" long:"datadir" description:"the data directory of the node, used to find its API settings"`
+	APIHost string `long:"apihost" description:"the host of the node's API, defaults to the one in the config file or localhost"`
+	APIPort int    `short:"a" long:"apiport" description:"the json API port of the node, defaults to the one in the config file"`
+	APIUser string `long:"apiuser" description:"username for the API"`
+	APIPass string `long:"apipass" description:"password for the API"`
+	JSON    bool   `long:"json" description:"print the raw JSON response"`
+}
+
+// baseURL returns the URL of the node's API and sets up the client to trust the API's
+// certificate if it's using TLS.
+func (c *clientOptions) baseURL(cfg *r.Config, client *http.Client) (string, error) {
+	host := c.APIHost
+	if host == "" {
+		host = cfg.API.Host
+	}
+	// A node listening on all interfaces can be reached on localhost
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = r.DefaultAPIHost
+	}
+	port := c.APIPort
+	if port == 0 {
+		port = cfg.API.Port
+	}
+	if port == 0 {
+		return "", errors.New("No API port to connect to. Use the -a flag or set it in the config file.")
+	}
+	if !cfg.API.TLS {
+		return "http://" + net.JoinHostPort(host, strconv.Itoa(port)), nil
+	}
+	cert, err := r.ReadAPICert(c.DataDir)
+	if err != nil {
+		return "", err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(cert) {
+		return "", errors.New("Invalid API certificate in the data directory.")
+	}
+	client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	return "https://" + net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// request makes an API call to the node and returns the response body. Errors returned
+// by the node are turned into a Go error.
+func (c *clientOptions) request(method, pth string, body interface{}) ([]byte, error) {
+	cfg, err := r.LoadConfig(c.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: time.Minute}
+	base, err := c.baseURL(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		ser, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(ser)
+	}
+	req, err := http.NewRequest(method, base+pth, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.APIUser != "" {
+		req.SetBasicAuth(c.APIUser, c.APIPass)
+	} else {
+		token, err := r.ReadAPICookie(c.DataDir)
+		if err != nil {
+			return nil, fmt.Errorf("Can't read the API cookie, is the node running with this data directory? %s", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	ser, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, apiError(resp.StatusCode, ser)
+	}
+	return ser, nil
+}
+
+// apiError turns an error response from the node into an error listing the details.
+func apiError(status int, ser []byte) error {
+	var e api.ErrorResponse
+	if err := json.Unmarshal(ser, &e); err != nil || e.Message == "" {
+		return fmt.Errorf("%d %s", status, http.StatusText(status))
+	}
+	msg := e.Message
+	var fields []string
+	for field := range e.Details {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		msg += fmt.Sprintf("\n  %s: %s", field, e.Details[field])
+	}
+	return errors.New(msg)
+}
+
+// call makes the API call and either prints the raw response, if --json was given, or
+// decodes it into out and calls print.
+func (c *clientOptions) call(method, pth string, body, out interface{}, print func()) error {
+	ser, err := c.request(method, pth, body)
+	if err != nil {
+		return err
+	}
+	if c.JSON {
+		printJSON(ser)
+		return nil
+	}
+	if out != nil && len(bytes.TrimSpace(ser)) > 0 {
+		if err := json.Unmarshal(ser, out); err != nil {
+			return err
+		}
+	}
+	print()
+	return nil
+}
+
+func printJSON(ser []byte) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, ser, "", "    "); err != nil {
+		os.Stdout.Write(ser)
+		return
+	}
+	buf.WriteTo(os.Stdout)
+	fmt.Println()
+}
+
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+}
+
+func side(buyBTC bool) string {
+	if buyBTC {
+		return "buy"
+	}
+	return "sell"
+}
+
+type PlaceOrder struct {
+	clientOptions
+	Quantity    uint64 `short:"q" long:"quantity" description:"the amount of BTC to trade in satoshis" required:"true"`
+	Price       uint64 `long:"price" description:"the price in BCH satoshis per BTC" required:"true"`
+	Buy         bool   `long:"buy" description:"buy BTC with BCH"`
+	Sell        bool   `long:"sell" description:"sell BTC for BCH"`
+	MinQuantity uint64 `long:"minquantity" description:"the smallest amount in satoshis a taker may fill"`
+	TimeInForce string `long:"tif" description:"time in force: GTC, GTT, IOC or FOK" default:"GTC"`
+	Expiry      string `long:"expiry" description:"when a GTT order expires, as a duration like 12h or an RFC3339 time"`
+}
+
+// The placeorder command publishes a new limit order from the node, or takes orders
+// from the book for IOC and FOK orders.
+func (x *PlaceOrder) Execute(args []string) error {
+	if x.Buy == x.Sell {
+		return errors.New("Use exactly one of --buy or --sell.")
+	}
+	order := struct {
+		Quantity    uint64     `json:"quantity"`
+		Price       uint64     `json:"price"`
+		BuyBTC      bool       `json:"buyBTC"`
+		MinQuantity uint64     `json:"minQuantity"`
+		TimeInForce string     `json:"timeInForce"`
+		Expiry      *time.Time `json:"expiry,omitempty"`
+	}{x.Quantity, x.Price, x.Buy, x.MinQuantity, x.TimeInForce, nil}
+	if x.Expiry != "" {
+		expiry, err := parseExpiry(x.Expiry)
+		if err != nil {
+			return err
+		}
+		order.Expiry = &expiry
+	}
+	var resp api.OrderResponse
+	return x.call("POST", "/limitorder", order, &resp, func() {
+		if resp.OrderID != "" {
+			fmt.Printf("Placed order %s to %s %d satoshis at %d\n", resp.OrderID, side(x.Buy), x.Quantity, x.Price)
+			return
+		}
+		fmt.Printf("Started %d swaps\n", len(resp.Swaps))
+		printSwaps(resp.Swaps)
+	})
+}
+
+type AmendOrder struct {
+	clientOptions
+	Quantity    uint64 `short:"q" long:"quantity" description:"the new amount of BTC in satoshis" required:"true"`
+	Price       uint64 `long:"price" description:"the new price in BCH satoshis per BTC" required:"true"`
+	MinQuantity uint64 `long:"minquantity" description:"the smallest amount in satoshis a taker may fill"`
+	Expiry      string `long:"expiry" description:"when the new order expires, as a duration like 12h or an RFC3339 time"`
+	Args        struct {
+		OrderID string `positional-arg-name:"orderID"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// The amendorder command replaces one of the node's orders with one at a new price or quantity.
+func (x *AmendOrder) Execute(args []string) error {
+	amend := struct {
+		Quantity    uint64     `json:"quantity"`
+		Price       uint64     `json:"price"`
+		MinQuantity uint64     `json:"minQuantity"`
+		Expiry      *time.Time `json:"expiry,omitempty"`
+	}{x.Quantity, x.Price, x.MinQuantity, nil}
+	if x.Expiry != "" {
+		expiry, err := parseExpiry(x.Expiry)
+		if err != nil {
+			return err
+		}
+		amend.Expiry = &expiry
+	}
+	var resp api.OrderResponse
+	return x.call("POST", "/amendorder/"+url.PathEscape(x.Args.OrderID), amend, &resp, func() {
+		fmt.Printf("Replaced order %s with %s\n", resp.Replaced, resp.OrderID)
+	})
+}
+
+// parseExpiry parses either a duration from now or an RFC3339 time.
+func parseExpiry(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid expiry %s, use a duration like 12h or an RFC3339 time.", s)
+	}
+	return t, nil
+}
+
+type CloseOrder struct {
+	clientOptions
+	Args struct {
+		OrderID string `positional-arg-name:"orderID"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// The closeorder command closes one of the node's open orders.
+func (x *CloseOrder) Execute(args []string) error {
+	return x.call("POST", "/closeorder/"+url.PathEscape(x.Args.OrderID), nil, nil, func() {
+		fmt.Printf("Closed order %s\n", x.Args.OrderID)
+	})
+}
+
+type OrderBook struct {
+	clientOptions
+	Mine bool `long:"mine" description:"only list our own orders"`
+}
+
+// The orderbook command prints the node's view of the order book.
+func (x *OrderBook) Execute(args []string) error {
+	pth := "/orderbook"
+	if x.Mine {
+		pth = "/orders/mine"
+	}
+	var orders []ob.LimitOrder
+	return x.call("GET", pth, nil, &orders, func() {
+		tw := newTable()
+		fmt.Fprintln(tw, "ORDER ID\tSIDE\tPRICE\tQUANTITY\tREMAINING\tMIN\tPEER")
+		for _, o := range orders {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%s\n", o.OrderID, side(o.BuyBTC), o.Price, o.Quantity, o.Remaining, o.MinQuantity, o.PeerID)
+		}
+		tw.Flush()
+	})
+}
+
+type Swaps struct {
+	clientOptions
+}
+
+// The swaps command prints the node's swaps.
+func (x *Swaps) Execute(args []string) error {
+	var swaps []api.SwapResponse
+	return x.call("GET", "/swaps", nil, &swaps, func() {
+		printSwaps(swaps)
+	})
+}
+
+func printSwaps(swaps []api.SwapResponse) {
+	tw := newTable()
+	fmt.Fprintln(tw, "SWAP ID\tORDER ID\tROLE\tSTATE\tSEND\tRECEIVE\tCOUNTERPARTY")
+	for _, s := range swaps {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d %s\t%d %s\t%s\n", s.SwapID, s.OrderID, s.Role, s.State,
+			s.SendAmount, s.SendCoin, s.ReceiveAmount, s.ReceiveCoin, s.Counterparty)
+	}
+	tw.Flush()
+}
+
+type Balance struct {
+	clientOptions
+}
+
+// The balance command prints the balance of the node's wallets.
+func (x *Balance) Execute(args []string) error {
+	var balances []api.BalanceResponse
+	return x.call("GET", "/balance", nil, &balances, func() {
+		tw := newTable()
+		fmt.Fprintln(tw, "COIN\tCONFIRMED\tUNCONFIRMED")
+		for _, b := range balances {
+			fmt.Fprintf(tw, "%s\t%d\t%d\n", b.Coin, b.Confirmed, b.Unconfirmed)
+		}
+		tw.Flush()
+	})
+}
+
+type Peers struct {
+	clientOptions
+}
+
+// The peers command prints the peers the node is trading with.
+func (x *Peers) Execute(args []string) error {
+	var peers []api.PeerResponse
+	return x.call("GET", "/peers", nil, &peers, func() {
+		tw := newTable()
+		fmt.Fprintln(tw, "PEER ID\tSCORE")
+		for _, p := range peers {
+			fmt.Fprintf(tw, "%s\t%d\n", p.PeerID, p.Score)
+		}
+		tw.Flush()
+	})
+}
+
+type Take struct {
+	clientOptions
+	Quantity uint64 `short:"q" long:"quantity" description:"the amount of BTC to take in satoshis, defaults to all that's left"`
+	Args     struct {
+		OrderID string `positional-arg-name:"orderID"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// The take command takes an order from the order book and starts a swap.
+func (x *Take) Execute(args []string) error {
+	pth := "/takeorder/" + url.PathEscape(x.Args.OrderID)
+	if x.Quantity > 0 {
+		pth += "?quantity=" + strconv.FormatUint(x.Quantity, 10)
+	}
+	var s api.SwapResponse
+	return x.call("POST", pth, nil, &s, func() {
+		printSwaps([]api.SwapResponse{s})
+	})
+}
@@ -3,18 +3,27 @@ package cmd
 import (
 	"context"
 	"errors"
+	"fmt"
 	api2 "github.com/cpacia/atomicswap/api"
+	"github.com/cpacia/atomicswap/chain"
 	"github.com/cpacia/atomicswap/core"
 	"github.com/cpacia/atomicswap/net"
 	"github.com/cpacia/atomicswap/net/service"
+	ob "github.com/cpacia/atomicswap/orderbook"
 	r "github.com/cpacia/atomicswap/repo"
+	"github.com/cpacia/atomicswap/swap"
+	"github.com/cpacia/atomicswap/wallet"
 	fs "github.com/libp2p/go-floodsub"
 	"github.com/libp2p/go-libp2p-kad-dht"
 	"github.com/libp2p/go-libp2p-kad-dht/opts"
 	"github.com/libp2p/go-libp2p-protocol"
 	"github.com/libp2p/go-libp2p-record"
 	"github.com/op/go-logging"
+	"github.com/tyler-smith/go-bip39"
+	"io/ioutil"
+	stdnet "net"
 	"os"
+	"strings"
 )
 
 var stdoutLogFormat = logging.MustStringFormatter(
@@ -24,11 +33,44 @@ var stdoutLogFormat = logging.MustStringFormatter(
 var log = logging.MustGetLogger("cmd")
 
 type Start struct {
-	DataDir string `short:"d" long:"datadir" description:"specify the data directory to be used"`
-	Port    int    `short:"p" long:"port" description:"the port to use" default:"0"`
-	APIPort int    `short:"a" long:"apiport" description:"the json API port to use" default:"0"`
+	DataDir  string `short:"d" long:"datadir" description:"specify the data directory to be used"`
+	Port     int    `short:"p" long:"port" description:"the port to listen on, overrides the listen addresses in the config file" default:"0"`
+	APIPort  int    `short:"a" long:"apiport" description:"the json API port to use" default:"0"`
+	GRPCPort int    `short:"g" long:"grpcport" description:"the gRPC API port to use, 0 to disable it" default:"0"`
+
+	Bootstrap []string `long:"bootstrap" description:"a bootstrap peer multiaddr, can be used more than once, overrides the config file"`
+	LogLevel  string   `long:"loglevel" description:"the log level: CRITICAL, ERROR, WARNING, NOTICE, INFO or DEBUG"`
+
+	APIHost string `long:"apihost" description:"the address the APIs listen on, defaults to localhost"`
+	APIUser string `long:"apiuser" description:"username to allow into the APIs alongside the cookie token"`
+	APIPass string `long:"apipass" description:"password for the API username"`
+	APITLS  bool   `long:"apitls" description:"serve the APIs over TLS with a self-signed certificate"`
+
+	BTCRPC     string `long:"btcrpc" description:"host:port of the bitcoind JSON-RPC server used for BTC"`
+	BTCRPCUser string `long:"btcrpcuser" description:"username for the BTC JSON-RPC server"`
+	BTCRPCPass string `long:"btcrpcpass" description:"password for the BTC JSON-RPC server"`
+	BCHRPC     string `long:"bchrpc" description:"host:port of the Bitcoin ABC JSON-RPC server used for BCH"`
+	BCHRPCUser string `long:"bchrpcuser" description:"username for the BCH JSON-RPC server"`
+	BCHRPCPass string `long:"bchrpcpass" description:"password for the BCH JSON-RPC server"`
+
+	FeeRate    *int64 `long:"feerate" description:"the fee rate in satoshis per byte to use when the chain backend has no estimate"`
+	MinFeeRate *int64 `long:"minfeerate" description:"the lowest fee rate in satoshis per byte to pay, 0 for no limit"`
+	MaxFeeRate *int64 `long:"maxfeerate" description:"the highest fee rate in satoshis per byte to pay, 0 for no limit"`
+
+	MaxOrders     *int    `long:"maxorders" description:"the most orders to hold in the order book, 0 for no limit"`
+	MaxPeerOrders *int    `long:"maxpeerorders" description:"the most open orders to accept from a single peer, 0 for no limit"`
+	MinQuantity   *uint64 `long:"minquantity" description:"the smallest order quantity in satoshis to accept"`
+
+	WalletPasswordFile string `long:"walletpasswordfile" description:"a file holding the password used to encrypt the wallet seed, otherwise it's read from the ATOMICSWAP_WALLET_PASSWORD environment variable"`
+	Testnet            bool   `long:"testnet" description:"use the test networks"`
+	Regtest            bool   `long:"regtest" description:"use the regression test networks"`
 }
 
+// WalletPasswordEnv is the environment variable the wallet password is read from
+// when no password file is given. We don't take it as a flag so it doesn't end up
+// in the process list or the shell history.
+const WalletPasswordEnv = "ATOMICSWAP_WALLET_PASSWORD"
+
 // The start command will start up our atomic swap node, connect to the p2p network, and download the order book, and initialize the API
 func (x *Start) Execute(args []string) error {
 	// First create our repo which is where we'll store or app related data
@@ -38,23 +80,43 @@ func (x *Start) Execute(args []string) error {
 		return err
 	}
 
+	config := repo.Config()
+
 	// Force the user to select a port. We could just use the default but given that this is primarily
 	// a demo we'll likely be running multiple nodes on localhost.
-	if x.Port == 0 {
-		return errors.New("You must specify a port when starting up. Use the -p flag.")
+	listenAddrs := config.ListenAddrs
+	if x.Port != 0 {
+		listenAddrs = []string{fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", x.Port)}
+	}
+	if len(listenAddrs) == 0 {
+		return errors.New("You must specify a port when starting up. Use the -p flag or set listenAddrs in the config file.")
+	}
+	if x.APIPort == 0 {
+		x.APIPort = config.API.Port
 	}
 	if x.APIPort == 0 {
-		return errors.New("You must specify an API port when starting up. Use the -a flag.")
+		return errors.New("You must specify an API port when starting up. Use the -a flag or set it in the config file.")
+	}
+	if x.GRPCPort == 0 {
+		x.GRPCPort = config.API.GRPCPort
+	}
+	if len(x.Bootstrap) > 0 {
+		if err := repo.SetBootstrapPeers(x.Bootstrap); err != nil {
+			return err
+		}
 	}
 
 	// Set up logging
-	backendStdout := logging.NewLogBackend(os.Stdout, "", 0)
-	backendStdoutFormatter := logging.NewBackendFormatter(backendStdout, stdoutLogFormat)
-	logging.SetBackend(backendStdoutFormatter)
+	if x.LogLevel != "" {
+		config.Logging.Level = x.LogLevel
+	}
+	if err := setupLogging(config.Logging); err != nil {
+		return err
+	}
 
 	// Build our host. This is the core of libp2p. We're going to initialize it with with the default
 	// transports, muxers, security, and peerstore.
-	peerHost, err := net.NewPeerHost(x.Port, repo)
+	peerHost, err := net.NewPeerHost(listenAddrs, repo)
 	if err != nil {
 		return err
 	}
@@ -83,17 +145,231 @@ func (x *Start) Execute(args []string) error {
 		return err
 	}
 
-	node := core.NewAtomicSwapNode(repo, peerHost, routing, floodsub)
-	node.StartOnlineServices()
+	node := core.NewAtomicSwapNode(repo, peerHost, routing, floodsub, x.network())
+	x.setLimits(&config.Limits)
+	node.OrderBook().SetLimits(ob.Limits{
+		MaxOrders:        config.Limits.MaxOrders,
+		MaxOrdersPerPeer: config.Limits.MaxOrdersPerPeer,
+		MinQuantity:      config.Limits.MinQuantity,
+	})
+
+	// Load our wallet seed. Both coins' wallets are derived from it.
+	password, err := x.walletPassword()
+	if err != nil {
+		return err
+	}
+	mnemonic, created, err := repo.Mnemonic(password)
+	if err != nil {
+		return err
+	}
+	if created {
+		// The recovery phrase only goes to the terminal, never to the log file
+		fmt.Printf("Created a new wallet. Write down your recovery phrase, it won't be shown again:\n\n%s\n\n", mnemonic)
+	}
+	seed := bip39.NewSeed(mnemonic, "")
+
+	// Connect to the full nodes we'll use to broadcast and watch swap transactions
+	// and start up a wallet for each coin we have one for.
+	x.setChainConfig(repo.ChainConfig())
+	x.setFees(&config.Fees)
+	feePolicy := config.Fees.Policy()
+	backends := map[swap.Coin]r.RPCConfig{
+		swap.BTC: repo.ChainConfig().BTC,
+		swap.BCH: repo.ChainConfig().BCH,
+	}
+	for coin, cfg := range backends {
+		if cfg.Host == "" {
+			log.Warningf("No %s JSON-RPC server configured, %s swaps are disabled", coin, coin)
+			continue
+		}
+		backend := chain.WithFeePolicy(chain.NewRPCBackend(cfg.Host, cfg.User, cfg.Password), feePolicy)
+		node.SetChainBackend(coin, backend)
+
+		w, err := wallet.NewWallet(coin, x.network(), seed, backend, repo.Datastore())
+		if err != nil {
+			return err
+		}
+		if err := w.Start(); err != nil {
+			return err
+		}
+		node.SetWallet(coin, w)
+	}
 
-	ws := service.NewWireService(node.MsgChan(), node.OrderBook(), peerHost)
+	ws := service.NewWireService(node.MsgChan(), node.OrderBook(), peerHost, node.Scorer())
 	node.SetWireService(ws)
 
+	// This also picks up any swaps that were in progress when we last shut down
+	if err := node.StartOnlineServices(); err != nil {
+		return err
+	}
+
 	log.Infof("Listening on %s, peerID: %s\n", peerHost.Addrs()[0], peerHost.ID().Pretty())
 
-	jsonAPI := api2.NewAPIServer(node)
-	jsonAPI.Serve(x.APIPort)
+	apiOpts, err := x.apiOptions(repo)
+	if err != nil {
+		return err
+	}
+
+	if x.GRPCPort != 0 {
+		grpcAPI, err := api2.NewGRPCServer(node, apiOpts)
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := grpcAPI.Serve(x.GRPCPort); err != nil {
+				log.Errorf("gRPC server stopped: %s", err)
+			}
+		}()
+	}
+
+	jsonAPI := api2.NewAPIServer(node, apiOpts)
+	return jsonAPI.Serve(x.APIPort)
+}
+
+// apiOptions builds the API settings from the config file and flags. The cookie token
+// and TLS certificate are created in the data directory if they don't exist yet.
+func (x *Start) apiOptions(repo *r.Repo) (api2.Options, error) {
+	cfg := repo.Config().API
+	if x.APIHost != "" {
+		cfg.Host = x.APIHost
+	}
+	if cfg.Host == "" {
+		cfg.Host = r.DefaultAPIHost
+	}
+	if x.APIUser != "" {
+		cfg.Username = x.APIUser
+	}
+	if x.APIPass != "" {
+		cfg.Password = x.APIPass
+	}
+	if x.APITLS {
+		cfg.TLS = true
+	}
+	if cfg.Username != "" && cfg.Password == "" {
+		return api2.Options{}, errors.New("An API password is required when an API username is set.")
+	}
+	if ip := stdnet.ParseIP(cfg.Host); cfg.Host != "localhost" && (ip == nil || !ip.IsLoopback()) && !cfg.TLS {
+		log.Warningf("The API is listening on %s without TLS, the auth token can be read by anyone on the network", cfg.Host)
+	}
+
+	token, err := repo.APICookie()
+	if err != nil {
+		return api2.Options{}, err
+	}
+	opts := api2.Options{
+		Host:     cfg.Host,
+		Token:    token,
+		Username: cfg.Username,
+		Password: cfg.Password,
+	}
+	if cfg.TLS {
+		opts.CertFile, opts.KeyFile, err = repo.APICert(cfg.Host)
+		if err != nil {
+			return api2.Options{}, err
+		}
+	}
+	return opts, nil
+}
+
+// walletPassword reads the wallet password from the password file if one was given
+// or else the environment.
+func (x *Start) walletPassword() (string, error) {
+	if x.WalletPasswordFile != "" {
+		ser, err := ioutil.ReadFile(x.WalletPasswordFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(ser), "\r\n"), nil
+	}
+	if password := os.Getenv(WalletPasswordEnv); password != "" {
+		return password, nil
+	}
+	return "", fmt.Errorf("You must set a wallet password. Use the --walletpasswordfile flag or the %s environment variable.", WalletPasswordEnv)
+}
+
+func (x *Start) network() swap.Network {
+	switch {
+	case x.Regtest:
+		return swap.RegTest
+	case x.Testnet:
+		return swap.TestNet
+	default:
+		return swap.MainNet
+	}
+}
+
+// setChainConfig overrides the repo's chain backend settings with any set on
+// the command line.
+func (x *Start) setChainConfig(cfg *r.ChainConfig) {
+	if x.BTCRPC != "" {
+		cfg.BTC.Host = x.BTCRPC
+	}
+	if x.BTCRPCUser != "" {
+		cfg.BTC.User = x.BTCRPCUser
+	}
+	if x.BTCRPCPass != "" {
+		cfg.BTC.Password = x.BTCRPCPass
+	}
+	if x.BCHRPC != "" {
+		cfg.BCH.Host = x.BCHRPC
+	}
+	if x.BCHRPCUser != "" {
+		cfg.BCH.User = x.BCHRPCUser
+	}
+	if x.BCHRPCPass != "" {
+		cfg.BCH.Password = x.BCHRPCPass
+	}
+}
+
+// setFees overrides the fee policy from the config file with any set on the command line.
+func (x *Start) setFees(cfg *r.FeeConfig) {
+	if x.FeeRate != nil {
+		cfg.Default = *x.FeeRate
+	}
+	if x.MinFeeRate != nil {
+		cfg.Min = *x.MinFeeRate
+	}
+	if x.MaxFeeRate != nil {
+		cfg.Max = *x.MaxFeeRate
+	}
+}
 
-	// Now we're listening let's just hang out here.
+// setLimits overrides the order book limits from the config file with any set on the
+// command line.
+func (x *Start) setLimits(cfg *r.LimitsConfig) {
+	if x.MaxOrders != nil {
+		cfg.MaxOrders = *x.MaxOrders
+	}
+	if x.MaxPeerOrders != nil {
+		cfg.MaxOrdersPerPeer = *x.MaxPeerOrders
+	}
+	if x.MinQuantity != nil {
+		cfg.MinQuantity = *x.MinQuantity
+	}
+}
+
+// setupLogging logs to stdout at the configured level, with any per module levels.
+func setupLogging(cfg r.LoggingConfig) error {
+	backendStdout := logging.NewLogBackend(os.Stdout, "", 0)
+	backendStdoutFormatter := logging.NewBackendFormatter(backendStdout, stdoutLogFormat)
+	leveled := logging.AddModuleLevel(backendStdoutFormatter)
+
+	level := logging.DEBUG
+	if cfg.Level != "" {
+		var err error
+		level, err = logging.LogLevel(cfg.Level)
+		if err != nil {
+			return fmt.Errorf("invalid log level %s", cfg.Level)
+		}
+	}
+	leveled.SetLevel(level, "")
+	for module, l := range cfg.Modules {
+		moduleLevel, err := logging.LogLevel(l)
+		if err != nil {
+			return fmt.Errorf("invalid log level %s for %s", l, module)
+		}
+		leveled.SetLevel(moduleLevel, module)
+	}
+	logging.SetBackend(leveled)
 	return nil
 }
@@ -0,0 +1,100 @@
+package core
+
+import (
+	ob "github.com/cpacia/atomicswap/orderbook"
+	"github.com/libp2p/go-libp2p-peer"
+	"time"
+)
+
+// EventBufferSize is how many events a subscriber can fall behind before we start
+// dropping events for it.
+const EventBufferSize = 256
+
+type EventType string
+
+const (
+	EventOrderAdded       EventType = "orderAdded"
+	EventOrderClosed      EventType = "orderClosed"
+	EventOrderFilled      EventType = "orderFilled"
+	EventOrderAmended     EventType = "orderAmended"
+	EventPeerConnected    EventType = "peerConnected"
+	EventPeerDisconnected EventType = "peerDisconnected"
+	EventSwapState        EventType = "swapState"
+)
+
+// EventTypes lists every type of event the node publishes.
+var EventTypes = []EventType{
+	EventOrderAdded,
+	EventOrderClosed,
+	EventOrderFilled,
+	EventOrderAmended,
+	EventPeerConnected,
+	EventPeerDisconnected,
+	EventSwapState,
+}
+
+// Event is something that happened on the node. Only the fields for the type of
+// event are set. For swap events State is the state the swap moved to; the swap
+// itself may have moved on by the time the event is read.
+type Event struct {
+	Type  EventType
+	Time  time.Time
+	Order *ob.LimitOrder
+	Peer  peer.ID
+	Swap  *Swap
+	State SwapState
+}
+
+// SubscribeEvents returns a channel which receives the node's events. If the
+// subscriber falls too far behind events are dropped rather than blocking the
+// node. Call the returned function to unsubscribe.
+func (n *AtomicSwapNode) SubscribeEvents() (<-chan Event, func()) {
+	ch := make(chan Event, EventBufferSize)
+	n.eventLock.Lock()
+	n.eventSubs[ch] = struct{}{}
+	n.eventLock.Unlock()
+
+	unsubscribe := func() {
+		n.eventLock.Lock()
+		defer n.eventLock.Unlock()
+		if _, ok := n.eventSubs[ch]; ok {
+			delete(n.eventSubs, ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe
+}
+
+func (n *AtomicSwapNode) publishEvent(e Event) {
+	e.Time = time.Now()
+	n.eventLock.Lock()
+	defer n.eventLock.Unlock()
+	for ch := range n.eventSubs {
+		select {
+		case ch <- e:
+		default:
+			log.Warningf("Event subscriber is falling behind, dropping %s event", e.Type)
+		}
+	}
+}
+
+// publishBookUpdate publishes the event for a change to the order book, if there was one.
+func (n *AtomicSwapNode) publishBookUpdate(u *ob.BookUpdate) {
+	if u == nil {
+		return
+	}
+	e := Event{Order: &u.Order}
+	switch u.Type {
+	case ob.OrderAdded:
+		e.Type = EventOrderAdded
+	case ob.OrderClosed:
+		e.Type = EventOrderClosed
+	case ob.OrderFilled:
+		e.Type = EventOrderFilled
+	case ob.OrderAmended:
+		e.Type = EventOrderAmended
+	default:
+		return
+	}
+	n.publishEvent(e)
+}
@@ -3,16 +3,22 @@ package core
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/cpacia/atomicswap/chain"
+	"github.com/cpacia/atomicswap/net/score"
 	"github.com/cpacia/atomicswap/net/service"
 	ob "github.com/cpacia/atomicswap/orderbook"
 	"github.com/cpacia/atomicswap/pb"
 	r "github.com/cpacia/atomicswap/repo"
+	"github.com/cpacia/atomicswap/swap"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/ipfs/go-cid"
 	fs "github.com/libp2p/go-floodsub"
 	"github.com/libp2p/go-libp2p-host"
 	"github.com/libp2p/go-libp2p-kad-dht"
+	inet "github.com/libp2p/go-libp2p-net"
 	"github.com/libp2p/go-libp2p-peer"
 	pstore "github.com/libp2p/go-libp2p-peerstore"
 	"github.com/multiformats/go-multihash"
@@ -20,7 +26,6 @@ import (
 	"io"
 	"sync"
 	"time"
-	"errors"
 )
 
 var (
@@ -28,6 +33,11 @@ var (
 	Topic *cid.Cid
 )
 
+var (
+	ErrNotMyOrder = errors.New("order is not owned by this node")
+	ErrOwnOrder   = errors.New("cannot take our own order")
+)
+
 const (
 	ReSubscribeInterval     = time.Hour
 	ReconnectInterval       = time.Minute
@@ -55,14 +65,29 @@ type removePeer struct {
 	peerID peer.ID
 }
 
+// Order messages carry the peer they came from so it can be penalized if the
+// order book rejects them. It's empty for our own messages and for ones from
+// pubsub, where we can't tell which peer sent them.
 type newOrder struct {
 	serializedMessage []byte
-	mine bool
+	mine              bool
+	from              peer.ID
+	// done, if set, receives the result of processing the order
+	done chan error
 }
 
 type closeOrder struct {
 	serializedMessage []byte
-	mine bool
+	mine              bool
+	from              peer.ID
+	// done, if set, receives the result of processing the close
+	done chan error
+}
+
+type orderUpdate struct {
+	serializedMessage []byte
+	mine              bool
+	from              peer.ID
 }
 
 // This struct contains the relevant components of our node that we'll need
@@ -75,19 +100,56 @@ type AtomicSwapNode struct {
 	msgChan       chan interface{}
 	connectedSubs map[peer.ID]bool
 	orderBook     *ob.OrderBook
+	scorer        *score.Scorer
 	wireService   *service.WireService
+	wallets       map[swap.Coin]swap.Wallet
+	chains        map[swap.Coin]chain.ChainBackend
+	swaps         map[string]*Swap
+	swapLock      sync.RWMutex
+	fillLock      sync.Mutex
+	utxoLock      sync.Mutex
+	eventSubs     map[chan Event]struct{}
+	eventLock     sync.Mutex
+	network       swap.Network
 }
 
-func NewAtomicSwapNode(repo *r.Repo, peerHost host.Host, routing *dht.IpfsDHT, floodsub *fs.PubSub) *AtomicSwapNode {
-	return &AtomicSwapNode{
+func NewAtomicSwapNode(repo *r.Repo, peerHost host.Host, routing *dht.IpfsDHT, floodsub *fs.PubSub, network swap.Network) *AtomicSwapNode {
+	n := &AtomicSwapNode{
 		repo:          repo,
+		network:       network,
 		peerHost:      peerHost,
 		routing:       routing,
 		floodsub:      floodsub,
 		msgChan:       make(chan interface{}),
 		connectedSubs: make(map[peer.ID]bool),
-		orderBook:     ob.NewOrderBook(),
+		orderBook:     ob.NewOrderBook(repo.Datastore(), network),
+		scorer:        score.NewScorer(repo.Datastore()),
+		wallets:       make(map[swap.Coin]swap.Wallet),
+		chains:        make(map[swap.Coin]chain.ChainBackend),
+		swaps:         make(map[string]*Swap),
+		eventSubs:     make(map[chan Event]struct{}),
 	}
+
+	n.orderBook.SetPenaltyHandler(n.scorer.PenalizeError)
+
+	// Orders saved by older versions have to be signed again before we announce them
+	n.orderBook.ResignOrders(repo.PrivKey())
+
+	// Drop banned peers as soon as they're banned and whenever they try to connect to us.
+	n.scorer.SetBanHandler(func(p peer.ID) {
+		if err := n.peerHost.Network().ClosePeer(p); err != nil {
+			log.Errorf("Error disconnecting banned peer %s: %s", p.Pretty(), err)
+		}
+	})
+	n.peerHost.Network().Notify(&inet.NotifyBundle{
+		ConnectedF: func(_ inet.Network, c inet.Conn) {
+			if n.scorer.Banned(c.RemotePeer()) {
+				log.Debugf("Refusing connection from banned peer %s", c.RemotePeer().Pretty())
+				go c.Close()
+			}
+		},
+	})
+	return n
 }
 
 func (n *AtomicSwapNode) MsgChan() chan interface{} {
@@ -98,12 +160,30 @@ func (n *AtomicSwapNode) SetWireService(ws *service.WireService) {
 	n.wireService = ws
 }
 
+func (n *AtomicSwapNode) Scorer() *score.Scorer {
+	return n.scorer
+}
+
 // Here we are going to set self as a subscriber in the dht and query the dht for other
 // subscribers and open connections to a few of them.
-func (n *AtomicSwapNode) StartOnlineServices() {
+//
+// Our swaps are loaded before the message handler starts so no swap message is handled
+// before the swap it's for is known, and the ones in progress are resumed before we
+// go online. The wire service must be set first as resuming may resend swap messages.
+func (n *AtomicSwapNode) StartOnlineServices() error {
+	if err := n.loadSwaps(); err != nil {
+		return err
+	}
+	n.utxoLock.Lock()
+	n.lockOrderUTXOs("")
+	n.utxoLock.Unlock()
+	go n.messageHandler()
+	n.resumeSwaps()
+
 	go n.subscribeTopic()
 	go n.connectToSubscribers()
-	go n.messageHandler()
+	go n.watchdog()
+	return nil
 }
 
 // This is the main loop which handles adding and removing of peers and adding and removing
@@ -115,57 +195,148 @@ func (n *AtomicSwapNode) messageHandler() {
 		case m := <-n.msgChan:
 			switch msg := m.(type) {
 			case addPeer:
-				n.connectedSubs[msg.peerID] = true
+				if !n.connectedSubs[msg.peerID] {
+					n.connectedSubs[msg.peerID] = true
+					n.publishEvent(Event{Type: EventPeerConnected, Peer: msg.peerID})
+				}
 			case removePeer:
 				if _, ok := n.connectedSubs[msg.peerID]; ok {
 					log.Infof("Lost subscriber peer %s", msg.peerID.Pretty())
 					delete(n.connectedSubs, msg.peerID)
+					n.publishEvent(Event{Type: EventPeerDisconnected, Peer: msg.peerID})
 				}
 			case newOrder:
-				n.orderBook.ProcessNewLimitOrder(msg.serializedMessage, msg.mine)
+				u, err := n.orderBook.ProcessNewLimitOrder(msg.serializedMessage, msg.mine)
+				n.penalize(msg.from, err)
+				n.publishBookUpdate(u)
+				if msg.done != nil {
+					msg.done <- err
+				}
 			case closeOrder:
-				n.orderBook.ProcessCloseOrder(msg.serializedMessage, msg.mine)
+				u, err := n.orderBook.ProcessCloseOrder(msg.serializedMessage, msg.mine, msg.from)
+				n.penalize(msg.from, err)
+				n.publishBookUpdate(u)
+				if msg.done != nil {
+					msg.done <- err
+				}
+			case orderUpdate:
+				u, err := n.orderBook.ProcessOrderUpdate(msg.serializedMessage, msg.mine)
+				n.penalize(msg.from, err)
+				n.publishBookUpdate(u)
+			case ob.BookUpdate:
+				n.publishBookUpdate(&msg)
+			case service.SwapMessage:
+				// Swaps can block on the wallet so each message is handled in its own goroutine.
+				go n.handleSwapMessage(msg.Peer, msg.Message)
 			}
 		}
 	}
 }
 
-func (n *AtomicSwapNode) PublishLimitOrder(quantity, price uint64, buyBTC bool) error {
-	ts, err := ptypes.TimestampProto(time.Now().Add(time.Hour * 24 * 30))
+// penalize lowers the score of the peer a rejected order message came from.
+func (n *AtomicSwapNode) penalize(from peer.ID, err error) {
+	if err == nil || from == "" || from == n.peerHost.ID() {
+		return
+	}
+	n.scorer.PenalizeError(from, err)
+}
+
+// PublishLimitOrder signs and publishes a new order. Takers may fill it in pieces no
+// smaller than minQuantity; zero means any size. The order expires at the expiry, which
+// can't be more than MaxOrderDuration away; a zero expiry uses the max. The order is
+// added to our own book before it's published and the order ID and order as stored in
+// the book are returned.
+func (n *AtomicSwapNode) PublishLimitOrder(quantity, price uint64, buyBTC bool, minQuantity uint64, expiry time.Time) (string, ob.LimitOrder, error) {
+	orderID, order, msg, err := n.addLimitOrder(quantity, price, buyBTC, minQuantity, expiry)
 	if err != nil {
-		return err
+		return "", ob.LimitOrder{}, err
+	}
+	if err := n.publish(msg); err != nil {
+		return "", ob.LimitOrder{}, err
+	}
+	return orderID, order, nil
+}
+
+// addLimitOrder signs a new order and adds it to our own book. It returns the order
+// and the message to publish it with.
+func (n *AtomicSwapNode) addLimitOrder(quantity, price uint64, buyBTC bool, minQuantity uint64, expiry time.Time) (string, ob.LimitOrder, []byte, error) {
+	expiry, err := n.checkOrderTerms(quantity, minQuantity, expiry)
+	if err != nil {
+		return "", ob.LimitOrder{}, nil, err
+	}
+	ts, err := ptypes.TimestampProto(expiry)
+	if err != nil {
+		return "", ob.LimitOrder{}, nil, err
 	}
 	lopb := &pb.LimitOrder{
-		PeerID:   n.peerHost.ID().Pretty(),
-		Expiry:   ts,
-		Quantity: quantity,
-		Price:    price,
-		BuyBTC:   buyBTC,
+		PeerID:      n.peerHost.ID().Pretty(),
+		Expiry:      ts,
+		Quantity:    quantity,
+		Price:       price,
+		BuyBTC:      buyBTC,
+		MinQuantity: minQuantity,
 	}
 
-	// TODO: add signed UTXO to limit order
+	// Attach a UTXO large enough to fund our side of the swap. Other peers will
+	// reject the order if it's spent or we can't sign for it.
+	// The UTXO stays locked in the wallet while the order is open.
+	coin, amount := swap.MakerFunds(buyBTC, quantity, price)
+	w, err := n.wallet(coin)
+	if err != nil {
+		return "", ob.LimitOrder{}, nil, err
+	}
+	n.utxoLock.Lock()
+	defer n.utxoLock.Unlock()
+	n.lockOrderUTXOs("")
+	op, key, err := w.UTXO(amount)
+	if err != nil {
+		return "", ob.LimitOrder{}, nil, err
+	}
+	utxoSig, err := swap.SignUTXO(lopb.PeerID, op, key)
+	if err != nil {
+		return "", ob.LimitOrder{}, nil, err
+	}
+	lopb.Utxo = &pb.LimitOrder_SignedUTXO{
+		Outpoint:  swap.EncodeOutPoint(op),
+		Signature: utxoSig,
+	}
 
 	ser, err := proto.Marshal(lopb)
 	if err != nil {
-		return err
+		return "", ob.LimitOrder{}, nil, err
+	}
+	id, err := (&ob.LimitOrder{LimitOrder: lopb}).ID()
+	if err != nil {
+		return "", ob.LimitOrder{}, nil, err
 	}
 	privKey := n.repo.PrivKey()
-	signature, err := privKey.Sign(ser)
+	header, signature, err := ob.Sign(privKey, pb.Message_LimitOrder, n.network, ser)
 	if err != nil {
-		return err
+		return "", ob.LimitOrder{}, nil, err
 	}
 	signed := &pb.SignedLimitOrder{
 		SerializedLimitOrder: ser,
 		Signature:            signature,
+		Header:               header,
 	}
 	serializedWithSig, err := proto.Marshal(signed)
 	if err != nil {
-		return err
+		return "", ob.LimitOrder{}, nil, err
 	}
-	n.msgChan <- newOrder{serializedMessage: serializedWithSig, mine: true}
+	done := make(chan error, 1)
+	n.msgChan <- newOrder{serializedMessage: serializedWithSig, mine: true, done: done}
+	if err := <-done; err != nil {
+		return "", ob.LimitOrder{}, nil, err
+	}
+	n.lockOrderUTXOs("")
+	order, _, err := n.orderBook.GetOrder(id.String())
+	if err != nil {
+		return "", ob.LimitOrder{}, nil, err
+	}
+	order.OrderID = id.String()
 	any, err := ptypes.MarshalAny(signed)
 	if err != nil {
-		return err
+		return "", ob.LimitOrder{}, nil, err
 	}
 	m := &pb.Message{
 		MessageType: pb.Message_LimitOrder,
@@ -173,32 +344,54 @@ func (n *AtomicSwapNode) PublishLimitOrder(quantity, price uint64, buyBTC bool)
 	}
 	serializedMessage, err := proto.Marshal(m)
 	if err != nil {
-		return err
+		return "", ob.LimitOrder{}, nil, err
 	}
-	return n.floodsub.Publish("OrderBook", serializedMessage)
+	return id.String(), order, serializedMessage, nil
 }
 
+// checkOrderTerms checks the terms of an order we're placing and returns its expiry.
+func (n *AtomicSwapNode) checkOrderTerms(quantity, minQuantity uint64, expiry time.Time) (time.Time, error) {
+	expiry, err := orderExpiry(expiry)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if minQuantity > quantity {
+		return time.Time{}, errors.New("minimum quantity exceeds order quantity")
+	}
+	if limits := n.orderBook.Limits(); quantity < limits.MinQuantity {
+		return time.Time{}, fmt.Errorf("order quantity must be at least %d", limits.MinQuantity)
+	}
+	return expiry, nil
+}
+
+// CloseOrder removes one of our orders from our book, which unlocks its UTXO, and
+// publishes the close.
 func (n *AtomicSwapNode) CloseOrder(orderID string) error {
 	_, mine, err := n.orderBook.GetOrder(orderID)
 	if err != nil {
 		return err
 	}
 	if !mine {
-		return errors.New("order is not owned by this node")
+		return ErrNotMyOrder
 	}
-	sig, err := n.repo.PrivKey().Sign([]byte(orderID))
+	header, sig, err := ob.Sign(n.repo.PrivKey(), pb.Message_OrderClose, n.network, []byte(orderID))
 	if err != nil {
 		return err
 	}
 	cpb := &pb.SignedRemoveOrder{
-		OrderID: orderID,
+		OrderID:   orderID,
 		Signature: sig,
+		Header:    header,
 	}
 	serializedWithSig, err := proto.Marshal(cpb)
 	if err != nil {
 		return err
 	}
-	n.msgChan <- closeOrder{serializedMessage: serializedWithSig, mine: true}
+	done := make(chan error, 1)
+	n.msgChan <- closeOrder{serializedMessage: serializedWithSig, mine: true, done: done}
+	if err := <-done; err != nil {
+		return err
+	}
 	any, err := ptypes.MarshalAny(cpb)
 	if err != nil {
 		return err
@@ -211,9 +404,81 @@ func (n *AtomicSwapNode) CloseOrder(orderID string) error {
 	if err != nil {
 		return err
 	}
-	return n.floodsub.Publish("OrderBook", serializedMessage)
+	return n.publish(serializedMessage)
+
+}
+
+// AmendOrder changes the remaining quantity of one of our open orders. The order
+// can only be shrunk below its original quantity as that's what our UTXO proof covers.
+func (n *AtomicSwapNode) AmendOrder(orderID string, remaining uint64) error {
+	order, mine, err := n.orderBook.GetOrder(orderID)
+	if err != nil {
+		return err
+	}
+	if !mine {
+		return ErrNotMyOrder
+	}
+	if remaining > order.Quantity {
+		return errors.New("remaining quantity exceeds order quantity")
+	}
+	if remaining == 0 {
+		return n.CloseOrder(orderID)
+	}
+	return n.publishOrderUpdate(orderID, order, pb.OrderUpdate_Amend, remaining, "")
+}
+
+// publishOrderUpdate signs an update to one of our orders and publishes it so
+// other peers' order books reflect the remaining quantity.
+func (n *AtomicSwapNode) publishOrderUpdate(orderID string, order ob.LimitOrder, t pb.OrderUpdate_UpdateType, remaining uint64, swapID string) error {
+	update := &pb.OrderUpdate{
+		OrderID:   orderID,
+		Type:      t,
+		Remaining: remaining,
+		Sequence:  order.Sequence + 1,
+		SwapID:    swapID,
+	}
+	ser, err := proto.Marshal(update)
+	if err != nil {
+		return err
+	}
+	header, sig, err := ob.Sign(n.repo.PrivKey(), pb.Message_OrderUpdate, n.network, ser)
+	if err != nil {
+		return err
+	}
+	signed := &pb.SignedOrderUpdate{
+		SerializedOrderUpdate: ser,
+		Signature:             sig,
+		Header:                header,
+	}
+	serializedWithSig, err := proto.Marshal(signed)
+	if err != nil {
+		return err
+	}
+	n.msgChan <- orderUpdate{serializedMessage: serializedWithSig, mine: true}
+	any, err := ptypes.MarshalAny(signed)
+	if err != nil {
+		return err
+	}
+	m := &pb.Message{
+		MessageType: pb.Message_OrderUpdate,
+		Payload:     any,
+	}
+	serializedMessage, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return n.publish(serializedMessage)
+}
 
+// publish sends the message to the order book topic. A node that was never put
+// online, like the ones in the tests, has no pubsub and the message goes nowhere.
+func (n *AtomicSwapNode) publish(msg []byte) error {
+	if n.floodsub == nil {
+		return nil
+	}
+	return n.floodsub.Publish("OrderBook", msg)
 }
+
 func (n *AtomicSwapNode) subscribeTopic() {
 	go n.setSelfAsSubscriber()
 
@@ -230,17 +495,25 @@ func (n *AtomicSwapNode) subscribeTopic() {
 			log.Error(err)
 			return
 		}
+		// Floodsub doesn't tell us which peer relayed the message and anyone can
+		// put any peer in the from field, so pubsub messages aren't scored. Peers
+		// are only scored for what they send us directly over the wire service.
+		if msg.GetFrom() == n.peerHost.ID() {
+			continue
+		}
 		mpb := new(pb.Message)
 		err = proto.Unmarshal(msg.Data, mpb)
-		if err != nil {
-			log.Error(err)
-			return
+		if err != nil || mpb.Payload == nil {
+			log.Errorf("Malformed pubsub message claiming to be from %s", msg.GetFrom().Pretty())
+			continue
 		}
 		switch mpb.MessageType {
 		case pb.Message_LimitOrder:
 			n.msgChan <- newOrder{serializedMessage: mpb.Payload.Value}
 		case pb.Message_OrderClose:
 			n.msgChan <- closeOrder{serializedMessage: mpb.Payload.Value}
+		case pb.Message_OrderUpdate:
+			n.msgChan <- orderUpdate{serializedMessage: mpb.Payload.Value}
 		}
 
 	}
@@ -267,6 +540,7 @@ func (n *AtomicSwapNode) setSelfAsSubscriber() {
 // This will query the dht for other subscribers and connect to a few of them
 func (n *AtomicSwapNode) connectToSubscribers() {
 	n.connectionRound()
+	n.announceMyOrders()
 	ticker := time.NewTicker(ReconnectInterval)
 	for range ticker.C {
 		for peer := range n.connectedSubs {
@@ -288,6 +562,9 @@ func (n *AtomicSwapNode) connectionRound() {
 	provs := n.routing.FindProvidersAsync(ctx, Topic, 10)
 	wg := &sync.WaitGroup{}
 	for p := range provs {
+		if n.scorer.Banned(p.ID) {
+			continue
+		}
 		wg.Add(1)
 		go func(pi pstore.PeerInfo) {
 			defer wg.Done()
@@ -301,16 +578,64 @@ func (n *AtomicSwapNode) connectionRound() {
 			log.Debug("connected to pubsub peer:", pi.ID)
 			n.msgChan <- addPeer{pi.ID}
 			if n.wireService != nil {
-				m := &pb.Message{
-					MessageType: pb.Message_GetOrderBook,
+				if err := n.wireService.SyncOrderBook(pi.ID); err != nil {
+					log.Errorf("Error syncing order book with %s: %s", pi.ID.Pretty(), err)
 				}
-				n.wireService.SendMessage(pi.ID, m)
 			}
 		}(p)
 	}
 	wg.Wait()
 }
 
+// announceMyOrders publishes our own orders which were loaded from the datastore
+// on startup, along with their latest updates, so peers that dropped them while
+// we were offline pick them up again.
+func (n *AtomicSwapNode) announceMyOrders() {
+	publish := func(t pb.Message_MessageType, msg proto.Message) error {
+		any, err := ptypes.MarshalAny(msg)
+		if err != nil {
+			return err
+		}
+		m := &pb.Message{
+			MessageType: t,
+			Payload:     any,
+		}
+		serializedMessage, err := proto.Marshal(m)
+		if err != nil {
+			return err
+		}
+		return n.publish(serializedMessage)
+	}
+	for _, o := range n.orderBook.MyOrders() {
+		signed, err := o.SignedLimitOrder()
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		if err := publish(pb.Message_LimitOrder, signed); err != nil {
+			log.Error(err)
+			continue
+		}
+		su, err := o.SignedUpdate()
+		if err != nil || su == nil {
+			continue
+		}
+		if err := publish(pb.Message_OrderUpdate, su); err != nil {
+			log.Error(err)
+		}
+	}
+}
+
 func (n *AtomicSwapNode) OrderBook() *ob.OrderBook {
 	return n.orderBook
 }
+
+// PeerID returns our own peer ID.
+func (n *AtomicSwapNode) PeerID() peer.ID {
+	return n.peerHost.ID()
+}
+
+// Peers returns the peers we're connected to on the order book topic.
+func (n *AtomicSwapNode) Peers() []peer.ID {
+	return n.floodsub.ListPeers("OrderBook")
+}
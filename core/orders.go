@@ -0,0 +1,281 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/wire"
+	ob "github.com/cpacia/atomicswap/orderbook"
+	"github.com/cpacia/atomicswap/swap"
+	"github.com/golang/protobuf/ptypes"
+	"strings"
+	"time"
+)
+
+// MaxOrderDuration is the longest an order we place can stay in the book. Good til
+// cancelled orders expire after this long.
+const MaxOrderDuration = time.Hour * 24 * 30
+
+var (
+	ErrInvalidExpiry = errors.New("order expiry must be in the future and at most 30 days away")
+	ErrNotFilled     = errors.New("order could not be filled")
+	ErrOrderBusy     = errors.New("order has swaps in progress")
+)
+
+// TimeInForce says how long an order stays active.
+type TimeInForce int
+
+const (
+	// GoodTilCancelled orders rest in the book until they're closed or MaxOrderDuration passes.
+	GoodTilCancelled TimeInForce = iota
+	// GoodTilTime orders rest in the book until the expiry the caller picks.
+	GoodTilTime
+	// ImmediateOrCancel orders take whatever they can from the book right away and
+	// the rest is dropped. They never rest in the book.
+	ImmediateOrCancel
+	// FillOrKill orders take their whole quantity from the book right away or nothing.
+	FillOrKill
+)
+
+func (t TimeInForce) String() string {
+	switch t {
+	case GoodTilCancelled:
+		return "GTC"
+	case GoodTilTime:
+		return "GTT"
+	case ImmediateOrCancel:
+		return "IOC"
+	case FillOrKill:
+		return "FOK"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseTimeInForce parses GTC, GTT, IOC or FOK. An empty string is GTC.
+func ParseTimeInForce(s string) (TimeInForce, error) {
+	switch strings.ToUpper(s) {
+	case "", "GTC":
+		return GoodTilCancelled, nil
+	case "GTT":
+		return GoodTilTime, nil
+	case "IOC":
+		return ImmediateOrCancel, nil
+	case "FOK":
+		return FillOrKill, nil
+	default:
+		return 0, fmt.Errorf("unknown time in force %s", s)
+	}
+}
+
+// OrderRequest is an order to buy or sell BTC at a limit price.
+type OrderRequest struct {
+	Quantity    uint64
+	Price       uint64
+	BuyBTC      bool
+	MinQuantity uint64
+	TimeInForce TimeInForce
+	// Expiry is when a GoodTilTime order expires. It's not used by the others.
+	Expiry time.Time
+}
+
+// OrderResult is what became of an order. Orders resting in the book have an ID;
+// immediate orders have the swaps they started instead.
+type OrderResult struct {
+	OrderID string
+	Order   *ob.LimitOrder
+	Swaps   []*Swap
+}
+
+// PlaceOrder places an order with the time in force. GTC and GTT orders are published
+// to the book. IOC and FOK orders take the orders in the book at the limit price or
+// better, starting a swap with each. Every fill is checked before any swap starts so
+// a FOK order which can't be filled in full starts none. Swaps can't be taken back so
+// if sending a market order fails after others have gone out, the error is returned
+// along with the swaps that started.
+func (n *AtomicSwapNode) PlaceOrder(req OrderRequest) (*OrderResult, error) {
+	if req.Quantity == 0 || req.Price == 0 {
+		return nil, errors.New("quantity and price must be greater than zero")
+	}
+	switch req.TimeInForce {
+	case GoodTilCancelled, GoodTilTime:
+		var expiry time.Time
+		if req.TimeInForce == GoodTilTime {
+			if req.Expiry.IsZero() {
+				return nil, ErrInvalidExpiry
+			}
+			expiry = req.Expiry
+		}
+		orderID, order, err := n.PublishLimitOrder(req.Quantity, req.Price, req.BuyBTC, req.MinQuantity, expiry)
+		if err != nil {
+			return nil, err
+		}
+		return &OrderResult{OrderID: orderID, Order: &order}, nil
+	case ImmediateOrCancel, FillOrKill:
+		return n.takeImmediately(req)
+	default:
+		return nil, fmt.Errorf("unknown time in force %d", req.TimeInForce)
+	}
+}
+
+// takeImmediately takes the orders that cross the request's price.
+func (n *AtomicSwapNode) takeImmediately(req OrderRequest) (*OrderResult, error) {
+	side := ob.Ask
+	if req.BuyBTC {
+		side = ob.Bid
+	}
+	fills := n.orderBook.MatchLimit(req.Quantity, side, req.Price)
+	var total uint64
+	for _, f := range fills {
+		total += f.Quantity
+	}
+	if total == 0 || (req.TimeInForce == FillOrKill && total < req.Quantity) {
+		return nil, ErrNotFilled
+	}
+
+	// Check every fill before starting any swaps. A fill or kill order fails if any of
+	// them can't be taken; an immediate or cancel order takes the rest.
+	var swaps []*Swap
+	for _, f := range fills {
+		s, err := n.newTakerSwap(f.OrderID, f.Quantity)
+		if err != nil {
+			log.Debugf("Can't take order %s for %s order: %s", f.OrderID, req.TimeInForce, err)
+			if req.TimeInForce == FillOrKill {
+				return nil, ErrNotFilled
+			}
+			continue
+		}
+		swaps = append(swaps, s)
+	}
+	if len(swaps) == 0 {
+		return nil, ErrNotFilled
+	}
+
+	// Sending a market order can still fail if the maker has gone away
+	result := new(OrderResult)
+	for _, s := range swaps {
+		if err := n.startTakerSwap(s); err != nil {
+			if len(result.Swaps) == 0 {
+				return nil, err
+			}
+			return result, fmt.Errorf("%s order only partly placed, taking order %s failed: %s", req.TimeInForce, s.OrderID, err)
+		}
+		result.Swaps = append(result.Swaps, s)
+	}
+	return result, nil
+}
+
+// ReplaceOrder amends one of our orders by closing it and publishing a new one with
+// the new quantity, price, minimum quantity and expiry on the same side. The old order
+// is closed first so the new one can be backed by the same UTXO. If the new order
+// still can't be placed the old one is placed again with its remaining quantity, which
+// gives it a new ID. Orders with swaps in progress can't be replaced since the swaps
+// are for the old order's terms.
+func (n *AtomicSwapNode) ReplaceOrder(orderID string, quantity, price, minQuantity uint64, expiry time.Time) (string, ob.LimitOrder, error) {
+	// Hold the fill lock so no one can take the old order while we replace it
+	n.fillLock.Lock()
+	defer n.fillLock.Unlock()
+
+	old, mine, err := n.orderBook.GetOrder(orderID)
+	if err != nil {
+		return "", ob.LimitOrder{}, err
+	}
+	if !mine {
+		return "", ob.LimitOrder{}, ErrNotMyOrder
+	}
+	if n.pendingFills(orderID) > 0 {
+		return "", ob.LimitOrder{}, ErrOrderBusy
+	}
+	// Catch bad terms and a lack of funds before the old order is gone
+	if _, err := n.checkOrderTerms(quantity, minQuantity, expiry); err != nil {
+		return "", ob.LimitOrder{}, err
+	}
+	if err := n.checkFunds(old.BuyBTC, quantity, price, orderID); err != nil {
+		return "", ob.LimitOrder{}, err
+	}
+	if err := n.CloseOrder(orderID); err != nil {
+		return "", ob.LimitOrder{}, err
+	}
+	newID, order, msg, err := n.addLimitOrder(quantity, price, old.BuyBTC, minQuantity, expiry)
+	if err != nil {
+		n.restoreOrder(orderID, old)
+		return "", ob.LimitOrder{}, err
+	}
+	if err := n.publish(msg); err != nil {
+		return "", ob.LimitOrder{}, err
+	}
+	log.Infof("Replaced order %s with %s", orderID, newID)
+	return newID, order, nil
+}
+
+// restoreOrder places a closed order of ours again with its remaining quantity. It
+// expires a second earlier than the old one, otherwise an order which was never
+// filled would get the closed order's ID and be ignored.
+func (n *AtomicSwapNode) restoreOrder(orderID string, old ob.LimitOrder) {
+	expiry, err := ptypes.Timestamp(old.Expiry)
+	if err != nil {
+		log.Errorf("Error restoring order %s: %s", orderID, err)
+		return
+	}
+	expiry = expiry.Add(-time.Second)
+	minQuantity := old.MinQuantity
+	if minQuantity > old.Remaining {
+		minQuantity = old.Remaining
+	}
+	newID, _, err := n.PublishLimitOrder(old.Remaining, old.Price, old.BuyBTC, minQuantity, expiry)
+	if err != nil {
+		log.Errorf("Error restoring order %s: %s", orderID, err)
+		return
+	}
+	log.Infof("Restored order %s as %s", orderID, newID)
+}
+
+// checkFunds checks our wallet has a UTXO to back an order with the UTXO of the
+// except order free to use.
+func (n *AtomicSwapNode) checkFunds(buyBTC bool, quantity, price uint64, except string) error {
+	coin, amount := swap.MakerFunds(buyBTC, quantity, price)
+	w, err := n.wallet(coin)
+	if err != nil {
+		return err
+	}
+	n.utxoLock.Lock()
+	defer n.utxoLock.Unlock()
+	n.lockOrderUTXOs(except)
+	defer n.lockOrderUTXOs("")
+	_, _, err = w.UTXO(amount)
+	return err
+}
+
+// lockOrderUTXOs locks the UTXOs backing our open orders in our wallets so they
+// aren't spent funding other swaps. UTXOs of orders which were closed, filled or
+// expired are unlocked. The UTXO of the except order is left unlocked so it can
+// fund a swap against that order. The caller must hold the utxo lock.
+func (n *AtomicSwapNode) lockOrderUTXOs(except string) {
+	locked := make(map[swap.Coin][]wire.OutPoint)
+	for _, order := range n.orderBook.MyOrders() {
+		if order.OrderID == except || order.Utxo == nil {
+			continue
+		}
+		op, err := swap.DecodeOutPoint(order.Utxo.Outpoint)
+		if err != nil {
+			continue
+		}
+		coin, _ := swap.MakerFunds(order.BuyBTC, order.Quantity, order.Price)
+		locked[coin] = append(locked[coin], op)
+	}
+	for coin, w := range n.wallets {
+		w.LockUTXOs(locked[coin])
+	}
+}
+
+// orderExpiry checks the expiry of an order we're placing. A zero expiry is the longest
+// one allowed.
+func orderExpiry(expiry time.Time) (time.Time, error) {
+	now := time.Now()
+	if expiry.IsZero() {
+		return now.Add(MaxOrderDuration), nil
+	}
+	if !expiry.After(now) || expiry.After(now.Add(MaxOrderDuration)) {
+		return time.Time{}, ErrInvalidExpiry
+	}
+	return expiry, nil
+}
@@ -0,0 +1,327 @@
+package core
+
+import (
+	"bytes"
+	"github.com/cpacia/atomicswap/chain"
+	ob "github.com/cpacia/atomicswap/orderbook"
+	"github.com/cpacia/atomicswap/pb"
+	"github.com/cpacia/atomicswap/swap"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// placeOrder has the maker place an order selling BTC and passes it to the taker as
+// if it came in over pubsub.
+func placeOrder(t *testing.T, maker, taker *testNode, quantity, price, minQuantity uint64) string {
+	orderID, _, msg, err := maker.addLimitOrder(quantity, price, false, minQuantity, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := new(pb.Message)
+	if err := proto.Unmarshal(msg, m); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	taker.msgChan <- newOrder{serializedMessage: m.Payload.Value, from: maker.PeerID(), done: done}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	return orderID
+}
+
+// addMaker adds another funded node to the test net.
+func addMaker(t *testing.T, tn *testNet, chains map[swap.Coin]*chain.SimChain) (*testNode, func()) {
+	dir, err := ioutil.TempDir("", "atomicswap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := newTestNode(t, dir, tn, chains)
+	for _, c := range chains {
+		c.MineBlocks(1)
+	}
+	return n, func() { os.RemoveAll(dir) }
+}
+
+func TestOrderUTXOLocked(t *testing.T) {
+	maker, _, _, _, cleanup := setupSwap(t)
+	defer cleanup()
+
+	// The maker's wallet has a single UTXO on each chain and the order locks it
+	orderID, _, _, err := maker.addLimitOrder(testQuantity, testPrice, false, 0, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := maker.addLimitOrder(testQuantity, testPrice, false, 0, time.Time{}); err != chain.ErrInsufficientFunds {
+		t.Errorf("second order got error %v, want %v", err, chain.ErrInsufficientFunds)
+	}
+	if _, err := maker.wallets[swap.BTC].FundContract([]byte{0x51}, testQuantity); err != chain.ErrInsufficientFunds {
+		t.Errorf("funding a contract got error %v, want %v", err, chain.ErrInsufficientFunds)
+	}
+
+	// Closing the order unlocks it
+	if err := maker.CloseOrder(orderID); err != nil {
+		t.Fatal(err)
+	}
+	if !maker.orderClosed(orderID) {
+		t.Fatal("closed order still in the book")
+	}
+	if _, _, _, err := maker.addLimitOrder(testQuantity, testPrice, false, 0, time.Time{}); err != nil {
+		t.Errorf("order rejected after the utxo was unlocked: %s", err)
+	}
+}
+
+func TestGoodTilTime(t *testing.T) {
+	maker, _, _, _, cleanup := setupSwap(t)
+	defer cleanup()
+
+	req := OrderRequest{Quantity: testQuantity, Price: testPrice, TimeInForce: GoodTilTime}
+	for _, expiry := range []time.Time{{}, time.Now().Add(-time.Minute), time.Now().Add(MaxOrderDuration + time.Hour)} {
+		req.Expiry = expiry
+		if _, err := maker.PlaceOrder(req); err != ErrInvalidExpiry {
+			t.Errorf("expiry %s got error %v, want %v", expiry, err, ErrInvalidExpiry)
+		}
+	}
+
+	req.Expiry = time.Now().Add(time.Hour).Truncate(time.Second)
+	result, err := maker.PlaceOrder(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OrderID == "" || len(result.Swaps) != 0 {
+		t.Errorf("got result %+v, want a resting order", result)
+	}
+	order, mine, err := maker.OrderBook().GetOrder(result.OrderID)
+	if err != nil || !mine {
+		t.Fatalf("order not in our book: %v", err)
+	}
+	expiry, err := ptypes.Timestamp(order.Expiry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !expiry.Equal(req.Expiry) {
+		t.Errorf("got expiry %s, want %s", expiry, req.Expiry)
+	}
+
+	// Good til cancelled orders get the longest expiry
+	req = OrderRequest{Quantity: testQuantity, Price: testPrice, BuyBTC: true}
+	result, err = maker.PlaceOrder(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiry, err = ptypes.Timestamp(result.Order.Expiry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(expiry); d < MaxOrderDuration-time.Minute || d > MaxOrderDuration {
+		t.Errorf("GTC order expires in %s, want %s", d, MaxOrderDuration)
+	}
+}
+
+func TestImmediateOrCancel(t *testing.T) {
+	maker, taker, _, _, cleanup := setupSwap(t)
+	defer cleanup()
+
+	orderID := placeOrder(t, maker, taker, testQuantity, testPrice, 0)
+
+	// Nothing crosses a lower price
+	req := OrderRequest{Quantity: 2 * testQuantity, Price: testPrice - 1, BuyBTC: true, TimeInForce: ImmediateOrCancel}
+	if _, err := taker.PlaceOrder(req); err != ErrNotFilled {
+		t.Errorf("got error %v, want %v", err, ErrNotFilled)
+	}
+	if n := len(taker.Swaps()); n != 0 {
+		t.Fatalf("unfilled order started %d swaps", n)
+	}
+
+	// The order is taken in full and the rest is dropped
+	req.Price = testPrice
+	result, err := taker.PlaceOrder(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OrderID != "" || result.Order != nil {
+		t.Errorf("IOC order rests in the book as %s", result.OrderID)
+	}
+	if len(result.Swaps) != 1 {
+		t.Fatalf("got %d swaps, want 1", len(result.Swaps))
+	}
+	if s := result.Swaps[0]; s.OrderID != orderID || s.quantity() != testQuantity {
+		t.Errorf("got swap for %d of order %s, want %d of %s", s.quantity(), s.OrderID, testQuantity, orderID)
+	}
+	if orders := taker.OrderBook().MyOrders(); len(orders) != 0 {
+		t.Errorf("taker has %d orders in the book", len(orders))
+	}
+}
+
+func TestFillOrKill(t *testing.T) {
+	maker, taker, chains, tn, cleanup := setupSwap(t)
+	defer cleanup()
+	maker2, cleanup2 := addMaker(t, tn, chains)
+	defer cleanup2()
+
+	first := placeOrder(t, maker, taker, testQuantity, testPrice, 0)
+	second := placeOrder(t, maker2, taker, testQuantity, testPrice, testQuantity)
+
+	// There isn't enough in the book
+	req := OrderRequest{Quantity: 3 * testQuantity, Price: testPrice, BuyBTC: true, TimeInForce: FillOrKill}
+	if _, err := taker.PlaceOrder(req); err != ErrNotFilled {
+		t.Errorf("got error %v, want %v", err, ErrNotFilled)
+	}
+
+	// The second order can't be taken in part
+	req.Quantity = testQuantity * 3 / 2
+	if _, err := taker.PlaceOrder(req); err != ErrNotFilled {
+		t.Errorf("got error %v, want %v", err, ErrNotFilled)
+	}
+
+	// Every order can be matched but none of them can be taken
+	bch := taker.wallets[swap.BCH]
+	delete(taker.AtomicSwapNode.wallets, swap.BCH)
+	req.Quantity = 2 * testQuantity
+	if _, err := taker.PlaceOrder(req); err != ErrNotFilled {
+		t.Errorf("got error %v, want %v", err, ErrNotFilled)
+	}
+	if n := len(taker.Swaps()); n != 0 {
+		t.Fatalf("unfilled order started %d swaps", n)
+	}
+	taker.SetWallet(swap.BCH, bch)
+
+	// Both orders are taken
+	result, err := taker.PlaceOrder(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OrderID != "" {
+		t.Errorf("FOK order rests in the book as %s", result.OrderID)
+	}
+	taken := make(map[string]uint64)
+	for _, s := range result.Swaps {
+		taken[s.OrderID] += s.quantity()
+	}
+	if len(result.Swaps) != 2 || taken[first] != testQuantity || taken[second] != testQuantity {
+		t.Errorf("got %d swaps taking %v, want all of %s and %s", len(result.Swaps), taken, first, second)
+	}
+}
+
+func TestReplaceOrder(t *testing.T) {
+	maker, _, _, _, cleanup := setupSwap(t)
+	defer cleanup()
+
+	// The order holds the maker's only BTC UTXO
+	orderID, old, _, err := maker.addLimitOrder(testQuantity, testPrice, false, 0, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bad terms leave the old order alone
+	if _, _, err := maker.ReplaceOrder(orderID, testQuantity, testPrice, 2*testQuantity, time.Time{}); err == nil {
+		t.Error("replaced with a minimum quantity above the quantity")
+	}
+	if _, _, err := maker.ReplaceOrder(orderID, testQuantity, testPrice, 0, time.Now().Add(-time.Minute)); err != ErrInvalidExpiry {
+		t.Errorf("got error %v, want %v", err, ErrInvalidExpiry)
+	}
+	if _, _, err := maker.OrderBook().GetOrder(orderID); err != nil {
+		t.Fatalf("order gone after a failed replace: %s", err)
+	}
+
+	// The new order is backed by the old order's UTXO
+	newID, order, err := maker.ReplaceOrder(orderID, 2*testQuantity, testPrice+1, testQuantity, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maker.orderClosed(orderID) {
+		t.Error("replaced order still in the book")
+	}
+	if order.Quantity != 2*testQuantity || order.Price != testPrice+1 || order.MinQuantity != testQuantity || order.BuyBTC {
+		t.Errorf("got order %+v", order.LimitOrder)
+	}
+	if !bytes.Equal(order.Utxo.Outpoint, old.Utxo.Outpoint) {
+		t.Error("replacement is backed by a different UTXO")
+	}
+	if orders := maker.OrderBook().MyOrders(); len(orders) != 1 {
+		t.Errorf("maker has %d orders, want 1", len(orders))
+	}
+
+	// The UTXO stays locked
+	if _, err := maker.wallets[swap.BTC].FundContract([]byte{0x51}, testQuantity); err != chain.ErrInsufficientFunds {
+		t.Errorf("funding a contract got error %v, want %v", err, chain.ErrInsufficientFunds)
+	}
+
+	// The wallet can't fund a bigger order so the old one is left alone
+	if _, _, err := maker.ReplaceOrder(newID, 200*testPrice, testPrice, 0, time.Time{}); err != chain.ErrInsufficientFunds {
+		t.Fatalf("got error %v, want %v", err, chain.ErrInsufficientFunds)
+	}
+	if _, _, err := maker.OrderBook().GetOrder(newID); err != nil {
+		t.Errorf("order gone after a failed replace: %s", err)
+	}
+}
+
+func TestRestoreOrder(t *testing.T) {
+	maker, _, _, _, cleanup := setupSwap(t)
+	defer cleanup()
+
+	orderID, old, _, err := maker.addLimitOrder(testQuantity, testPrice, false, testQuantity/2, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := maker.CloseOrder(orderID); err != nil {
+		t.Fatal(err)
+	}
+
+	// The restored order gets a new ID so peers don't take it for the closed one
+	maker.restoreOrder(orderID, old)
+	orders := maker.OrderBook().MyOrders()
+	if len(orders) != 1 {
+		t.Fatalf("maker has %d orders, want 1", len(orders))
+	}
+	o := orders[0]
+	if o.OrderID == orderID {
+		t.Error("restored order has the closed order's ID")
+	}
+	if o.Remaining != testQuantity || o.Price != testPrice || o.MinQuantity != testQuantity/2 || o.BuyBTC {
+		t.Errorf("restored order %+v, want %+v", o.LimitOrder, old.LimitOrder)
+	}
+}
+
+func TestAmendOrder(t *testing.T) {
+	maker, _, _, _, cleanup := setupSwap(t)
+	defer cleanup()
+
+	orderID, _, _, err := maker.addLimitOrder(testQuantity, testPrice, false, 0, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The UTXO proof only covers the original quantity
+	if err := maker.AmendOrder(orderID, testQuantity+1); err == nil {
+		t.Error("order grown past its quantity")
+	}
+
+	if err := maker.AmendOrder(orderID, testQuantity/2); err != nil {
+		t.Fatal(err)
+	}
+	var order ob.LimitOrder
+	for i := 0; i < 100; i++ {
+		order, _, err = maker.OrderBook().GetOrder(orderID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if order.Remaining != testQuantity {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if order.Remaining != testQuantity/2 {
+		t.Errorf("got remaining %d, want %d", order.Remaining, testQuantity/2)
+	}
+
+	// Amending to zero closes it
+	if err := maker.AmendOrder(orderID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !maker.orderClosed(orderID) {
+		t.Error("order amended to zero still in the book")
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"github.com/btcsuite/btcd/wire"
+	"github.com/cpacia/atomicswap/chain"
+	"github.com/cpacia/atomicswap/swap"
+	"time"
+)
+
+// watchdog periodically looks through our swaps for funded contracts whose
+// locktime has passed on the send chain without the swap completing and refunds
+// them. This covers the case where the counterparty disappears mid-swap. It also
+// retries redeems which failed or haven't confirmed and fails swaps against our
+// orders whose initiator never sent its contract. Swaps loaded from the datastore
+// on start up are picked up on the next pass. The UTXOs of our orders which have
+// since closed or expired are unlocked.
+func (n *AtomicSwapNode) watchdog() {
+	ticker := time.NewTicker(RefundCheckInterval)
+	for range ticker.C {
+		n.failUninitiated()
+		n.retryRedeems()
+		n.refundExpired()
+		n.utxoLock.Lock()
+		n.lockOrderUTXOs("")
+		n.utxoLock.Unlock()
+	}
+}
+
+// failUninitiated fails the swaps against our orders whose initiator never sent
+// its contract.
+func (n *AtomicSwapNode) failUninitiated() {
+	for _, s := range n.Swaps() {
+		s.lock.Lock()
+		if !s.initiateExpired() {
+			s.lock.Unlock()
+			continue
+		}
+		log.Warningf("Swap %s failed, %s never sent its contract", s.ID, s.Counterparty.Pretty())
+		s.State = StateFailed
+		n.saveAndUnlock(s)
+	}
+}
+
+func (n *AtomicSwapNode) refundExpired() {
+	for _, s := range n.Swaps() {
+		s.lock.Lock()
+		if !n.refundable(s) {
+			s.lock.Unlock()
+			continue
+		}
+		if err := n.refundContract(s); err != nil {
+			s.RefundError = err.Error()
+			log.Errorf("Error refunding swap %s: %s", s.ID, err)
+		}
+		n.saveAndUnlock(s)
+	}
+}
+
+// refundable returns whether we have a funded contract in the swap which can
+// now be refunded. That's any contract still unspent once its locktime passes,
+// whatever state the swap is in. A swap can fail after our contract was broadcast
+// and the initiator's counterparty may never redeem after we redeem theirs.
+func (n *AtomicSwapNode) refundable(s *Swap) bool {
+	if s.ContractTx == nil || s.State == StateRefunded {
+		return false
+	}
+	backend, err := n.chainBackend(s.SendCoin)
+	if err != nil {
+		return false
+	}
+	// Locktimes are checked against the median time of past blocks, not the wall
+	// clock, and the refund is only valid once the median time is past the locktime.
+	medianTime, err := backend.MedianTime()
+	if err != nil {
+		log.Errorf("Error getting %s median time: %s", s.SendCoin, err)
+		return false
+	}
+	if medianTime.Unix() <= s.LockTime {
+		return false
+	}
+	// The counterparty may have redeemed it, or it may never have made it on chain.
+	idx, _, err := swap.ContractOutput(s.ContractTx, s.Contract)
+	if err != nil {
+		return false
+	}
+	contractHash := s.ContractTx.TxHash()
+	_, _, err = backend.GetTxOut(*wire.NewOutPoint(&contractHash, idx))
+	if err != nil && err != chain.ErrTxOutSpent {
+		log.Errorf("Error looking up contract for swap %s: %s", s.ID, err)
+	}
+	return err == nil
+}
+
+// refundContract builds, signs and broadcasts the transaction refunding our
+// contract back to our refund key.
+func (n *AtomicSwapNode) refundContract(s *Swap) error {
+	backend, err := n.chainBackend(s.SendCoin)
+	if err != nil {
+		return err
+	}
+	tx, err := swap.NewRefundTx(s.SendCoin, s.ContractTx, s.Contract, s.RefundKey, n.feeRate(backend))
+	if err != nil {
+		return err
+	}
+	s.RefundTx = tx
+	if err := n.saveSwap(s); err != nil {
+		return err
+	}
+	if err := backend.Broadcast(tx); err != nil {
+		return err
+	}
+	s.State = StateRefunded
+	s.RefundError = ""
+	log.Infof("Refunded contract for swap %s in %s", s.ID, tx.TxHash().String())
+	return nil
+}
@@ -0,0 +1,924 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/cpacia/atomicswap/chain"
+	"github.com/cpacia/atomicswap/pb"
+	"github.com/cpacia/atomicswap/swap"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/libp2p/go-libp2p-peer"
+	"sync"
+	"time"
+)
+
+const (
+	// The initiator's contract must stay locked for longer than the participant's
+	// so that the participant always has time to redeem after the secret is revealed.
+	InitiatorLockTime   = 48 * time.Hour
+	ParticipantLockTime = 24 * time.Hour
+
+	// MinInitiatorLockTime is the minimum time that must be left on the initiator's
+	// contract before the participant will fund its side of the swap.
+	MinInitiatorLockTime = 36 * time.Hour
+
+	// MinParticipantLockTime is the minimum time that must be left on the participant's
+	// contract before the initiator will redeem it.
+	MinParticipantLockTime = 12 * time.Hour
+
+	// RefundCheckInterval is how often the watchdog looks for expired contracts and
+	// redeems to retry.
+	RefundCheckInterval = 10 * time.Minute
+
+	// ContractConfirmations is how many confirmations the counterparty's contract
+	// needs before we fund ours or redeem it.
+	ContractConfirmations = 1
+
+	// ContractConfirmTimeout is how long we wait for the counterparty's contract to
+	// confirm. It's well inside the margin between the initiator's locktime and
+	// MinInitiatorLockTime.
+	ContractConfirmTimeout = 6 * time.Hour
+
+	// InitiateTimeout is how long the participant waits for the initiator's contract
+	// after accepting a market order. Until then the swap counts against the order so
+	// the swap is failed once it passes, otherwise takers who never fund could tie up
+	// the order for good.
+	InitiateTimeout = 15 * time.Minute
+)
+
+// SwapRole is the part we play in a swap. The taker of an order is always the
+// initiator, meaning it creates the secret and funds the first contract.
+type SwapRole int
+
+const (
+	Initiator SwapRole = iota
+	Participant
+)
+
+func (r SwapRole) String() string {
+	switch r {
+	case Initiator:
+		return "initiator"
+	case Participant:
+		return "participant"
+	default:
+		return "unknown"
+	}
+}
+
+// SwapState tracks how far along a swap is. The initiator moves through
+// Taken -> Initiated -> Redeemed while the participant moves through
+// Taken -> Participated -> RedeemPending -> Redeemed. Either side can end in
+// Refunded if the counterparty disappears after we funded our contract.
+//
+// The states are stored by number so new ones go at the end.
+type SwapState int
+
+const (
+	StateTaken SwapState = iota
+	StateInitiated
+	StateParticipated
+	StateRedeemed
+	StateRefunded
+	StateFailed
+
+	// StateRedeemPending means the participant knows the secret but its redeem
+	// of the initiator's contract hasn't confirmed yet.
+	StateRedeemPending
+)
+
+func (s SwapState) String() string {
+	switch s {
+	case StateTaken:
+		return "taken"
+	case StateInitiated:
+		return "initiated"
+	case StateParticipated:
+		return "participated"
+	case StateRedeemed:
+		return "redeemed"
+	case StateRefunded:
+		return "refunded"
+	case StateFailed:
+		return "failed"
+	case StateRedeemPending:
+		return "redeem pending"
+	default:
+		return "unknown"
+	}
+}
+
+// Swap holds the state of a single swap. The ID is the hex encoded secret hash
+// which is unique and known to both sides from the start.
+type Swap struct {
+	ID           string
+	OrderID      string
+	Role         SwapRole
+	State        SwapState
+	Counterparty peer.ID
+
+	SendCoin      swap.Coin
+	ReceiveCoin   swap.Coin
+	SendAmount    int64
+	ReceiveAmount int64
+
+	// The initiator knows the secret from the start. The participant only
+	// learns it when the initiator redeems.
+	Secret     []byte
+	SecretHash [32]byte
+
+	// RedeemKey redeems the counterparty's contract on the receive chain
+	// and RefundKey refunds our contract on the send chain.
+	RedeemKey              *btcec.PrivateKey
+	RefundKey              *btcec.PrivateKey
+	CounterpartyRedeemHash []byte
+
+	Contract               []byte
+	ContractTx             *wire.MsgTx
+	LockTime               int64
+	CounterpartyContract   []byte
+	CounterpartyContractTx *wire.MsgTx
+	CounterpartyLockTime   int64
+
+	RedeemTx *wire.MsgTx
+	RefundTx *wire.MsgTx
+
+	// RefundError holds the reason our last refund attempt failed, if any.
+	RefundError string
+
+	// Created is when the swap was taken.
+	Created time.Time
+
+	// savedState is the state the swap was last saved in and saved is false until
+	// it's been saved once. They're used to publish an event when the state changes.
+	savedState SwapState
+	saved      bool
+
+	lock sync.Mutex
+}
+
+// SetWallet sets the wallet used to fund and redeem swaps on the given coin.
+func (n *AtomicSwapNode) SetWallet(coin swap.Coin, wallet swap.Wallet) {
+	n.wallets[coin] = wallet
+}
+
+func (n *AtomicSwapNode) wallet(coin swap.Coin) (swap.Wallet, error) {
+	w, ok := n.wallets[coin]
+	if !ok {
+		return nil, fmt.Errorf("no %s wallet configured", coin)
+	}
+	return w, nil
+}
+
+// balancer is implemented by wallets which can report their balance.
+type balancer interface {
+	Balance() (confirmed, unconfirmed int64)
+}
+
+// Balance returns the confirmed and unconfirmed balance of our wallet for the coin.
+func (n *AtomicSwapNode) Balance(coin swap.Coin) (confirmed, unconfirmed int64, err error) {
+	w, err := n.wallet(coin)
+	if err != nil {
+		return 0, 0, err
+	}
+	b, ok := w.(balancer)
+	if !ok {
+		return 0, 0, fmt.Errorf("%s wallet can't report its balance", coin)
+	}
+	confirmed, unconfirmed = b.Balance()
+	return confirmed, unconfirmed, nil
+}
+
+// SetChainBackend sets the backend used to broadcast and watch swap transactions
+// on the given coin.
+func (n *AtomicSwapNode) SetChainBackend(coin swap.Coin, backend chain.ChainBackend) {
+	n.chains[coin] = backend
+	n.orderBook.SetChainBackend(coin, backend)
+}
+
+func (n *AtomicSwapNode) chainBackend(coin swap.Coin) (chain.ChainBackend, error) {
+	b, ok := n.chains[coin]
+	if !ok {
+		return nil, fmt.Errorf("no %s chain backend configured", coin)
+	}
+	return b, nil
+}
+
+// feeRate returns the backend's fee estimate for the coin, falling back to the
+// default rate if the backend can't give us one.
+func (n *AtomicSwapNode) feeRate(backend chain.ChainBackend) int64 {
+	fee, err := backend.EstimateFee()
+	if err != nil || fee <= 0 {
+		return swap.FeePerByte
+	}
+	return fee
+}
+
+// Swaps returns all the swaps we know about.
+func (n *AtomicSwapNode) Swaps() []*Swap {
+	n.swapLock.RLock()
+	defer n.swapLock.RUnlock()
+	var swaps []*Swap
+	for _, s := range n.swaps {
+		swaps = append(swaps, s)
+	}
+	return swaps
+}
+
+func (n *AtomicSwapNode) getSwap(swapID string) (*Swap, error) {
+	n.swapLock.RLock()
+	defer n.swapLock.RUnlock()
+	s, ok := n.swaps[swapID]
+	if !ok {
+		return nil, errors.New("unknown swap")
+	}
+	return s, nil
+}
+
+func (n *AtomicSwapNode) addSwap(s *Swap) error {
+	n.swapLock.Lock()
+	defer n.swapLock.Unlock()
+	if _, ok := n.swaps[s.ID]; ok {
+		return errors.New("duplicate swap ID")
+	}
+	n.swaps[s.ID] = s
+	return nil
+}
+
+// TakeOrder starts a swap against a resting limit order in the order book. We become
+// the initiator, create the secret and send a market order to the maker. The rest of
+// the swap proceeds automatically as the maker responds. The quantity is in BTC satoshis
+// and may be part of the order; zero takes the whole remaining quantity.
+func (n *AtomicSwapNode) TakeOrder(orderID string, quantity uint64) (*Swap, error) {
+	s, err := n.newTakerSwap(orderID, quantity)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.startTakerSwap(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// newTakerSwap checks the order can be taken for the quantity and sets up a swap to
+// take it. Nothing is saved or sent to the maker yet.
+func (n *AtomicSwapNode) newTakerSwap(orderID string, quantity uint64) (*Swap, error) {
+	order, mine, err := n.orderBook.GetOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if mine {
+		return nil, ErrOwnOrder
+	}
+	if quantity == 0 {
+		quantity = order.Remaining
+	}
+	if err := order.CheckFill(quantity); err != nil {
+		return nil, err
+	}
+	maker, err := peer.IDB58Decode(order.PeerID)
+	if err != nil {
+		return nil, err
+	}
+	s := &Swap{
+		OrderID:      orderID,
+		Role:         Initiator,
+		State:        StateTaken,
+		Counterparty: maker,
+		Created:      time.Now(),
+	}
+	s.setTerms(order.LimitOrder, quantity)
+
+	sendWallet, err := n.wallet(s.SendCoin)
+	if err != nil {
+		return nil, err
+	}
+	receiveWallet, err := n.wallet(s.ReceiveCoin)
+	if err != nil {
+		return nil, err
+	}
+	secret, secretHash, err := swap.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+	s.Secret = secret[:]
+	s.SecretHash = secretHash
+	s.ID = hex.EncodeToString(secretHash[:])
+	s.RedeemKey, err = receiveWallet.NewKey()
+	if err != nil {
+		return nil, err
+	}
+	s.RefundKey, err = sendWallet.NewKey()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// startTakerSwap saves a swap from newTakerSwap and sends the maker our market order.
+func (n *AtomicSwapNode) startTakerSwap(s *Swap) error {
+	if err := n.addSwap(s); err != nil {
+		return err
+	}
+	if err := n.saveSwap(s); err != nil {
+		return err
+	}
+
+	mo := &pb.MarketOrder{
+		OrderID:    s.OrderID,
+		SecretHash: s.SecretHash[:],
+		RedeemHash: pubKeyHash(s.RedeemKey),
+		Quantity:   s.quantity(),
+	}
+	if err := n.sendSwapMessage(s.Counterparty, pb.Message_MarketOrder, mo); err != nil {
+		s.State = StateFailed
+		n.saveSwap(s)
+		return err
+	}
+	log.Infof("Sent market order for order %s, swap %s", s.OrderID, s.ID)
+	return nil
+}
+
+// handleSwapMessage is called for each swap protocol message we receive from the wire.
+func (n *AtomicSwapNode) handleSwapMessage(p peer.ID, m *pb.Message) {
+	if m.Payload == nil {
+		log.Errorf("Received %s message from %s with no payload", m.MessageType, p.Pretty())
+		return
+	}
+	var err error
+	switch m.MessageType {
+	case pb.Message_MarketOrder:
+		err = n.handleMarketOrder(p, m.Payload.Value)
+	case pb.Message_SwapAccept:
+		err = n.handleSwapAccept(p, m.Payload.Value)
+	case pb.Message_SwapReject:
+		err = n.handleSwapReject(p, m.Payload.Value)
+	case pb.Message_InitiateSwap:
+		err = n.handleInitiateSwap(p, m.Payload.Value)
+	case pb.Message_ParticipateSwap:
+		err = n.handleParticipateSwap(p, m.Payload.Value)
+	case pb.Message_RedeemSwap:
+		err = n.handleRedeemSwap(p, m.Payload.Value)
+	}
+	if err != nil {
+		log.Errorf("Error processing %s message from %s: %s", m.MessageType, p.Pretty(), err)
+	}
+}
+
+// The maker receives a market order from the taker. If it's for one of our open orders
+// we'll accept it and pull the order from the order book.
+func (n *AtomicSwapNode) handleMarketOrder(p peer.ID, payload []byte) error {
+	mo := new(pb.MarketOrder)
+	if err := proto.Unmarshal(payload, mo); err != nil {
+		return err
+	}
+	if len(mo.SecretHash) != 32 {
+		return errors.New("invalid secret hash")
+	}
+	swapID := hex.EncodeToString(mo.SecretHash)
+	reject := func(reason string) error {
+		n.sendSwapMessage(p, pb.Message_SwapReject, &pb.SwapReject{SwapID: swapID, Reason: reason})
+		return fmt.Errorf("rejected swap %s: %s", swapID, reason)
+	}
+	if len(mo.RedeemHash) != 20 {
+		return reject("invalid redeem hash")
+	}
+	order, mine, err := n.orderBook.GetOrder(mo.OrderID)
+	if err != nil || !mine {
+		return reject("order not found")
+	}
+
+	// Hold the fill lock until the swap is added so concurrent takers can't fill
+	// more than what's left of the order.
+	n.fillLock.Lock()
+	defer n.fillLock.Unlock()
+	quantity := mo.Quantity
+	if quantity == 0 {
+		quantity = order.Remaining
+	}
+	pending := n.pendingFills(mo.OrderID)
+	if pending >= order.Remaining {
+		return reject("order is fully committed to other swaps")
+	}
+	order.Remaining -= pending
+	if err := order.CheckFill(quantity); err != nil {
+		return reject(err.Error())
+	}
+
+	s := &Swap{
+		ID:                     swapID,
+		OrderID:                mo.OrderID,
+		Role:                   Participant,
+		State:                  StateTaken,
+		Counterparty:           p,
+		CounterpartyRedeemHash: mo.RedeemHash,
+		Created:                time.Now(),
+	}
+	copy(s.SecretHash[:], mo.SecretHash)
+	s.setTerms(order.LimitOrder, quantity)
+
+	sendWallet, err := n.wallet(s.SendCoin)
+	if err != nil {
+		return reject("maker wallet unavailable")
+	}
+	receiveWallet, err := n.wallet(s.ReceiveCoin)
+	if err != nil {
+		return reject("maker wallet unavailable")
+	}
+	s.RedeemKey, err = receiveWallet.NewKey()
+	if err != nil {
+		return reject("maker wallet unavailable")
+	}
+	s.RefundKey, err = sendWallet.NewKey()
+	if err != nil {
+		return reject("maker wallet unavailable")
+	}
+	if err := n.addSwap(s); err != nil {
+		return reject(err.Error())
+	}
+	if err := n.saveSwap(s); err != nil {
+		return reject("internal error")
+	}
+
+	// The order stays in the book. Its remaining quantity is lowered once the swap
+	// completes and until then the swap counts against what we'll accept.
+	log.Infof("Accepted market order from %s for order %s, swap %s", p.Pretty(), mo.OrderID, swapID)
+	return n.sendSwapMessage(p, pb.Message_SwapAccept, &pb.SwapAccept{
+		SwapID:     swapID,
+		RedeemHash: pubKeyHash(s.RedeemKey),
+	})
+}
+
+// The taker learns the maker accepted. We now fund the initiator contract.
+func (n *AtomicSwapNode) handleSwapAccept(p peer.ID, payload []byte) error {
+	sa := new(pb.SwapAccept)
+	if err := proto.Unmarshal(payload, sa); err != nil {
+		return err
+	}
+	s, err := n.swapForMessage(p, sa.SwapID, Initiator, StateTaken)
+	if err != nil {
+		return err
+	}
+	defer n.saveAndUnlock(s)
+	if len(sa.RedeemHash) != 20 {
+		s.State = StateFailed
+		return errors.New("invalid redeem hash")
+	}
+	s.CounterpartyRedeemHash = sa.RedeemHash
+
+	if err := n.fundContract(s, time.Now().Add(InitiatorLockTime)); err != nil {
+		s.State = StateFailed
+		return err
+	}
+	log.Infof("Funded initiator contract %s for swap %s", s.ContractTx.TxHash().String(), s.ID)
+	return n.sendContract(s)
+}
+
+// The taker learns the maker rejected the market order.
+func (n *AtomicSwapNode) handleSwapReject(p peer.ID, payload []byte) error {
+	sr := new(pb.SwapReject)
+	if err := proto.Unmarshal(payload, sr); err != nil {
+		return err
+	}
+	s, err := n.swapForMessage(p, sr.SwapID, Initiator, StateTaken)
+	if err != nil {
+		return err
+	}
+	defer n.saveAndUnlock(s)
+	s.State = StateFailed
+	log.Warningf("Swap %s rejected by maker: %s", s.ID, sr.Reason)
+	return nil
+}
+
+// The maker receives the initiator's contract. If it checks out and is confirmed on
+// chain we fund the participant contract.
+func (n *AtomicSwapNode) handleInitiateSwap(p peer.ID, payload []byte) error {
+	sc := new(pb.SwapContract)
+	if err := proto.Unmarshal(payload, sc); err != nil {
+		return err
+	}
+	s, err := n.swapForMessage(p, sc.SwapID, Participant, StateTaken)
+	if err != nil {
+		return err
+	}
+	defer n.saveAndUnlock(s)
+	if s.CounterpartyContractTx != nil {
+		// Already waiting on this contract
+		return nil
+	}
+	if s.initiateExpired() {
+		s.State = StateFailed
+		return errors.New("initiator contract arrived too late")
+	}
+
+	contractTx, err := n.auditContract(s, sc, time.Now().Add(MinInitiatorLockTime))
+	if err != nil {
+		s.State = StateFailed
+		return err
+	}
+	s.CounterpartyContract = sc.Contract
+	s.CounterpartyContractTx = contractTx
+	return n.checkContractConfirmed(s)
+}
+
+// The taker receives the participant's contract. If it checks out and is confirmed on
+// chain we redeem it, revealing the secret to the maker.
+func (n *AtomicSwapNode) handleParticipateSwap(p peer.ID, payload []byte) error {
+	sc := new(pb.SwapContract)
+	if err := proto.Unmarshal(payload, sc); err != nil {
+		return err
+	}
+	s, err := n.swapForMessage(p, sc.SwapID, Initiator, StateInitiated)
+	if err != nil {
+		return err
+	}
+	defer n.saveAndUnlock(s)
+	if s.CounterpartyContractTx != nil {
+		// Already waiting on this contract
+		return nil
+	}
+
+	contractTx, err := n.auditContract(s, sc, time.Now().Add(MinParticipantLockTime))
+	if err != nil {
+		// We don't fail the swap here. Our contract is funded so it will be
+		// refunded once the locktime expires.
+		return err
+	}
+	if s.CounterpartyLockTime >= s.LockTime {
+		return errors.New("participant contract locktime is not before ours")
+	}
+	s.CounterpartyContract = sc.Contract
+	s.CounterpartyContractTx = contractTx
+	return n.checkContractConfirmed(s)
+}
+
+// checkContractConfirmed looks the counterparty's contract up on chain. If it's already
+// confirmed we carry on with the swap, otherwise we wait for it to confirm. The caller
+// must hold the swap lock.
+func (n *AtomicSwapNode) checkContractConfirmed(s *Swap) error {
+	confirmations, err := n.verifyCounterpartyContract(s)
+	if err != nil && err != errContractNotSeen {
+		if s.Role == Participant {
+			s.State = StateFailed
+		} else {
+			// Our contract is funded so it will be refunded once the locktime
+			// expires unless the participant sends us a contract that checks out.
+			s.CounterpartyContract = nil
+			s.CounterpartyContractTx = nil
+		}
+		return err
+	}
+	if err == nil && confirmations >= ContractConfirmations {
+		return n.contractConfirmed(s)
+	}
+	log.Infof("Waiting for counterparty contract %s to confirm for swap %s", s.CounterpartyContractTx.TxHash().String(), s.ID)
+	return n.awaitContract(s)
+}
+
+// contractConfirmed is called once the counterparty's contract has confirmed. The
+// participant funds its contract and the initiator redeems the participant's, revealing
+// the secret. Time has passed since the contract was audited so the locktime is
+// checked again.
+func (n *AtomicSwapNode) contractConfirmed(s *Swap) error {
+	if s.Role == Participant {
+		if time.Unix(s.CounterpartyLockTime, 0).Before(time.Now().Add(MinInitiatorLockTime)) {
+			s.State = StateFailed
+			return errors.New("initiator contract locktime is too soon")
+		}
+		if err := n.fundContract(s, time.Now().Add(ParticipantLockTime)); err != nil {
+			s.State = StateFailed
+			return err
+		}
+		log.Infof("Funded participant contract %s for swap %s", s.ContractTx.TxHash().String(), s.ID)
+		return n.sendContract(s)
+	}
+	if time.Unix(s.CounterpartyLockTime, 0).Before(time.Now().Add(MinParticipantLockTime)) {
+		return errors.New("participant contract locktime is too soon")
+	}
+	if err := n.redeemContract(s); err != nil {
+		return err
+	}
+	log.Infof("Redeemed participant contract for swap %s", s.ID)
+	return n.sendRedeem(s)
+}
+
+// awaitingContract returns whether we have the counterparty's contract but haven't
+// acted on it yet because it hasn't confirmed.
+func (s *Swap) awaitingContract() bool {
+	if s.CounterpartyContractTx == nil {
+		return false
+	}
+	if s.Role == Participant {
+		return s.State == StateTaken && s.ContractTx == nil
+	}
+	return s.State == StateInitiated && s.RedeemTx == nil
+}
+
+// The maker receives the initiator's redeem transaction. We extract the secret
+// from it and redeem the initiator's contract.
+func (n *AtomicSwapNode) handleRedeemSwap(p peer.ID, payload []byte) error {
+	sr := new(pb.SwapRedeem)
+	if err := proto.Unmarshal(payload, sr); err != nil {
+		return err
+	}
+	s, err := n.swapForMessage(p, sr.SwapID, Participant, StateParticipated)
+	if err != nil {
+		return err
+	}
+	defer n.saveAndUnlock(s)
+
+	redeemTx, err := deserializeTx(sr.RedeemTx)
+	if err != nil {
+		return err
+	}
+	secret, err := swap.ExtractSecret(redeemTx, s.SecretHash)
+	if err != nil {
+		return err
+	}
+	return n.redeemWithSecret(s, secret)
+}
+
+// swapForMessage looks up the swap a message refers to and makes sure it came from
+// our counterparty and that we are in the state to handle it. The swap is returned locked.
+func (n *AtomicSwapNode) swapForMessage(p peer.ID, swapID string, role SwapRole, state SwapState) (*Swap, error) {
+	s, err := n.getSwap(swapID)
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	if s.Counterparty != p {
+		s.lock.Unlock()
+		return nil, errors.New("message not from swap counterparty")
+	}
+	if s.Role != role || s.State != state {
+		err := fmt.Errorf("unexpected message for %s in state %s", s.Role, s.State)
+		s.lock.Unlock()
+		return nil, err
+	}
+	return s, nil
+}
+
+// auditContract checks the counterparty's contract pays to our redeem key with the
+// agreed secret hash and amount and that it will not expire before minLockTime.
+func (n *AtomicSwapNode) auditContract(s *Swap, sc *pb.SwapContract, minLockTime time.Time) (*wire.MsgTx, error) {
+	c, err := swap.ParseContract(sc.Contract)
+	if err != nil {
+		return nil, err
+	}
+	if c.SecretHash != s.SecretHash {
+		return nil, errors.New("contract secret hash does not match")
+	}
+	if !bytes.Equal(c.RecipientHash[:], pubKeyHash(s.RedeemKey)) {
+		return nil, errors.New("contract does not pay to our redeem key")
+	}
+	if time.Unix(c.LockTime, 0).Before(minLockTime) {
+		return nil, errors.New("contract locktime is too soon")
+	}
+	contractTx, err := deserializeTx(sc.ContractTx)
+	if err != nil {
+		return nil, err
+	}
+	_, out, err := swap.ContractOutput(contractTx, sc.Contract)
+	if err != nil {
+		return nil, err
+	}
+	if out.Value < s.ReceiveAmount {
+		return nil, fmt.Errorf("contract pays %d, expected %d", out.Value, s.ReceiveAmount)
+	}
+	s.CounterpartyLockTime = c.LockTime
+	return contractTx, nil
+}
+
+// fundContract builds our contract with the given locktime, then funds and
+// broadcasts it on the send chain. The funding transaction is journaled before
+// it's broadcast so we can't lose track of it if we crash.
+func (n *AtomicSwapNode) fundContract(s *Swap, lockTime time.Time) error {
+	w, err := n.wallet(s.SendCoin)
+	if err != nil {
+		return err
+	}
+	c := &swap.Contract{
+		SecretHash: s.SecretHash,
+		LockTime:   lockTime.Unix(),
+	}
+	copy(c.RecipientHash[:], s.CounterpartyRedeemHash)
+	copy(c.RefundHash[:], pubKeyHash(s.RefundKey))
+	script, err := c.Script()
+	if err != nil {
+		return err
+	}
+	pkScript, err := c.PkScript()
+	if err != nil {
+		return err
+	}
+	// Our order's UTXO can fund a swap against the order, but not any other swap.
+	except := ""
+	if s.Role == Participant {
+		except = s.OrderID
+	}
+	n.utxoLock.Lock()
+	n.lockOrderUTXOs(except)
+	tx, err := w.FundContract(pkScript, s.SendAmount)
+	n.utxoLock.Unlock()
+	if err != nil {
+		return err
+	}
+	s.Contract = script
+	s.ContractTx = tx
+	s.LockTime = c.LockTime
+	if err := n.saveSwap(s); err != nil {
+		w.Unreserve(tx)
+		return err
+	}
+	if err := n.publishContract(s); err != nil {
+		// The swap fails so the coins can go towards another one
+		w.Unreserve(tx)
+		return err
+	}
+	return nil
+}
+
+// publishContract broadcasts our funded contract and moves the swap to the next state.
+func (n *AtomicSwapNode) publishContract(s *Swap) error {
+	backend, err := n.chainBackend(s.SendCoin)
+	if err != nil {
+		return err
+	}
+	if err := backend.Broadcast(s.ContractTx); err != nil {
+		return err
+	}
+	if s.Role == Initiator {
+		s.State = StateInitiated
+	} else {
+		s.State = StateParticipated
+		n.watchForSecret(s)
+	}
+	return nil
+}
+
+// sendContract sends our funded contract to the counterparty.
+func (n *AtomicSwapNode) sendContract(s *Swap) error {
+	t := pb.Message_InitiateSwap
+	if s.Role == Participant {
+		t = pb.Message_ParticipateSwap
+	}
+	return n.sendSwapMessage(s.Counterparty, t, &pb.SwapContract{
+		SwapID:     s.ID,
+		Contract:   s.Contract,
+		ContractTx: serializeTx(s.ContractTx),
+	})
+}
+
+// sendRedeem sends our redeem transaction to the participant so it learns the secret.
+func (n *AtomicSwapNode) sendRedeem(s *Swap) error {
+	return n.sendSwapMessage(s.Counterparty, pb.Message_RedeemSwap, &pb.SwapRedeem{
+		SwapID:   s.ID,
+		RedeemTx: serializeTx(s.RedeemTx),
+	})
+}
+
+// redeemContract redeems the counterparty's contract using the secret. The initiator's
+// swap is redeemed once the redeem is broadcast. The participant's stays redeem pending
+// until it confirms.
+func (n *AtomicSwapNode) redeemContract(s *Swap) error {
+	backend, err := n.chainBackend(s.ReceiveCoin)
+	if err != nil {
+		return err
+	}
+	tx, err := swap.NewRedeemTx(s.ReceiveCoin, s.CounterpartyContractTx, s.CounterpartyContract, s.Secret, s.RedeemKey, n.feeRate(backend))
+	if err != nil {
+		return err
+	}
+	s.RedeemTx = tx
+	if err := n.saveSwap(s); err != nil {
+		return err
+	}
+	if err := backend.Broadcast(tx); err != nil {
+		return err
+	}
+	if s.Role == Initiator {
+		s.State = StateRedeemed
+	}
+	return nil
+}
+
+// fillOrder is called when a swap against one of our orders completes. It publishes
+// an update lowering the order's remaining quantity by the amount swapped.
+func (n *AtomicSwapNode) fillOrder(s *Swap) {
+	order, mine, err := n.orderBook.GetOrder(s.OrderID)
+	if err != nil || !mine {
+		// The order was closed or expired while the swap was in progress.
+		return
+	}
+	remaining := uint64(0)
+	if q := s.quantity(); q < order.Remaining {
+		remaining = order.Remaining - q
+	}
+	// If there's nothing left close the order so peers keep a tombstone for it
+	if remaining == 0 {
+		if err := n.CloseOrder(s.OrderID); err != nil {
+			log.Errorf("Error closing filled order %s: %s", s.OrderID, err)
+			return
+		}
+		log.Infof("Order %s fully filled by swap %s", s.OrderID, s.ID)
+		return
+	}
+	if err := n.publishOrderUpdate(s.OrderID, order, pb.OrderUpdate_Fill, remaining, s.ID); err != nil {
+		log.Errorf("Error publishing fill of order %s for swap %s: %s", s.OrderID, s.ID, err)
+		return
+	}
+	log.Infof("Filled %d of order %s with swap %s, %d remaining", s.quantity(), s.OrderID, s.ID, remaining)
+}
+
+// pendingFills returns the quantity of our order tied up in swaps which haven't
+// finished yet. Swaps whose initiator is past InitiateTimeout don't count.
+func (n *AtomicSwapNode) pendingFills(orderID string) uint64 {
+	var pending uint64
+	for _, s := range n.Swaps() {
+		if s.OrderID != orderID || s.Role != Participant {
+			continue
+		}
+		s.lock.Lock()
+		state := s.State
+		expired := s.initiateExpired()
+		s.lock.Unlock()
+		if expired {
+			continue
+		}
+		switch state {
+		case StateTaken, StateInitiated, StateParticipated, StateRedeemPending:
+			pending += s.quantity()
+		}
+	}
+	return pending
+}
+
+// initiateExpired returns whether we're the participant and the initiator didn't
+// send its contract within InitiateTimeout. The caller must hold the swap's lock.
+func (s *Swap) initiateExpired() bool {
+	return s.Role == Participant && s.State == StateTaken && s.CounterpartyContractTx == nil &&
+		s.ContractTx == nil && time.Since(s.Created) > InitiateTimeout
+}
+
+func (n *AtomicSwapNode) sendSwapMessage(p peer.ID, t pb.Message_MessageType, msg proto.Message) error {
+	if n.wireService == nil {
+		return errors.New("wire service not initialized")
+	}
+	any, err := ptypes.MarshalAny(msg)
+	if err != nil {
+		return err
+	}
+	m := &pb.Message{
+		MessageType: t,
+		Payload:     any,
+	}
+	return n.wireService.SendMessage(p, m)
+}
+
+// setTerms sets the coins and amounts we send and receive for a swap filling quantity
+// of the order. Quantities are denominated in BTC satoshis and prices in BCH satoshis
+// per whole BTC. A BuyBTC order means the maker sends BCH and receives BTC.
+func (s *Swap) setTerms(order *pb.LimitOrder, quantity uint64) {
+	btcAmount, bchAmount := swap.OrderAmounts(quantity, order.Price)
+
+	sendBCH := order.BuyBTC == (s.Role == Participant)
+	if sendBCH {
+		s.SendCoin, s.SendAmount = swap.BCH, bchAmount
+		s.ReceiveCoin, s.ReceiveAmount = swap.BTC, btcAmount
+	} else {
+		s.SendCoin, s.SendAmount = swap.BTC, btcAmount
+		s.ReceiveCoin, s.ReceiveAmount = swap.BCH, bchAmount
+	}
+}
+
+// quantity returns the BTC satoshis traded in the swap.
+func (s *Swap) quantity() uint64 {
+	if s.SendCoin == swap.BTC {
+		return uint64(s.SendAmount)
+	}
+	return uint64(s.ReceiveAmount)
+}
+
+func pubKeyHash(key *btcec.PrivateKey) []byte {
+	return btcutil.Hash160(key.PubKey().SerializeCompressed())
+}
+
+func serializeTx(tx *wire.MsgTx) []byte {
+	var buf bytes.Buffer
+	tx.Serialize(&buf)
+	return buf.Bytes()
+}
+
+func deserializeTx(ser []byte) (*wire.MsgTx, error) {
+	tx := new(wire.MsgTx)
+	if err := tx.Deserialize(bytes.NewReader(ser)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
@@ -0,0 +1,496 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/cpacia/atomicswap/chain"
+	"github.com/cpacia/atomicswap/net/service"
+	"github.com/cpacia/atomicswap/pb"
+	"github.com/cpacia/atomicswap/repo"
+	"github.com/cpacia/atomicswap/swap"
+	"github.com/golang/protobuf/proto"
+	"github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	"github.com/libp2p/go-libp2p-peer"
+	"github.com/libp2p/go-libp2p-protocol"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testQuantity = 1000000
+	testPrice    = 1000000000
+)
+
+// testNet connects the hosts of the test nodes in memory. Swap messages of the
+// types in drop are lost on the way, like a counterparty that went away.
+type testNet struct {
+	hosts map[peer.ID]*testHost
+	drop  map[pb.Message_MessageType]bool
+	lock  sync.Mutex
+}
+
+func newTestNet() *testNet {
+	return &testNet{
+		hosts: make(map[peer.ID]*testHost),
+		drop:  make(map[pb.Message_MessageType]bool),
+	}
+}
+
+func (tn *testNet) dropMessages(t pb.Message_MessageType) {
+	tn.lock.Lock()
+	defer tn.lock.Unlock()
+	tn.drop[t] = true
+}
+
+func (tn *testNet) dropped(t pb.Message_MessageType) bool {
+	tn.lock.Lock()
+	defer tn.lock.Unlock()
+	return tn.drop[t]
+}
+
+// testHost implements just enough of a host for the wire service. Each stream
+// delivers the message written to it to the remote host's stream handler and
+// reads back whatever the handler replies.
+type testHost struct {
+	host.Host
+	id       peer.ID
+	net      *testNet
+	handlers map[protocol.ID]inet.StreamHandler
+	lock     sync.Mutex
+}
+
+func (h *testHost) ID() peer.ID {
+	return h.id
+}
+
+func (h *testHost) Network() inet.Network {
+	return testNetwork{}
+}
+
+func (h *testHost) SetStreamHandler(pid protocol.ID, handler inet.StreamHandler) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.handlers[pid] = handler
+}
+
+func (h *testHost) NewStream(ctx context.Context, p peer.ID, pids ...protocol.ID) (inet.Stream, error) {
+	h.net.lock.Lock()
+	remote, ok := h.net.hosts[p]
+	h.net.lock.Unlock()
+	if !ok || len(pids) == 0 {
+		return nil, errors.New("peer not found")
+	}
+	remote.lock.Lock()
+	handler, ok := remote.handlers[pids[0]]
+	remote.lock.Unlock()
+	if !ok {
+		return nil, errors.New("protocol not supported")
+	}
+	pr, pw := io.Pipe()
+	deliver := func(msg []byte, m *pb.Message) {
+		if h.net.dropped(m.MessageType) {
+			pw.Close()
+			return
+		}
+		go handler(&testStream{r: bytes.NewReader(msg), w: pw, conn: testConn{peer: h.id}})
+	}
+	return &testStream{r: pr, conn: testConn{peer: p}, deliver: deliver}, nil
+}
+
+type testNetwork struct {
+	inet.Network
+}
+
+func (testNetwork) Notify(inet.Notifiee) {}
+
+func (testNetwork) ClosePeer(peer.ID) error { return nil }
+
+// testStream buffers what's written to it and passes each complete message to
+// deliver, or writes it to w on the remote end. Reads come from r.
+type testStream struct {
+	inet.Stream
+	r       io.Reader
+	w       io.WriteCloser
+	conn    testConn
+	buf     bytes.Buffer
+	deliver func(msg []byte, m *pb.Message)
+}
+
+func (s *testStream) Read(p []byte) (int, error) {
+	if s.r == nil {
+		return 0, io.EOF
+	}
+	return s.r.Read(p)
+}
+
+func (s *testStream) Write(p []byte) (int, error) {
+	if s.w != nil {
+		return s.w.Write(p)
+	}
+	s.buf.Write(p)
+	for s.deliver != nil {
+		size, n := proto.DecodeVarint(s.buf.Bytes())
+		if n == 0 || s.buf.Len() < n+int(size) {
+			break
+		}
+		msg := append([]byte(nil), s.buf.Next(n+int(size))...)
+		m := new(pb.Message)
+		if err := proto.Unmarshal(msg[n:], m); err != nil {
+			return 0, err
+		}
+		s.deliver(msg, m)
+	}
+	return len(p), nil
+}
+
+func (s *testStream) Close() error {
+	if s.w != nil {
+		return s.w.Close()
+	}
+	return nil
+}
+
+func (s *testStream) Reset() error { return s.Close() }
+
+func (s *testStream) Conn() inet.Conn { return s.conn }
+
+type testConn struct {
+	inet.Conn
+	peer peer.ID
+}
+
+func (c testConn) RemotePeer() peer.ID { return c.peer }
+
+type testNode struct {
+	*AtomicSwapNode
+	wallets map[swap.Coin]*chain.SimWallet
+}
+
+// newTestNode returns a node swapping on the simulated chains with a funded
+// wallet on each of them. It's offline except for the swap protocol.
+func newTestNode(t *testing.T, dir string, tn *testNet, chains map[swap.Coin]*chain.SimChain) *testNode {
+	r, err := repo.NewRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPrivateKey(r.PrivKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &testHost{id: id, net: tn, handlers: make(map[protocol.ID]inet.StreamHandler)}
+	tn.lock.Lock()
+	tn.hosts[id] = h
+	tn.lock.Unlock()
+
+	n := &testNode{
+		AtomicSwapNode: NewAtomicSwapNode(r, h, nil, nil, swap.RegTest),
+		wallets:        make(map[swap.Coin]*chain.SimWallet),
+	}
+	n.SetWireService(service.NewWireService(n.msgChan, n.orderBook, h, n.scorer))
+	for coin, c := range chains {
+		w := chain.NewSimWallet(c)
+		if _, err := w.Deposit(100 * testPrice); err != nil {
+			t.Fatal(err)
+		}
+		n.wallets[coin] = w
+		n.SetWallet(coin, w)
+		n.SetChainBackend(coin, c)
+	}
+	go n.messageHandler()
+	return n
+}
+
+func (n *testNode) balance(coin swap.Coin) int64 {
+	confirmed, unconfirmed := n.wallets[coin].Balance()
+	return confirmed + unconfirmed
+}
+
+func (n *testNode) swapState(swapID string) SwapState {
+	s, err := n.getSwap(swapID)
+	if err != nil {
+		return StateTaken
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.State
+}
+
+// orderClosed waits for the order to leave the node's book. Orders are closed
+// through the message handler so it can take a moment.
+func (n *testNode) orderClosed(orderID string) bool {
+	for i := 0; i < 100; i++ {
+		if _, _, err := n.OrderBook().GetOrder(orderID); err != nil {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+// startSwap has the maker place an order selling BTC, passes it to the taker as if
+// it came in over pubsub, and has the taker take it.
+func startSwap(t *testing.T, maker, taker *testNode) *Swap {
+	orderID := placeOrder(t, maker, taker, testQuantity, testPrice, 0)
+	s, err := taker.TakeOrder(orderID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// mineUntil mines any transactions waiting on the chains until done returns true.
+func mineUntil(t *testing.T, chains map[swap.Coin]*chain.SimChain, done func() bool) {
+	timeout := time.After(10 * time.Second)
+	for !done() {
+		select {
+		case <-timeout:
+			t.Fatal("timed out")
+		case <-time.After(10 * time.Millisecond):
+		}
+		for _, c := range chains {
+			if len(c.Mempool()) > 0 {
+				c.MineBlocks(1)
+			}
+		}
+	}
+}
+
+func setupSwap(t *testing.T) (maker, taker *testNode, chains map[swap.Coin]*chain.SimChain, tn *testNet, cleanup func()) {
+	dir, err := ioutil.TempDir("", "atomicswap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chains = map[swap.Coin]*chain.SimChain{
+		swap.BTC: chain.NewSimChain(swap.BTC),
+		swap.BCH: chain.NewSimChain(swap.BCH),
+	}
+	tn = newTestNet()
+	maker = newTestNode(t, path.Join(dir, "maker"), tn, chains)
+	taker = newTestNode(t, path.Join(dir, "taker"), tn, chains)
+	for _, c := range chains {
+		c.MineBlocks(1)
+	}
+	return maker, taker, chains, tn, func() { os.RemoveAll(dir) }
+}
+
+func TestSwap(t *testing.T) {
+	maker, taker, chains, _, cleanup := setupSwap(t)
+	defer cleanup()
+
+	btcAmount, bchAmount := swap.OrderAmounts(testQuantity, testPrice)
+	makerBTC, makerBCH := maker.balance(swap.BTC), maker.balance(swap.BCH)
+	takerBTC, takerBCH := taker.balance(swap.BTC), taker.balance(swap.BCH)
+
+	s := startSwap(t, maker, taker)
+	mineUntil(t, chains, func() bool {
+		maker.retryRedeems()
+		return taker.swapState(s.ID) == StateRedeemed && maker.swapState(s.ID) == StateRedeemed
+	})
+
+	// The maker's order was filled
+	if !maker.orderClosed(s.OrderID) {
+		t.Error("filled order still in the maker's book")
+	}
+
+	// Each side paid the swap amount plus fees and received the swap amount less fees
+	const maxFees = 10000
+	checkBalance := func(name string, got, want int64) {
+		if got > want || got < want-maxFees {
+			t.Errorf("%s balance %d, want %d less fees", name, got, want)
+		}
+	}
+	checkBalance("maker BTC", maker.balance(swap.BTC), makerBTC-btcAmount)
+	checkBalance("maker BCH", maker.balance(swap.BCH), makerBCH+bchAmount)
+	checkBalance("taker BTC", taker.balance(swap.BTC), takerBTC+btcAmount)
+	checkBalance("taker BCH", taker.balance(swap.BCH), takerBCH-bchAmount)
+}
+
+// failingBackend fails to broadcast while fail is set.
+type failingBackend struct {
+	chain.ChainBackend
+	fail bool
+	lock sync.Mutex
+}
+
+func (b *failingBackend) setFail(fail bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.fail = fail
+}
+
+func (b *failingBackend) Broadcast(tx *wire.MsgTx) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.fail {
+		return errors.New("broadcast failed")
+	}
+	return b.ChainBackend.Broadcast(tx)
+}
+
+func TestSwapRedeemRetry(t *testing.T) {
+	maker, taker, chains, _, cleanup := setupSwap(t)
+	defer cleanup()
+
+	// The maker can't broadcast its redeem when it learns the secret
+	backend := &failingBackend{ChainBackend: chains[swap.BCH], fail: true}
+	maker.SetChainBackend(swap.BCH, backend)
+
+	s := startSwap(t, maker, taker)
+	mineUntil(t, chains, func() bool {
+		return taker.swapState(s.ID) == StateRedeemed && maker.swapState(s.ID) == StateRedeemPending
+	})
+	maker.retryRedeems()
+	if state := maker.swapState(s.ID); state != StateRedeemPending {
+		t.Fatalf("maker swap is %s, want redeem pending", state)
+	}
+
+	backend.setFail(false)
+	mineUntil(t, chains, func() bool {
+		maker.retryRedeems()
+		return maker.swapState(s.ID) == StateRedeemed
+	})
+	if !maker.orderClosed(s.OrderID) {
+		t.Error("filled order still in the maker's book")
+	}
+}
+
+func TestSwapBroadcastFailure(t *testing.T) {
+	maker, taker, chains, _, cleanup := setupSwap(t)
+	defer cleanup()
+
+	// The taker pays BCH but can't broadcast its contract so the swap fails
+	backend := &failingBackend{ChainBackend: chains[swap.BCH], fail: true}
+	taker.SetChainBackend(swap.BCH, backend)
+
+	s := startSwap(t, maker, taker)
+	for i := 0; i < 100 && taker.swapState(s.ID) != StateFailed; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if state := taker.swapState(s.ID); state != StateFailed {
+		t.Fatalf("taker swap is %s, want failed", state)
+	}
+
+	// The coins it tried to spend can fund another contract
+	if _, err := taker.wallets[s.SendCoin].FundContract([]byte{0x51}, s.SendAmount); err != nil {
+		t.Errorf("funding after the failed broadcast: %s", err)
+	}
+}
+
+func TestSwapNeverInitiated(t *testing.T) {
+	maker, taker, _, tn, cleanup := setupSwap(t)
+	defer cleanup()
+
+	// The taker's contract never reaches the maker
+	tn.dropMessages(pb.Message_InitiateSwap)
+
+	s := startSwap(t, maker, taker)
+	for i := 0; i < 100 && taker.swapState(s.ID) != StateInitiated; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	ms, err := maker.getSwap(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending := maker.pendingFills(s.OrderID); pending != testQuantity {
+		t.Fatalf("got %d pending, want %d", pending, testQuantity)
+	}
+
+	// Once the timeout passes the swap no longer ties up the order
+	ms.lock.Lock()
+	ms.Created = time.Now().Add(-InitiateTimeout - time.Minute)
+	ms.lock.Unlock()
+	if pending := maker.pendingFills(s.OrderID); pending != 0 {
+		t.Errorf("got %d pending after the timeout, want 0", pending)
+	}
+	maker.failUninitiated()
+	if state := maker.swapState(s.ID); state != StateFailed {
+		t.Errorf("maker swap is %s, want failed", state)
+	}
+
+	// The deadline survives a restart
+	ms.lock.Lock()
+	loaded, err := swapFromRecord(ms.record())
+	ms.lock.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Created.Unix() != ms.Created.Unix() {
+		t.Errorf("loaded swap created %s, want %s", loaded.Created, ms.Created)
+	}
+
+	// Someone else can take the order
+	s2, err := taker.TakeOrder(s.OrderID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && taker.swapState(s2.ID) == StateTaken; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if state := taker.swapState(s2.ID); state != StateInitiated {
+		t.Errorf("second swap is %s, want initiated", state)
+	}
+}
+
+func TestSwapRefund(t *testing.T) {
+	maker, taker, chains, tn, cleanup := setupSwap(t)
+	defer cleanup()
+
+	// The taker never hears about the maker's contract so neither side can redeem
+	tn.dropMessages(pb.Message_ParticipateSwap)
+
+	makerBTC, takerBCH := maker.balance(swap.BTC), taker.balance(swap.BCH)
+	s := startSwap(t, maker, taker)
+	mineUntil(t, chains, func() bool {
+		return maker.swapState(s.ID) == StateParticipated
+	})
+
+	// Nothing can be refunded until the locktimes pass
+	taker.refundExpired()
+	maker.refundExpired()
+	if taker.swapState(s.ID) == StateRefunded || maker.swapState(s.ID) == StateRefunded {
+		t.Fatal("contract refunded before its locktime")
+	}
+
+	lockTime := time.Now().Add(InitiatorLockTime)
+	for _, c := range chains {
+		c.AdvanceTime(InitiatorLockTime)
+		for {
+			median, err := c.MedianTime()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if median.After(lockTime) {
+				break
+			}
+			c.MineBlocks(1)
+		}
+	}
+	taker.refundExpired()
+	maker.refundExpired()
+	if state := taker.swapState(s.ID); state != StateRefunded {
+		t.Errorf("taker swap is %s, want refunded", state)
+	}
+	if state := maker.swapState(s.ID); state != StateRefunded {
+		t.Errorf("maker swap is %s, want refunded", state)
+	}
+	for _, c := range chains {
+		c.MineBlocks(1)
+	}
+
+	// All that's lost are the fees
+	const maxFees = 10000
+	if got := maker.balance(swap.BTC); got > makerBTC || got < makerBTC-maxFees {
+		t.Errorf("maker BTC balance %d, want %d less fees", got, makerBTC)
+	}
+	if got := taker.balance(swap.BCH); got > takerBCH || got < takerBCH-maxFees {
+		t.Errorf("taker BCH balance %d, want %d less fees", got, takerBCH)
+	}
+}
@@ -0,0 +1,256 @@
+package core
+
+import (
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/cpacia/atomicswap/pb"
+	"github.com/cpacia/atomicswap/swap"
+	"github.com/golang/protobuf/proto"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/libp2p/go-libp2p-peer"
+	"time"
+)
+
+// Swaps are journaled in the datastore under this prefix keyed by swap ID.
+const swapsPrefix = "/swaps"
+
+func swapKey(swapID string) ds.Key {
+	return ds.NewKey(swapsPrefix + "/" + swapID)
+}
+
+// saveSwap writes the current state of the swap to the datastore. The caller
+// must hold the swap lock. This needs to happen before we broadcast anything
+// otherwise a crash could leave us with funds in a contract we can't refund.
+// State changes are published straight to the event subscribers, which never
+// blocks, so it's safe to call with the swap lock held.
+func (n *AtomicSwapNode) saveSwap(s *Swap) error {
+	ser, err := proto.Marshal(s.record())
+	if err != nil {
+		return err
+	}
+	if err := n.repo.Datastore().Put(swapKey(s.ID), ser); err != nil {
+		return err
+	}
+	if !s.saved || s.State != s.savedState {
+		s.saved = true
+		s.savedState = s.State
+		n.publishEvent(Event{Type: EventSwapState, Swap: s, State: s.State})
+	}
+	return nil
+}
+
+// saveAndUnlock journals the swap then releases its lock. The swap handlers
+// defer this so every state change they make is persisted.
+func (n *AtomicSwapNode) saveAndUnlock(s *Swap) {
+	if err := n.saveSwap(s); err != nil {
+		log.Errorf("Error saving swap %s: %s", s.ID, err)
+	}
+	s.lock.Unlock()
+}
+
+// loadSwaps reads all the swaps from the datastore into memory.
+func (n *AtomicSwapNode) loadSwaps() error {
+	results, err := n.repo.Datastore().Query(query.Query{Prefix: swapsPrefix})
+	if err != nil {
+		return err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		ser, ok := e.Value.([]byte)
+		if !ok {
+			continue
+		}
+		rec := new(pb.SwapRecord)
+		if err := proto.Unmarshal(ser, rec); err != nil {
+			log.Errorf("Error unmarshalling swap %s: %s", e.Key, err)
+			continue
+		}
+		s, err := swapFromRecord(rec)
+		if err != nil {
+			log.Errorf("Error loading swap %s: %s", rec.SwapID, err)
+			continue
+		}
+		n.addSwap(s)
+	}
+	return nil
+}
+
+// resumeSwaps picks up the swaps that were in progress when we shut down. The
+// message handler has to be running as finishing a swap can update our order.
+func (n *AtomicSwapNode) resumeSwaps() {
+	for _, s := range n.Swaps() {
+		s.lock.Lock()
+		if err := n.resumeSwap(s); err != nil {
+			log.Errorf("Error resuming swap %s: %s", s.ID, err)
+		}
+		n.saveAndUnlock(s)
+	}
+}
+
+// resumeSwap finishes whatever step the swap was in the middle of. Anything we
+// broadcast may already be on chain so rebroadcasting is harmless. Likewise the
+// counterparty ignores messages it has already processed.
+func (n *AtomicSwapNode) resumeSwap(s *Swap) error {
+	switch s.State {
+	case StateTaken:
+		// We may have been waiting for the initiator's contract to confirm.
+		if s.awaitingContract() {
+			return n.awaitContract(s)
+		}
+		// We may have crashed between funding our contract and broadcasting it.
+		if s.ContractTx == nil {
+			return nil
+		}
+		if err := n.publishContract(s); err != nil {
+			return err
+		}
+		log.Infof("Resumed swap %s, published contract %s", s.ID, s.ContractTx.TxHash().String())
+		return n.sendContract(s)
+	case StateInitiated, StateParticipated:
+		// We may have crashed between signing our redeem transaction and broadcasting it.
+		if s.RedeemTx != nil && s.Role == Participant {
+			s.State = StateRedeemPending
+			return n.retryRedeem(s)
+		}
+		if s.RedeemTx != nil {
+			backend, err := n.chainBackend(s.ReceiveCoin)
+			if err != nil {
+				return err
+			}
+			if err := backend.Broadcast(s.RedeemTx); err != nil {
+				return err
+			}
+			s.State = StateRedeemed
+			log.Infof("Resumed swap %s, published redeem %s", s.ID, s.RedeemTx.TxHash().String())
+			return n.sendRedeem(s)
+		}
+		log.Infof("Resumed swap %s in state %s", s.ID, s.State)
+		if s.State == StateParticipated {
+			n.watchForSecret(s)
+		}
+		if s.awaitingContract() {
+			if err := n.awaitContract(s); err != nil {
+				return err
+			}
+		}
+		return n.sendContract(s)
+	case StateRedeemPending:
+		return n.retryRedeem(s)
+	case StateRedeemed:
+		// The participant needs our redeem transaction to learn the secret. Send
+		// it again in case it never got it.
+		if s.Role == Initiator {
+			return n.sendRedeem(s)
+		}
+	}
+	return nil
+}
+
+// record converts the swap into its protobuf form for storage.
+func (s *Swap) record() *pb.SwapRecord {
+	rec := &pb.SwapRecord{
+		SwapID:                 s.ID,
+		OrderID:                s.OrderID,
+		Role:                   uint32(s.Role),
+		State:                  uint32(s.State),
+		Counterparty:           s.Counterparty.Pretty(),
+		SendCoin:               uint32(s.SendCoin),
+		ReceiveCoin:            uint32(s.ReceiveCoin),
+		SendAmount:             s.SendAmount,
+		ReceiveAmount:          s.ReceiveAmount,
+		Secret:                 s.Secret,
+		SecretHash:             s.SecretHash[:],
+		CounterpartyRedeemHash: s.CounterpartyRedeemHash,
+		Contract:               s.Contract,
+		LockTime:               s.LockTime,
+		CounterpartyContract:   s.CounterpartyContract,
+		CounterpartyLockTime:   s.CounterpartyLockTime,
+		RefundError:            s.RefundError,
+	}
+	if !s.Created.IsZero() {
+		rec.Created = s.Created.Unix()
+	}
+	if s.RedeemKey != nil {
+		rec.RedeemKey = s.RedeemKey.Serialize()
+	}
+	if s.RefundKey != nil {
+		rec.RefundKey = s.RefundKey.Serialize()
+	}
+	if s.ContractTx != nil {
+		rec.ContractTx = serializeTx(s.ContractTx)
+	}
+	if s.CounterpartyContractTx != nil {
+		rec.CounterpartyContractTx = serializeTx(s.CounterpartyContractTx)
+	}
+	if s.RedeemTx != nil {
+		rec.RedeemTx = serializeTx(s.RedeemTx)
+	}
+	if s.RefundTx != nil {
+		rec.RefundTx = serializeTx(s.RefundTx)
+	}
+	return rec
+}
+
+// swapFromRecord rebuilds a swap from its stored protobuf form.
+func swapFromRecord(rec *pb.SwapRecord) (*Swap, error) {
+	counterparty, err := peer.IDB58Decode(rec.Counterparty)
+	if err != nil {
+		return nil, err
+	}
+	s := &Swap{
+		ID:                     rec.SwapID,
+		OrderID:                rec.OrderID,
+		Role:                   SwapRole(rec.Role),
+		State:                  SwapState(rec.State),
+		Counterparty:           counterparty,
+		SendCoin:               swap.Coin(rec.SendCoin),
+		ReceiveCoin:            swap.Coin(rec.ReceiveCoin),
+		SendAmount:             rec.SendAmount,
+		ReceiveAmount:          rec.ReceiveAmount,
+		Secret:                 rec.Secret,
+		CounterpartyRedeemHash: rec.CounterpartyRedeemHash,
+		Contract:               rec.Contract,
+		LockTime:               rec.LockTime,
+		CounterpartyContract:   rec.CounterpartyContract,
+		CounterpartyLockTime:   rec.CounterpartyLockTime,
+		RefundError:            rec.RefundError,
+		savedState:             SwapState(rec.State),
+		saved:                  true,
+	}
+	// Swaps saved before we kept the time get a fresh start
+	s.Created = time.Now()
+	if rec.Created != 0 {
+		s.Created = time.Unix(rec.Created, 0)
+	}
+	copy(s.SecretHash[:], rec.SecretHash)
+	if len(rec.RedeemKey) > 0 {
+		s.RedeemKey, _ = btcec.PrivKeyFromBytes(btcec.S256(), rec.RedeemKey)
+	}
+	if len(rec.RefundKey) > 0 {
+		s.RefundKey, _ = btcec.PrivKeyFromBytes(btcec.S256(), rec.RefundKey)
+	}
+	txs := []struct {
+		ser []byte
+		tx  **wire.MsgTx
+	}{
+		{rec.ContractTx, &s.ContractTx},
+		{rec.CounterpartyContractTx, &s.CounterpartyContractTx},
+		{rec.RedeemTx, &s.RedeemTx},
+		{rec.RefundTx, &s.RefundTx},
+	}
+	for _, t := range txs {
+		if len(t.ser) == 0 {
+			continue
+		}
+		tx, err := deserializeTx(t.ser)
+		if err != nil {
+			return nil, err
+		}
+		*t.tx = tx
+	}
+	return s, nil
+}
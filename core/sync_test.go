@@ -0,0 +1,73 @@
+package core
+
+import (
+	"github.com/cpacia/atomicswap/pb"
+	"github.com/golang/protobuf/proto"
+	"testing"
+	"time"
+)
+
+func TestOrderBookSync(t *testing.T) {
+	maker, taker, _, _, cleanup := setupSwap(t)
+	defer cleanup()
+
+	// Both books have the maker's first order but only the maker saw it closed
+	closed, _, msg, err := maker.addLimitOrder(testQuantity, testPrice, false, 0, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := new(pb.Message)
+	if err := proto.Unmarshal(msg, m); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	taker.msgChan <- newOrder{serializedMessage: m.Payload.Value, from: maker.PeerID(), done: done}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := maker.CloseOrder(closed); err != nil {
+		t.Fatal(err)
+	}
+	if !maker.orderClosed(closed) {
+		t.Fatal("closed order still in the maker's book")
+	}
+
+	// Each has an order the other hasn't seen
+	makerOrder, _, _, err := maker.addLimitOrder(testQuantity, testPrice, true, 0, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	takerOrder, _, _, err := taker.addLimitOrder(testQuantity, testPrice, false, 0, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if proto.Equal(maker.OrderBook().Digest(), taker.OrderBook().Digest()) {
+		t.Fatal("order books match before syncing")
+	}
+	if err := taker.wireService.SyncOrderBook(maker.PeerID()); err != nil {
+		t.Fatal(err)
+	}
+	// The orders the taker pushes are processed by the maker in the background
+	for i := 0; i < 100 && !proto.Equal(maker.OrderBook().Digest(), taker.OrderBook().Digest()); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !proto.Equal(maker.OrderBook().Digest(), taker.OrderBook().Digest()) {
+		t.Fatal("order books differ after syncing")
+	}
+	if !taker.orderClosed(closed) {
+		t.Error("taker didn't get the close")
+	}
+	if _, _, err := taker.OrderBook().GetOrder(makerOrder); err != nil {
+		t.Errorf("taker didn't get the maker's order: %s", err)
+	}
+	if _, _, err := maker.OrderBook().GetOrder(takerOrder); err != nil {
+		t.Errorf("maker didn't get the taker's order: %s", err)
+	}
+
+	// There's nothing left to exchange
+	want, push := taker.OrderBook().Reconcile(maker.OrderBook().Inventory(taker.OrderBook().Digest()))
+	if len(want) != 0 || len(push) != 0 {
+		t.Errorf("books still want %v and push %v after syncing", want, push)
+	}
+}
@@ -0,0 +1,235 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/cpacia/atomicswap/chain"
+	"github.com/cpacia/atomicswap/swap"
+	"time"
+)
+
+// errContractNotSeen is returned when the chain backend doesn't know about the
+// counterparty's contract transaction yet.
+var errContractNotSeen = errors.New("counterparty contract not seen on chain")
+
+// verifyCounterpartyContract checks the counterparty's contract output is on the
+// receive chain, unspent and pays the contract at least the amount we're owed. The
+// contract transaction they sent us proves nothing on its own as it may never have
+// been broadcast. It returns the number of confirmations the output has.
+func (n *AtomicSwapNode) verifyCounterpartyContract(s *Swap) (int32, error) {
+	backend, err := n.chainBackend(s.ReceiveCoin)
+	if err != nil {
+		return 0, err
+	}
+	idx, out, err := swap.ContractOutput(s.CounterpartyContractTx, s.CounterpartyContract)
+	if err != nil {
+		return 0, err
+	}
+	txid := s.CounterpartyContractTx.TxHash()
+	txOut, confirmations, err := backend.GetTxOut(*wire.NewOutPoint(&txid, idx))
+	if err == chain.ErrTxOutSpent {
+		// GetTxOut can't tell a spent output from one it's never seen
+		if _, _, err := backend.GetTransaction(txid); err == chain.ErrTxNotFound {
+			return 0, errContractNotSeen
+		}
+		return 0, errors.New("counterparty contract output is already spent")
+	} else if err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(txOut.PkScript, out.PkScript) {
+		return 0, errors.New("counterparty contract output on chain does not pay to the contract")
+	}
+	if txOut.Value < s.ReceiveAmount {
+		return 0, fmt.Errorf("counterparty contract output on chain pays %d, expected %d", txOut.Value, s.ReceiveAmount)
+	}
+	return confirmations, nil
+}
+
+// awaitContract watches for the counterparty's contract to confirm and then carries
+// on with the swap. If it hasn't confirmed after ContractConfirmTimeout we give up.
+// The participant hasn't funded anything yet so its swap just fails. The initiator's
+// contract will be refunded once its locktime expires.
+func (n *AtomicSwapNode) awaitContract(s *Swap) error {
+	backend, err := n.chainBackend(s.ReceiveCoin)
+	if err != nil {
+		return err
+	}
+	pkScript, err := swap.P2SHScript(s.CounterpartyContract)
+	if err != nil {
+		return err
+	}
+	sub, err := backend.WatchScript(pkScript)
+	if err != nil {
+		return err
+	}
+	contractHash := s.CounterpartyContractTx.TxHash()
+	go func() {
+		defer sub.Close()
+		timeout := time.NewTimer(ContractConfirmTimeout)
+		defer timeout.Stop()
+		for {
+			select {
+			case notification := <-sub.C:
+				if notification.Height == 0 || notification.Tx.TxHash() != contractHash {
+					continue
+				}
+				if done := n.processContractConfirmed(s); done {
+					return
+				}
+			case <-timeout.C:
+				s.lock.Lock()
+				if s.awaitingContract() {
+					log.Warningf("Counterparty contract for swap %s did not confirm in time", s.ID)
+					if s.Role == Participant {
+						s.State = StateFailed
+					}
+				}
+				n.saveAndUnlock(s)
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// processContractConfirmed handles the counterparty's contract confirming. It returns
+// true once there's nothing left to wait for.
+func (n *AtomicSwapNode) processContractConfirmed(s *Swap) bool {
+	s.lock.Lock()
+	defer n.saveAndUnlock(s)
+
+	if !s.awaitingContract() {
+		return true
+	}
+	confirmations, err := n.verifyCounterpartyContract(s)
+	if err == errContractNotSeen || (err == nil && confirmations < ContractConfirmations) {
+		return false
+	}
+	if err == nil {
+		err = n.contractConfirmed(s)
+	} else if s.Role == Participant {
+		s.State = StateFailed
+	}
+	if err != nil {
+		log.Errorf("Error continuing swap %s: %s", s.ID, err)
+	}
+	return true
+}
+
+// watchForSecret is started once the participant's contract is funded. The
+// initiator has to reveal the secret on chain to redeem our contract so we watch
+// for the transaction spending it, pull the secret out of its scriptSig and use
+// it to redeem the initiator's contract. This way we don't depend on the initiator
+// sending us a RedeemSwap message.
+func (n *AtomicSwapNode) watchForSecret(s *Swap) {
+	backend, err := n.chainBackend(s.SendCoin)
+	if err != nil {
+		log.Errorf("Can't watch swap %s for the secret: %s", s.ID, err)
+		return
+	}
+	idx, _, err := swap.ContractOutput(s.ContractTx, s.Contract)
+	if err != nil {
+		log.Errorf("Can't watch swap %s for the secret: %s", s.ID, err)
+		return
+	}
+	contractHash := s.ContractTx.TxHash()
+	sub, err := backend.WatchOutpoint(*wire.NewOutPoint(&contractHash, idx))
+	if err != nil {
+		log.Errorf("Can't watch swap %s for the secret: %s", s.ID, err)
+		return
+	}
+	go func() {
+		defer sub.Close()
+		for notification := range sub.C {
+			if done := n.processContractSpend(s, notification.Tx); done {
+				return
+			}
+		}
+	}()
+}
+
+// processContractSpend handles a transaction spending our participant contract.
+// It returns true once there's nothing left to watch for.
+func (n *AtomicSwapNode) processContractSpend(s *Swap, tx *wire.MsgTx) bool {
+	s.lock.Lock()
+	defer n.saveAndUnlock(s)
+
+	// We may have already redeemed using the RedeemSwap message or refunded.
+	if s.State != StateParticipated {
+		return true
+	}
+	secret, err := swap.ExtractSecret(tx, s.SecretHash)
+	if err != nil {
+		// Not a redeem. It could be our own refund which will move us out of
+		// the participated state.
+		return false
+	}
+	log.Infof("Found secret for swap %s in transaction %s", s.ID, tx.TxHash().String())
+
+	// We won't see this spend again so if the redeem fails it's left to the
+	// watchdog to retry.
+	if err := n.redeemWithSecret(s, secret); err != nil {
+		log.Errorf("Error redeeming swap %s: %s", s.ID, err)
+	}
+	return true
+}
+
+// redeemWithSecret redeems the initiator's contract once we've learned the
+// secret. The swap is redeem pending from here until the redeem confirms and
+// the watchdog retries the redeem until then. The caller must hold the swap lock.
+func (n *AtomicSwapNode) redeemWithSecret(s *Swap, secret []byte) error {
+	s.Secret = secret
+	s.State = StateRedeemPending
+	if err := n.saveSwap(s); err != nil {
+		return err
+	}
+	if err := n.redeemContract(s); err != nil {
+		return err
+	}
+	log.Infof("Broadcast redeem of initiator contract for swap %s", s.ID)
+	return nil
+}
+
+func (n *AtomicSwapNode) retryRedeems() {
+	for _, s := range n.Swaps() {
+		s.lock.Lock()
+		if s.State != StateRedeemPending {
+			s.lock.Unlock()
+			continue
+		}
+		if err := n.retryRedeem(s); err != nil {
+			log.Errorf("Error redeeming swap %s: %s", s.ID, err)
+		}
+		n.saveAndUnlock(s)
+	}
+}
+
+// retryRedeem checks on the redeem of a redeem pending swap. Once it confirms the
+// swap is redeemed and our order filled. If the node doesn't know about it we
+// broadcast it again, and if the node won't take it we build a new one. The caller
+// must hold the swap lock.
+func (n *AtomicSwapNode) retryRedeem(s *Swap) error {
+	backend, err := n.chainBackend(s.ReceiveCoin)
+	if err != nil {
+		return err
+	}
+	if s.RedeemTx != nil {
+		_, confirmations, err := backend.GetTransaction(s.RedeemTx.TxHash())
+		if err == nil {
+			if confirmations > 0 {
+				s.State = StateRedeemed
+				log.Infof("Redeem %s for swap %s confirmed", s.RedeemTx.TxHash().String(), s.ID)
+				n.fillOrder(s)
+			}
+			return nil
+		} else if err != chain.ErrTxNotFound {
+			return err
+		}
+		if err := backend.Broadcast(s.RedeemTx); err == nil {
+			return nil
+		}
+	}
+	return n.redeemContract(s)
+}
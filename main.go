@@ -12,6 +12,38 @@ func main() {
 		"start the app",
 		"The start command starts the app and connects to the p2p network",
 		&cmd.Start{})
+	parser.AddCommand("placeorder",
+		"place a limit order",
+		"The placeorder command publishes a new limit order from a running node",
+		&cmd.PlaceOrder{})
+	parser.AddCommand("amendorder",
+		"amend a limit order",
+		"The amendorder command replaces one of a running node's orders with one at a new price or quantity",
+		&cmd.AmendOrder{})
+	parser.AddCommand("closeorder",
+		"close a limit order",
+		"The closeorder command closes one of a running node's open orders",
+		&cmd.CloseOrder{})
+	parser.AddCommand("orderbook",
+		"print the order book",
+		"The orderbook command prints a running node's view of the order book",
+		&cmd.OrderBook{})
+	parser.AddCommand("take",
+		"take an order",
+		"The take command takes an order from the order book and starts a swap",
+		&cmd.Take{})
+	parser.AddCommand("swaps",
+		"list swaps",
+		"The swaps command lists a running node's swaps and their state",
+		&cmd.Swaps{})
+	parser.AddCommand("balance",
+		"print wallet balances",
+		"The balance command prints the balance of a running node's wallets",
+		&cmd.Balance{})
+	parser.AddCommand("peers",
+		"list peers",
+		"The peers command lists the peers a running node is connected to",
+		&cmd.Peers{})
 	if _, err := parser.Parse(); err != nil {
 		os.Exit(1)
 	}
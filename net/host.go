@@ -2,7 +2,7 @@ package net
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"github.com/cpacia/atomicswap/repo"
 	"github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p-host"
@@ -11,11 +11,15 @@ import (
 
 var log = logging.MustGetLogger("net")
 
-func NewPeerHost(port int, repo *repo.Repo) (host.Host, error) {
+// NewPeerHost creates a libp2p host listening on the multiaddrs, such as /ip4/0.0.0.0/tcp/9000.
+func NewPeerHost(listenAddrs []string, repo *repo.Repo) (host.Host, error) {
+	if len(listenAddrs) == 0 {
+		return nil, errors.New("no listen addresses")
+	}
 	privKey := repo.PrivKey()
 
 	opts := []libp2p.Option{
-		libp2p.ListenAddrStrings(fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", port)),
+		libp2p.ListenAddrStrings(listenAddrs...),
 		libp2p.Identity(privKey),
 	}
 
@@ -0,0 +1,335 @@
+package score
+
+import (
+	"fmt"
+	ob "github.com/cpacia/atomicswap/orderbook"
+	"github.com/cpacia/atomicswap/pb"
+	"github.com/golang/protobuf/proto"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/libp2p/go-libp2p-peer"
+	"github.com/op/go-logging"
+	"sync"
+	"time"
+)
+
+var log = logging.MustGetLogger("score")
+
+const (
+	// StartingScore is the score of a peer we haven't seen misbehave. It's also the most
+	// a peer can recover to.
+	StartingScore = 100
+
+	// BanThreshold is the score at or below which a peer is banned.
+	BanThreshold = 0
+
+	// BanDuration is how long a banned peer is refused.
+	BanDuration = time.Hour * 24
+
+	// RecoveryInterval is how often a peer gets a point back.
+	RecoveryInterval = time.Minute
+
+	// MessagesPerMinute is how many messages a peer may send us on average. Up to
+	// MessageBurst can be sent at once.
+	MessagesPerMinute = 120
+	MessageBurst      = 240
+)
+
+// Penalties for the ways a peer can misbehave.
+const (
+	PenaltyMalformedMessage = 20
+	PenaltyInvalidSignature = 50
+	PenaltyExpiredOrder     = 5
+	PenaltyInvalidOrder     = 20
+	PenaltyUnfundedOrder    = 10
+	PenaltyTooManyOrders    = 10
+	PenaltyRateLimited      = 5
+)
+
+// Bans are stored in the datastore under this prefix keyed by peer ID.
+const bansPrefix = "/bans"
+
+func banKey(p peer.ID) ds.Key {
+	return ds.NewKey(bansPrefix + "/" + p.Pretty())
+}
+
+// Ban is a peer we're refusing to talk to until the ban expires.
+type Ban struct {
+	PeerID string    `json:"peerID"`
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason"`
+}
+
+type peerScore struct {
+	score   int
+	updated time.Time
+	tokens  float64
+	filled  time.Time
+}
+
+// Scorer keeps track of how well behaved each peer is. Peers lose points for sending
+// us bad messages and slowly earn them back. A peer which drops to the ban threshold
+// is banned and the ban handler is called so the node can disconnect it.
+type Scorer struct {
+	db    ds.Datastore
+	peers map[peer.ID]*peerScore
+	bans  map[peer.ID]Ban
+	onBan func(p peer.ID)
+	lock  sync.Mutex
+}
+
+// NewScorer returns a new scorer and loads the bans saved in the datastore.
+func NewScorer(db ds.Datastore) *Scorer {
+	s := &Scorer{
+		db:    db,
+		peers: make(map[peer.ID]*peerScore),
+		bans:  make(map[peer.ID]Ban),
+	}
+	if db != nil {
+		if err := s.loadBans(); err != nil {
+			log.Errorf("Error loading bans: %s", err)
+		}
+	}
+	return s
+}
+
+// SetBanHandler sets a function to be called when a peer is banned.
+func (s *Scorer) SetBanHandler(handler func(p peer.ID)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.onBan = handler
+}
+
+// get returns the peer's score brought up to date with the points it's recovered.
+// The caller must hold the lock.
+func (s *Scorer) get(p peer.ID) *peerScore {
+	now := time.Now()
+	ps, ok := s.peers[p]
+	if !ok {
+		ps = &peerScore{score: StartingScore, updated: now, tokens: MessageBurst, filled: now}
+		s.peers[p] = ps
+		return ps
+	}
+	recovered := int(now.Sub(ps.updated) / RecoveryInterval)
+	if recovered > 0 {
+		ps.score += recovered
+		if ps.score > StartingScore {
+			ps.score = StartingScore
+		}
+		ps.updated = ps.updated.Add(time.Duration(recovered) * RecoveryInterval)
+	}
+	return ps
+}
+
+// Score returns the peer's current score.
+func (s *Scorer) Score(p peer.ID) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.get(p).score
+}
+
+// Scores returns the score of every peer that's misbehaved and not yet recovered.
+func (s *Scorer) Scores() map[peer.ID]int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	scores := make(map[peer.ID]int)
+	for p := range s.peers {
+		if score := s.get(p).score; score < StartingScore {
+			scores[p] = score
+		}
+	}
+	return scores
+}
+
+// Allow is called for each message we get from the peer. It returns false if the peer
+// is banned or is sending messages faster than the rate limit, in which case the
+// message should be dropped. Going over the limit costs the peer points.
+func (s *Scorer) Allow(p peer.ID) bool {
+	s.lock.Lock()
+	if s.banned(p) {
+		s.lock.Unlock()
+		return false
+	}
+	ps := s.get(p)
+	now := time.Now()
+	ps.tokens += now.Sub(ps.filled).Minutes() * MessagesPerMinute
+	if ps.tokens > MessageBurst {
+		ps.tokens = MessageBurst
+	}
+	ps.filled = now
+	if ps.tokens >= 1 {
+		ps.tokens--
+		s.lock.Unlock()
+		return true
+	}
+	s.lock.Unlock()
+	s.Penalize(p, PenaltyRateLimited, "rate limit exceeded")
+	return false
+}
+
+// Penalize takes points off the peer's score and bans it if it drops to the threshold.
+func (s *Scorer) Penalize(p peer.ID, points int, reason string) {
+	if points <= 0 {
+		return
+	}
+	s.lock.Lock()
+	if s.banned(p) {
+		s.lock.Unlock()
+		return
+	}
+	ps := s.get(p)
+	ps.score -= points
+	log.Debugf("Peer %s lost %d points for %s, score %d", p.Pretty(), points, reason, ps.score)
+	if ps.score > BanThreshold {
+		s.lock.Unlock()
+		return
+	}
+	s.ban(p, time.Now().Add(BanDuration), reason)
+	handler := s.onBan
+	s.lock.Unlock()
+
+	if handler != nil {
+		handler(p)
+	}
+}
+
+// PenalizeError penalizes the peer for a message rejected with the error. Errors
+// which aren't the peer's fault are ignored.
+func (s *Scorer) PenalizeError(p peer.ID, err error) {
+	if err == nil {
+		return
+	}
+	s.Penalize(p, Penalty(err), err.Error())
+}
+
+// Penalty returns the points a peer loses for a message rejected with the error.
+func Penalty(err error) int {
+	switch err {
+	case ob.ErrMalformedMessage:
+		return PenaltyMalformedMessage
+	case ob.ErrInvalidSignature:
+		return PenaltyInvalidSignature
+	case ob.ErrExpiredOrder:
+		return PenaltyExpiredOrder
+	case ob.ErrInvalidOrder:
+		return PenaltyInvalidOrder
+	case ob.ErrUnfundedOrder:
+		return PenaltyUnfundedOrder
+	case ob.ErrTooManyOrders:
+		return PenaltyTooManyOrders
+	default:
+		return 0
+	}
+}
+
+// Banned returns true if we're refusing the peer.
+func (s *Scorer) Banned(p peer.ID) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.banned(p)
+}
+
+// banned checks for a ban and lifts it if it's expired. The caller must hold the lock.
+func (s *Scorer) banned(p peer.ID) bool {
+	b, ok := s.bans[p]
+	if !ok {
+		return false
+	}
+	if b.Until.Before(time.Now()) {
+		s.unban(p)
+		return false
+	}
+	return true
+}
+
+// Bans returns the peers which are currently banned.
+func (s *Scorer) Bans() []Ban {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var bans []Ban
+	for p, b := range s.bans {
+		if s.banned(p) {
+			bans = append(bans, b)
+		}
+	}
+	return bans
+}
+
+// Unban lifts the ban on the peer and resets its score.
+func (s *Scorer) Unban(p peer.ID) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.bans[p]; !ok {
+		return fmt.Errorf("peer %s is not banned", p.Pretty())
+	}
+	s.unban(p)
+	return nil
+}
+
+// ban records the ban and saves it to the datastore. The caller must hold the lock.
+func (s *Scorer) ban(p peer.ID, until time.Time, reason string) {
+	log.Warningf("Banning peer %s until %s: %s", p.Pretty(), until.Format(time.RFC3339), reason)
+	s.bans[p] = Ban{PeerID: p.Pretty(), Until: until, Reason: reason}
+	delete(s.peers, p)
+	if s.db == nil {
+		return
+	}
+	ser, err := proto.Marshal(&pb.BanRecord{
+		PeerID: p.Pretty(),
+		Until:  until.Unix(),
+		Reason: reason,
+	})
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if err := s.db.Put(banKey(p), ser); err != nil {
+		log.Errorf("Error saving ban for peer %s: %s", p.Pretty(), err)
+	}
+}
+
+// unban removes the ban and deletes it from the datastore. The caller must hold the lock.
+func (s *Scorer) unban(p peer.ID) {
+	delete(s.bans, p)
+	delete(s.peers, p)
+	if s.db == nil {
+		return
+	}
+	if err := s.db.Delete(banKey(p)); err != nil && err != ds.ErrNotFound {
+		log.Errorf("Error deleting ban for peer %s: %s", p.Pretty(), err)
+	}
+}
+
+// loadBans reads the bans back from the datastore. Ones that expired while we were
+// offline are dropped.
+func (s *Scorer) loadBans() error {
+	results, err := s.db.Query(query.Query{Prefix: bansPrefix})
+	if err != nil {
+		return err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, e := range entries {
+		ser, ok := e.Value.([]byte)
+		if !ok {
+			continue
+		}
+		rec := new(pb.BanRecord)
+		if err := proto.Unmarshal(ser, rec); err != nil {
+			log.Errorf("Error unmarshalling ban %s: %s", e.Key, err)
+			continue
+		}
+		p, err := peer.IDB58Decode(rec.PeerID)
+		if err != nil {
+			log.Errorf("Error loading ban %s: %s", e.Key, err)
+			continue
+		}
+		s.bans[p] = Ban{PeerID: rec.PeerID, Until: time.Unix(rec.Until, 0), Reason: rec.Reason}
+		s.banned(p)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package score
+
+import (
+	"crypto/rand"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p-crypto"
+	"github.com/libp2p/go-libp2p-peer"
+	"testing"
+	"time"
+)
+
+func newTestPeer(t *testing.T) peer.ID {
+	key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestRateLimit(t *testing.T) {
+	s := NewScorer(nil)
+	p := newTestPeer(t)
+	for i := 0; i < MessageBurst; i++ {
+		if !s.Allow(p) {
+			t.Fatalf("message %d dropped within the burst", i)
+		}
+	}
+	if s.Allow(p) {
+		t.Fatal("message allowed over the burst")
+	}
+	if score := s.Score(p); score != StartingScore-PenaltyRateLimited {
+		t.Errorf("got score %d, want %d", score, StartingScore-PenaltyRateLimited)
+	}
+
+	// Other peers aren't affected
+	if !s.Allow(newTestPeer(t)) {
+		t.Error("message from another peer dropped")
+	}
+
+	// Half a minute later the peer can send half a minute's worth
+	s.lock.Lock()
+	s.peers[p].filled = s.peers[p].filled.Add(-30 * time.Second)
+	s.lock.Unlock()
+	for i := 0; i < MessagesPerMinute/2; i++ {
+		if !s.Allow(p) {
+			t.Fatalf("message %d dropped after the bucket refilled", i)
+		}
+	}
+	if s.Allow(p) {
+		t.Error("message allowed over the refilled amount")
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	s := NewScorer(nil)
+	p := newTestPeer(t)
+	s.Penalize(p, PenaltyInvalidOrder, "test")
+	if score := s.Score(p); score != StartingScore-PenaltyInvalidOrder {
+		t.Fatalf("got score %d, want %d", score, StartingScore-PenaltyInvalidOrder)
+	}
+	if _, ok := s.Scores()[p]; !ok {
+		t.Error("penalized peer missing from scores")
+	}
+
+	// A point comes back every interval, up to the starting score
+	s.lock.Lock()
+	s.peers[p].updated = s.peers[p].updated.Add(-5 * RecoveryInterval)
+	s.lock.Unlock()
+	if score := s.Score(p); score != StartingScore-PenaltyInvalidOrder+5 {
+		t.Errorf("got score %d, want %d", score, StartingScore-PenaltyInvalidOrder+5)
+	}
+	s.lock.Lock()
+	s.peers[p].updated = s.peers[p].updated.Add(-time.Hour)
+	s.lock.Unlock()
+	if score := s.Score(p); score != StartingScore {
+		t.Errorf("got score %d, want %d", score, StartingScore)
+	}
+}
+
+func TestBanPersistence(t *testing.T) {
+	db := ds.NewMapDatastore()
+	s := NewScorer(db)
+	banned := make(chan peer.ID, 1)
+	s.SetBanHandler(func(p peer.ID) { banned <- p })
+
+	p := newTestPeer(t)
+	s.Penalize(p, StartingScore-BanThreshold-1, "almost")
+	if s.Banned(p) {
+		t.Fatal("peer banned above the threshold")
+	}
+	s.Penalize(p, 1, "bad signature")
+	if !s.Banned(p) {
+		t.Fatal("peer not banned at the threshold")
+	}
+	select {
+	case b := <-banned:
+		if b != p {
+			t.Errorf("ban handler called for %s, want %s", b, p)
+		}
+	default:
+		t.Error("ban handler not called")
+	}
+	if s.Allow(p) {
+		t.Error("message from banned peer allowed")
+	}
+
+	// The ban survives a restart
+	s = NewScorer(db)
+	if !s.Banned(p) {
+		t.Fatal("ban lost on restart")
+	}
+	bans := s.Bans()
+	if len(bans) != 1 || bans[0].PeerID != p.Pretty() || bans[0].Reason != "bad signature" {
+		t.Errorf("got bans %+v", bans)
+	}
+
+	// And so does lifting it
+	if err := s.Unban(p); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Unban(p); err == nil {
+		t.Error("unbanning a peer twice succeeded")
+	}
+	s = NewScorer(db)
+	if s.Banned(p) {
+		t.Error("lifted ban came back on restart")
+	}
+	if score := s.Score(p); score != StartingScore {
+		t.Errorf("unbanned peer has score %d, want %d", score, StartingScore)
+	}
+}
+
+func TestBanExpires(t *testing.T) {
+	db := ds.NewMapDatastore()
+	s := NewScorer(db)
+	p := newTestPeer(t)
+	s.lock.Lock()
+	s.ban(p, time.Now().Add(-time.Minute), "test")
+	s.lock.Unlock()
+
+	// Bans which expired while we were offline are dropped on load
+	s = NewScorer(db)
+	if s.Banned(p) || len(s.Bans()) != 0 {
+		t.Error("expired ban still in force")
+	}
+	if _, err := db.Get(banKey(p)); err != ds.ErrNotFound {
+		t.Errorf("expired ban wasn't deleted, got error %v", err)
+	}
+}
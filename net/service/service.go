@@ -2,10 +2,10 @@ package service
 
 import (
 	"context"
+	"github.com/cpacia/atomicswap/net/score"
 	ob "github.com/cpacia/atomicswap/orderbook"
 	"github.com/cpacia/atomicswap/pb"
 	ggio "github.com/gogo/protobuf/io"
-	"github.com/golang/protobuf/ptypes"
 	ctxio "github.com/jbenet/go-context/io"
 	"github.com/libp2p/go-libp2p-host"
 	inet "github.com/libp2p/go-libp2p-net"
@@ -19,17 +19,26 @@ const SwapProtocol protocol.ID = "/SwapProtocol/1.0.0"
 
 var log = logging.MustGetLogger("service")
 
+// SwapMessage wraps a swap protocol message received from a peer. These are
+// passed to the node over the message channel.
+type SwapMessage struct {
+	Peer    peer.ID
+	Message *pb.Message
+}
+
 type WireService struct {
 	msgChan   chan interface{}
 	orderBook *ob.OrderBook
 	peerHost  host.Host
+	scorer    *score.Scorer
 }
 
-func NewWireService(msgChan chan interface{}, orderBook *ob.OrderBook, peerHost host.Host) *WireService {
+func NewWireService(msgChan chan interface{}, orderBook *ob.OrderBook, peerHost host.Host, scorer *score.Scorer) *WireService {
 	ws := &WireService{
 		msgChan:   msgChan,
 		orderBook: orderBook,
 		peerHost:  peerHost,
+		scorer:    scorer,
 	}
 	ws.peerHost.SetStreamHandler(SwapProtocol, ws.handleNewStream)
 	return ws
@@ -79,18 +88,34 @@ func (ws *WireService) handleNewMessage(s inet.Stream) {
 	cr := ctxio.NewReader(context.Background(), s)
 	r := ggio.NewDelimitedReader(cr, inet.MessageSizeMax)
 	mPeer := s.Conn().RemotePeer()
+	if !ws.scorer.Allow(mPeer) {
+		log.Debugf("Dropping stream from %s", mPeer.Pretty())
+		s.Reset()
+		return
+	}
 	pmes := new(pb.Message)
 	if err := r.ReadMsg(pmes); err != nil {
 		s.Reset()
 		if err == io.EOF {
 			log.Debugf("Disconnected from peer %s", mPeer.Pretty())
+		} else {
+			ws.scorer.PenalizeError(mPeer, ob.ErrMalformedMessage)
 		}
 		return
 	}
 	handler := ws.handlerForMsgType(pmes.MessageType)
+	if handler == nil {
+		log.Debugf("Received unknown message type %s from %s", pmes.MessageType, mPeer.Pretty())
+		return
+	}
+	if pmes.Payload == nil {
+		ws.scorer.PenalizeError(mPeer, ob.ErrMalformedMessage)
+		return
+	}
 	rmes, err := handler(mPeer, pmes)
 	if err != nil {
 		log.Error(err)
+		ws.scorer.PenalizeError(mPeer, err)
 		return
 	}
 	if rmes != nil {
@@ -106,33 +131,42 @@ func (ws *WireService) handlerForMsgType(t pb.Message_MessageType) func(peer.ID,
 	switch t {
 	case pb.Message_LimitOrder:
 		return ws.handleLimitOrder
-	case pb.Message_GetOrderBook:
-		return ws.handleGetOrderBook
+	case pb.Message_SyncDigest:
+		return ws.handleSyncDigest
+	case pb.Message_GetOrders:
+		return ws.handleGetOrders
+	case pb.Message_Orders:
+		return ws.handleOrders
+	case pb.Message_OrderUpdate:
+		return ws.handleOrderUpdate
+	case pb.Message_MarketOrder, pb.Message_SwapAccept, pb.Message_SwapReject,
+		pb.Message_InitiateSwap, pb.Message_ParticipateSwap, pb.Message_RedeemSwap:
+		return ws.handleSwapMessage
 	default:
 		return nil
 	}
 }
 
 func (ws *WireService) handleLimitOrder(p peer.ID, msg *pb.Message) (*pb.Message, error) {
-	ws.orderBook.ProcessNewLimitOrder(msg.Payload.Value, false)
-	return nil, nil
+	u, err := ws.orderBook.ProcessNewLimitOrder(msg.Payload.Value, false)
+	ws.notify(u)
+	return nil, err
 }
 
-func (ws *WireService) handleGetOrderBook(p peer.ID, msg *pb.Message) (*pb.Message, error) {
-	for _, o := range ws.orderBook.OpenOrders() {
-		so, err := o.SignedLimitOrder()
-		if err != nil {
-			continue
-		}
-		payload, err := ptypes.MarshalAny(so)
-		if err != nil {
-			continue
-		}
-		m := &pb.Message{
-			MessageType: pb.Message_LimitOrder,
-			Payload:     payload,
-		}
-		ws.SendMessage(p, m)
+func (ws *WireService) handleOrderUpdate(p peer.ID, msg *pb.Message) (*pb.Message, error) {
+	u, err := ws.orderBook.ProcessOrderUpdate(msg.Payload.Value, false)
+	ws.notify(u)
+	return nil, err
+}
+
+// notify passes a change to the order book on to the node.
+func (ws *WireService) notify(u *ob.BookUpdate) {
+	if u != nil {
+		ws.msgChan <- *u
 	}
+}
+
+func (ws *WireService) handleSwapMessage(p peer.ID, msg *pb.Message) (*pb.Message, error) {
+	ws.msgChan <- SwapMessage{Peer: p, Message: msg}
 	return nil, nil
 }
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"errors"
+	ob "github.com/cpacia/atomicswap/orderbook"
+	"github.com/cpacia/atomicswap/pb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/libp2p/go-libp2p-peer"
+)
+
+// SyncBatchSize is the most orders we'll request or send in a single message.
+const SyncBatchSize = 100
+
+// SyncOrderBook reconciles our order book with the peer's. We send a digest of our
+// book, the peer replies with the orders it has in the buckets that differ, then we
+// request the orders we're missing and push the ones it's missing. This way the
+// bandwidth used is proportional to the difference between the books rather than
+// their size.
+func (ws *WireService) SyncOrderBook(p peer.ID) error {
+	payload, err := ptypes.MarshalAny(ws.orderBook.Digest())
+	if err != nil {
+		return err
+	}
+	resp, err := ws.SendRequest(p, &pb.Message{
+		MessageType: pb.Message_SyncDigest,
+		Payload:     payload,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.MessageType != pb.Message_SyncInventory || resp.Payload == nil {
+		return errors.New("invalid response to sync digest")
+	}
+	inv := new(pb.OrderBookInventory)
+	if err := proto.Unmarshal(resp.Payload.Value, inv); err != nil {
+		return err
+	}
+	want, push := ws.orderBook.Reconcile(inv)
+	log.Debugf("Syncing order book with %s: requesting %d orders, sending %d", p.Pretty(), len(want), len(push))
+
+	for len(want) > 0 {
+		n := len(want)
+		if n > SyncBatchSize {
+			n = SyncBatchSize
+		}
+		payload, err := ptypes.MarshalAny(&pb.GetOrders{OrderIDs: want[:n]})
+		if err != nil {
+			return err
+		}
+		want = want[n:]
+		resp, err := ws.SendRequest(p, &pb.Message{
+			MessageType: pb.Message_GetOrders,
+			Payload:     payload,
+		})
+		if err != nil {
+			return err
+		}
+		if resp.MessageType != pb.Message_Orders || resp.Payload == nil {
+			return errors.New("invalid response to get orders")
+		}
+		if err := ws.processOrders(p, resp.Payload.Value); err != nil {
+			return err
+		}
+	}
+
+	for len(push) > 0 {
+		n := len(push)
+		if n > SyncBatchSize {
+			n = SyncBatchSize
+		}
+		payload, err := ptypes.MarshalAny(ws.orderBook.SignedOrders(push[:n]))
+		if err != nil {
+			return err
+		}
+		push = push[n:]
+		if err := ws.SendMessage(p, &pb.Message{
+			MessageType: pb.Message_Orders,
+			Payload:     payload,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processOrders adds the orders then applies the updates and closes from an Orders
+// message. The peer is penalized for each one the order book rejects, except for
+// going over the per peer limit or orders which have since expired or lost their
+// UTXO. The orders it relays may have been made by others and were fine when it
+// got them.
+func (ws *WireService) processOrders(p peer.ID, ser []byte) error {
+	orders := new(pb.Orders)
+	if err := proto.Unmarshal(ser, orders); err != nil {
+		return ob.ErrMalformedMessage
+	}
+	for _, o := range orders.Orders {
+		so, err := proto.Marshal(o)
+		if err != nil {
+			continue
+		}
+		u, err := ws.orderBook.ProcessNewLimitOrder(so, false)
+		ws.notify(u)
+		switch err {
+		case ob.ErrTooManyOrders, ob.ErrExpiredOrder, ob.ErrUnfundedOrder:
+			continue
+		}
+		ws.scorer.PenalizeError(p, err)
+	}
+	for _, u := range orders.Updates {
+		su, err := proto.Marshal(u)
+		if err != nil {
+			continue
+		}
+		u, err := ws.orderBook.ProcessOrderUpdate(su, false)
+		ws.notify(u)
+		ws.scorer.PenalizeError(p, err)
+	}
+	for _, c := range orders.Closes {
+		sc, err := proto.Marshal(c)
+		if err != nil {
+			continue
+		}
+		u, err := ws.orderBook.ProcessCloseOrder(sc, false, p)
+		ws.notify(u)
+		ws.scorer.PenalizeError(p, err)
+	}
+	return nil
+}
+
+func (ws *WireService) handleSyncDigest(p peer.ID, msg *pb.Message) (*pb.Message, error) {
+	digest := new(pb.OrderBookDigest)
+	if err := proto.Unmarshal(msg.Payload.Value, digest); err != nil {
+		return nil, ob.ErrMalformedMessage
+	}
+	payload, err := ptypes.MarshalAny(ws.orderBook.Inventory(digest))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Message{
+		MessageType: pb.Message_SyncInventory,
+		Payload:     payload,
+	}, nil
+}
+
+func (ws *WireService) handleGetOrders(p peer.ID, msg *pb.Message) (*pb.Message, error) {
+	req := new(pb.GetOrders)
+	if err := proto.Unmarshal(msg.Payload.Value, req); err != nil {
+		return nil, ob.ErrMalformedMessage
+	}
+	ids := req.OrderIDs
+	if len(ids) > SyncBatchSize {
+		ids = ids[:SyncBatchSize]
+	}
+	payload, err := ptypes.MarshalAny(ws.orderBook.SignedOrders(ids))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Message{
+		MessageType: pb.Message_Orders,
+		Payload:     payload,
+	}, nil
+}
+
+func (ws *WireService) handleOrders(p peer.ID, msg *pb.Message) (*pb.Message, error) {
+	return nil, ws.processOrders(p, msg.Payload.Value)
+}
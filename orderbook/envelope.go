@@ -0,0 +1,108 @@
+package orderbook
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/cpacia/atomicswap/pb"
+	"github.com/cpacia/atomicswap/swap"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/libp2p/go-libp2p-crypto"
+	"github.com/libp2p/go-libp2p-peer"
+	"time"
+)
+
+// SignatureVersion is the version of the signing envelope we produce and accept.
+const SignatureVersion = 1
+
+// MaxSignatureClockSkew is how far in the future a signature's timestamp may be.
+const MaxSignatureClockSkew = time.Minute * 10
+
+// signatureDomain is prepended to everything we sign with our identity key so the
+// signatures can't be confused with ones made by other protocols using the same key.
+const signatureDomain = "atomicswap signed message"
+
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// signingBytes serializes the envelope that's actually signed. It commits to the
+// domain, version, message type, network and timestamp as well as the payload.
+func signingBytes(t pb.Message_MessageType, header *pb.SignatureHeader, payload []byte) ([]byte, error) {
+	ts, err := ptypes.Timestamp(header.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writeBytes := func(b []byte) {
+		binary.Write(&buf, binary.BigEndian, uint32(len(b)))
+		buf.Write(b)
+	}
+	writeBytes([]byte(signatureDomain))
+	binary.Write(&buf, binary.BigEndian, header.Version)
+	binary.Write(&buf, binary.BigEndian, int32(t))
+	writeBytes([]byte(header.Network))
+	binary.Write(&buf, binary.BigEndian, ts.UnixNano())
+	writeBytes(payload)
+	return buf.Bytes(), nil
+}
+
+// Sign signs the payload of a message of the given type for the network. It returns
+// the header which has to be sent along with the signature.
+func Sign(key crypto.PrivKey, t pb.Message_MessageType, network swap.Network, payload []byte) (*pb.SignatureHeader, []byte, error) {
+	ts, err := ptypes.TimestampProto(time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	header := &pb.SignatureHeader{
+		Version:   SignatureVersion,
+		Network:   network.String(),
+		Timestamp: ts,
+	}
+	ser, err := signingBytes(t, header, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	sig, err := key.Sign(ser)
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, sig, nil
+}
+
+// Verify checks the signature was made by the peer over the payload for a message
+// of the given type on our network.
+func Verify(peerID string, t pb.Message_MessageType, network swap.Network, header *pb.SignatureHeader, payload, sig []byte) error {
+	if header == nil {
+		return errors.New("missing signature header")
+	}
+	if header.Version != SignatureVersion {
+		return fmt.Errorf("unsupported signature version %d", header.Version)
+	}
+	if header.Network != network.String() {
+		return fmt.Errorf("signature is for network %q", header.Network)
+	}
+	ts, err := ptypes.Timestamp(header.Timestamp)
+	if err != nil {
+		return err
+	}
+	if ts.After(time.Now().Add(MaxSignatureClockSkew)) {
+		return errors.New("signature timestamp is in the future")
+	}
+	pid, err := peer.IDB58Decode(peerID)
+	if err != nil {
+		return err
+	}
+	pubKey, err := pid.ExtractPublicKey()
+	if err != nil {
+		return err
+	}
+	ser, err := signingBytes(t, header, payload)
+	if err != nil {
+		return err
+	}
+	valid, err := pubKey.Verify(ser, sig)
+	if !valid || err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package orderbook
+
+import (
+	"errors"
+)
+
+// Limits keep the order book from growing without bound. Peer IDs cost nothing to
+// make so a single attacker could otherwise fill our memory with orders.
+type Limits struct {
+	// MaxOrders is the most orders we'll hold. Once the book is full a new order
+	// replaces the one farthest from the market if it's closer. Zero means no limit.
+	MaxOrders int `json:"maxOrders"`
+	// MaxOrdersPerPeer is the most open orders we'll hold from a single peer. Zero
+	// means no limit.
+	MaxOrdersPerPeer int `json:"maxOrdersPerPeer"`
+	// MinQuantity is the smallest order, in satoshis, we'll accept.
+	MinQuantity uint64 `json:"minQuantity"`
+}
+
+// DefaultLimits are the limits a new order book starts with.
+var DefaultLimits = Limits{
+	MaxOrders:        10000,
+	MaxOrdersPerPeer: 50,
+	MinQuantity:      10000,
+}
+
+var (
+	ErrOrderTooSmall = errors.New("order quantity is below the minimum")
+	ErrTooManyOrders = errors.New("peer has too many open orders")
+	ErrBookFull      = errors.New("order book is full")
+)
+
+// BookStats reports how full the book is against its limits.
+type BookStats struct {
+	Limits   Limits `json:"limits"`
+	Orders   int    `json:"orders"`
+	Bids     int    `json:"bids"`
+	Asks     int    `json:"asks"`
+	Peers    int    `json:"peers"`
+	MyOrders int    `json:"myOrders"`
+	Evicted  uint64 `json:"evicted"`
+	Rejected uint64 `json:"rejected"`
+}
+
+// SetLimits changes the order book limits. Orders already in the book are kept
+// even if they're over the new limits.
+func (ob *OrderBook) SetLimits(limits Limits) {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	ob.limits = limits
+}
+
+// Limits returns the order book limits.
+func (ob *OrderBook) Limits() Limits {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	return ob.limits
+}
+
+// Stats returns the size of the book and how many orders the limits turned away.
+func (ob *OrderBook) Stats() BookStats {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	return BookStats{
+		Limits:   ob.limits,
+		Orders:   len(ob.orders),
+		Bids:     len(ob.bids.entries),
+		Asks:     len(ob.asks.entries),
+		Peers:    len(ob.peerOrders),
+		MyOrders: len(ob.myOrders),
+		Evicted:  ob.evicted,
+		Rejected: ob.rejected,
+	}
+}
+
+// checkLimits returns an error if the order can't be added without going over our
+// limits. Our own orders only need to meet the minimum quantity. The caller must
+// hold the lock.
+func (ob *OrderBook) checkLimits(lo LimitOrder, myOrder bool) error {
+	if lo.Quantity < ob.limits.MinQuantity {
+		return ErrOrderTooSmall
+	}
+	if myOrder {
+		return nil
+	}
+	if ob.limits.MaxOrdersPerPeer > 0 && ob.peerOrders[lo.PeerID] >= ob.limits.MaxOrdersPerPeer {
+		return ErrTooManyOrders
+	}
+	if ob.limits.MaxOrders > 0 && len(ob.orders) >= ob.limits.MaxOrders {
+		e, ok := ob.evictionCandidate()
+		if !ok || ob.distance(lo) >= ob.distance(e.order) {
+			return ErrBookFull
+		}
+	}
+	return nil
+}
+
+// makeRoom evicts the order farthest from the market if the book is full. The
+// caller must hold the lock.
+func (ob *OrderBook) makeRoom() {
+	if ob.limits.MaxOrders <= 0 || len(ob.orders) < ob.limits.MaxOrders {
+		return
+	}
+	e, ok := ob.evictionCandidate()
+	if !ok {
+		return
+	}
+	log.Infof("Order book full, evicting order %s", e.id)
+	ob.removeFromBook(e.id)
+	ob.evicted++
+}
+
+// evictionCandidate returns the order which is the worst deal for a taker. That's
+// the order at the back of whichever side is farther from the market; at the same
+// price the newest order goes first. Our own orders are never evicted. The caller
+// must hold the lock.
+func (ob *OrderBook) evictionCandidate() (bookEntry, bool) {
+	bid, haveBid := ob.back(Bid)
+	ask, haveAsk := ob.back(Ask)
+	switch {
+	case haveBid && haveAsk:
+		if ob.distance(bid.order) > ob.distance(ask.order) {
+			return bid, true
+		}
+		return ask, true
+	case haveBid:
+		return bid, true
+	case haveAsk:
+		return ask, true
+	default:
+		return bookEntry{}, false
+	}
+}
+
+// back returns the lowest priority order on the side that isn't ours. The caller
+// must hold the lock.
+func (ob *OrderBook) back(s Side) (bookEntry, bool) {
+	entries := ob.side(s).entries
+	for i := len(entries) - 1; i >= 0; i-- {
+		if _, mine := ob.myOrders[entries[i].id]; !mine {
+			return entries[i], true
+		}
+	}
+	return bookEntry{}, false
+}
+
+// marketPrice returns the midpoint of the best bid and ask. If one side is empty it's
+// the best price on the other side. The caller must hold the lock.
+func (ob *OrderBook) marketPrice() uint64 {
+	var bid, ask uint64
+	if len(ob.bids.entries) > 0 {
+		bid = ob.bids.entries[0].order.Price
+	}
+	if len(ob.asks.entries) > 0 {
+		ask = ob.asks.entries[0].order.Price
+	}
+	switch {
+	case bid > 0 && ask > 0:
+		return (bid + ask) / 2
+	case bid > 0:
+		return bid
+	default:
+		return ask
+	}
+}
+
+// distance returns how far the order's price is from the market price, as a fraction
+// of the market price, in the direction that's worse for a taker. Bids below the market
+// and asks above it are positive. The caller must hold the lock.
+func (ob *OrderBook) distance(lo LimitOrder) float64 {
+	market := ob.marketPrice()
+	if market == 0 {
+		return 0
+	}
+	d := float64(lo.Price) - float64(market)
+	if lo.BuyBTC {
+		d = -d
+	}
+	return d / float64(market)
+}
@@ -0,0 +1,272 @@
+package orderbook
+
+import (
+	"sort"
+)
+
+// Side is the side of the book an order rests on. Bids are BuyBTC orders and asks
+// are orders selling BTC. Prices on both sides are BCH satoshis per BTC.
+type Side int
+
+const (
+	Bid Side = iota
+	Ask
+)
+
+func (s Side) String() string {
+	switch s {
+	case Bid:
+		return "bid"
+	case Ask:
+		return "ask"
+	default:
+		return "unknown"
+	}
+}
+
+// Opposite returns the side a taker on this side would trade against.
+func (s Side) Opposite() Side {
+	if s == Bid {
+		return Ask
+	}
+	return Bid
+}
+
+// SideOf returns the side of the book the order rests on.
+func SideOf(lo LimitOrder) Side {
+	if lo.BuyBTC {
+		return Bid
+	}
+	return Ask
+}
+
+// PriceLevel is the aggregated quantity resting at a single price.
+type PriceLevel struct {
+	Price    uint64 `json:"price"`
+	Quantity uint64 `json:"quantity"`
+	Orders   int    `json:"orders"`
+}
+
+// Fill is a resting order a taker would hit and how much of it they would take.
+type Fill struct {
+	OrderID  string     `json:"orderID"`
+	Order    LimitOrder `json:"order"`
+	Price    uint64     `json:"price"`
+	Quantity uint64     `json:"quantity"`
+	Partial  bool       `json:"partial"`
+}
+
+type bookEntry struct {
+	id    string
+	order LimitOrder
+}
+
+// bookSide holds one side of the book sorted in price-time priority. The best
+// price is at the front; orders at the same price are in the order we received them.
+type bookSide struct {
+	bids    bool
+	entries []bookEntry
+}
+
+func newBookSide(bids bool) *bookSide {
+	return &bookSide{bids: bids}
+}
+
+// before returns true if a has priority over b.
+func (bs *bookSide) before(a, b bookEntry) bool {
+	if a.order.Price != b.order.Price {
+		if bs.bids {
+			return a.order.Price > b.order.Price
+		}
+		return a.order.Price < b.order.Price
+	}
+	if !a.order.received.Equal(b.order.received) {
+		return a.order.received.Before(b.order.received)
+	}
+	return a.id < b.id
+}
+
+func (bs *bookSide) insert(id string, lo LimitOrder) {
+	e := bookEntry{id, lo}
+	i := sort.Search(len(bs.entries), func(i int) bool {
+		return bs.before(e, bs.entries[i])
+	})
+	bs.entries = append(bs.entries, bookEntry{})
+	copy(bs.entries[i+1:], bs.entries[i:])
+	bs.entries[i] = e
+}
+
+// update replaces the order without changing its place in the queue.
+func (bs *bookSide) update(id string, lo LimitOrder) {
+	for i, e := range bs.entries {
+		if e.id == id {
+			bs.entries[i].order = lo
+			return
+		}
+	}
+}
+
+func (bs *bookSide) remove(id string) {
+	for i, e := range bs.entries {
+		if e.id == id {
+			bs.entries = append(bs.entries[:i], bs.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+func (bs *bookSide) depth() []PriceLevel {
+	var levels []PriceLevel
+	for _, e := range bs.entries {
+		if len(levels) > 0 && levels[len(levels)-1].Price == e.order.Price {
+			levels[len(levels)-1].Quantity += e.order.Remaining
+			levels[len(levels)-1].Orders++
+			continue
+		}
+		levels = append(levels, PriceLevel{e.order.Price, e.order.Remaining, 1})
+	}
+	return levels
+}
+
+func (ob *OrderBook) side(s Side) *bookSide {
+	if s == Bid {
+		return ob.bids
+	}
+	return ob.asks
+}
+
+// addToBook adds the order to the map of open orders and the sorted side of the book.
+// The caller must hold the lock.
+func (ob *OrderBook) addToBook(id string, lo LimitOrder) {
+	ob.orders[id] = lo
+	ob.peerOrders[lo.PeerID]++
+	if u, ok := utxoOf(lo); ok {
+		ob.utxos[u] = id
+	}
+	ob.side(SideOf(lo)).insert(id, lo)
+}
+
+// updateInBook replaces an order after its remaining quantity changed. It keeps its
+// time priority. The caller must hold the lock.
+func (ob *OrderBook) updateInBook(id string, lo LimitOrder) {
+	ob.orders[id] = lo
+	ob.side(SideOf(lo)).update(id, lo)
+}
+
+// removeFromBook removes the order from the open orders, our own orders, its side of
+// the book and the datastore. The caller must hold the lock.
+func (ob *OrderBook) removeFromBook(id string) {
+	lo, ok := ob.orders[id]
+	if !ok {
+		return
+	}
+	delete(ob.orders, id)
+	delete(ob.myOrders, id)
+	ob.peerOrders[lo.PeerID]--
+	if ob.peerOrders[lo.PeerID] <= 0 {
+		delete(ob.peerOrders, lo.PeerID)
+	}
+	if u, ok := utxoOf(lo); ok && ob.utxos[u] == id {
+		delete(ob.utxos, u)
+	}
+	ob.side(SideOf(lo)).remove(id)
+	ob.deleteOrder(id)
+}
+
+// Bids returns the BuyBTC orders, best price first.
+func (ob *OrderBook) Bids() []LimitOrder {
+	return ob.sorted(Bid)
+}
+
+// Asks returns the orders selling BTC, best price first.
+func (ob *OrderBook) Asks() []LimitOrder {
+	return ob.sorted(Ask)
+}
+
+func (ob *OrderBook) sorted(s Side) []LimitOrder {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	var orders []LimitOrder
+	for _, e := range ob.side(s).entries {
+		orders = append(orders, e.order)
+	}
+	return orders
+}
+
+// BestBid returns the highest priced BuyBTC order. The bool is false if there are no bids.
+func (ob *OrderBook) BestBid() (LimitOrder, bool) {
+	return ob.best(Bid)
+}
+
+// BestAsk returns the lowest priced order selling BTC. The bool is false if there are no asks.
+func (ob *OrderBook) BestAsk() (LimitOrder, bool) {
+	return ob.best(Ask)
+}
+
+func (ob *OrderBook) best(s Side) (LimitOrder, bool) {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	entries := ob.side(s).entries
+	if len(entries) == 0 {
+		return LimitOrder{}, false
+	}
+	return entries[0].order, true
+}
+
+// Depth returns the quantity resting at each price level on both sides of the book,
+// best price first.
+func (ob *OrderBook) Depth() (bids []PriceLevel, asks []PriceLevel) {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	return ob.bids.depth(), ob.asks.depth()
+}
+
+// Match returns the resting orders, in priority order, that a taker on the given side
+// would hit to fill the quantity. A Bid taker is buying BTC and hits the asks; an Ask
+// taker is selling BTC and hits the bids. Fills may take only part of an order's
+// remaining quantity but never less than its minimum fill; orders which would need a
+// smaller fill are skipped, as are our own orders. If the book can't fill the whole
+// quantity the fills cover as much as it can.
+func (ob *OrderBook) Match(quantity uint64, side Side) []Fill {
+	return ob.MatchLimit(quantity, side, 0)
+}
+
+// MatchLimit is Match for a taker with a limit price. Only orders at the limit price or
+// better are hit: asks at or below it for a Bid taker and bids at or above it for an Ask
+// taker. A zero limit matches at any price.
+func (ob *OrderBook) MatchLimit(quantity uint64, side Side, limit uint64) []Fill {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	var fills []Fill
+	for _, e := range ob.side(side.Opposite()).entries {
+		if quantity == 0 {
+			break
+		}
+		// The side is sorted best price first so nothing after this crosses either
+		if limit > 0 && ((side == Bid && e.order.Price > limit) || (side == Ask && e.order.Price < limit)) {
+			break
+		}
+		if _, mine := ob.myOrders[e.id]; mine {
+			continue
+		}
+		if e.order.Remaining == 0 {
+			continue
+		}
+		q := e.order.Remaining
+		if q > quantity {
+			q = quantity
+		}
+		if q < e.order.MinFill() {
+			continue
+		}
+		fills = append(fills, Fill{
+			OrderID:  e.id,
+			Order:    e.order,
+			Price:    e.order.Price,
+			Quantity: q,
+			Partial:  q < e.order.Remaining,
+		})
+		quantity -= q
+	}
+	return fills
+}
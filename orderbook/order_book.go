@@ -2,30 +2,87 @@ package orderbook
 
 import (
 	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/cpacia/atomicswap/chain"
 	"github.com/cpacia/atomicswap/pb"
+	"github.com/cpacia/atomicswap/swap"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
 	"github.com/libp2p/go-libp2p-peer"
 	"github.com/multiformats/go-multihash"
 	"github.com/op/go-logging"
 	"sync"
 	"time"
-	"errors"
 )
 
 const GarbageCollectionInterval = time.Minute
 
 var log = logging.MustGetLogger("orderbook")
 
+// Errors returned when we reject a message from the network. Callers use them to
+// score the peer which sent it.
+var (
+	ErrMalformedMessage = errors.New("malformed message")
+	ErrExpiredOrder     = errors.New("order is expired")
+	ErrInvalidOrder     = errors.New("invalid order")
+	ErrUnfundedOrder    = errors.New("order is not backed by a valid utxo")
+	ErrOrderNotFound    = errors.New("order not found")
+)
+
+// UpdateType is the kind of change a message made to the book.
+type UpdateType int
+
+const (
+	OrderAdded UpdateType = iota
+	OrderClosed
+	OrderFilled
+	OrderAmended
+)
+
+func (t UpdateType) String() string {
+	switch t {
+	case OrderAdded:
+		return "added"
+	case OrderClosed:
+		return "closed"
+	case OrderFilled:
+		return "filled"
+	case OrderAmended:
+		return "amended"
+	default:
+		return "unknown"
+	}
+}
+
+// BookUpdate describes a change to the book. The order is as it was after the change.
+type BookUpdate struct {
+	Type  UpdateType
+	Order LimitOrder
+}
+
 type LimitOrder struct {
 	*pb.LimitOrder
 	signature []byte
+	header    *pb.SignatureHeader
 	OrderID   string
+
+	// Remaining is the quantity still available to take. It starts out as the order
+	// quantity and is lowered by signed updates from the maker.
+	Remaining uint64
+	// Sequence is the sequence number of the last update we applied.
+	Sequence uint64
+	update   []byte
+
+	// received is when we first saw the order and sets its time priority in the book.
+	received time.Time
 }
 
 func (lo *LimitOrder) ID() (*cid.Cid, error) {
-	ser, err := proto.Marshal(lo)
+	ser, err := proto.Marshal(lo.LimitOrder)
 	if err != nil {
 		return nil, err
 	}
@@ -49,36 +106,149 @@ func (lo *LimitOrder) SignedLimitOrder() (*pb.SignedLimitOrder, error) {
 	signed := &pb.SignedLimitOrder{
 		SerializedLimitOrder: ser,
 		Signature:            lo.signature,
+		Header:               lo.header,
+	}
+	return signed, nil
+}
+
+// SignedUpdate returns the latest signed update for the order, or nil if it
+// hasn't been updated since it was published.
+func (lo *LimitOrder) SignedUpdate() (*pb.SignedOrderUpdate, error) {
+	if lo.update == nil {
+		return nil, nil
+	}
+	signed := new(pb.SignedOrderUpdate)
+	if err := proto.Unmarshal(lo.update, signed); err != nil {
+		return nil, err
 	}
 	return signed, nil
 }
 
+// MinFill returns the smallest quantity a taker may fill. Once less than the
+// order's minimum quantity remains, the rest has to be taken all at once.
+func (lo *LimitOrder) MinFill() uint64 {
+	if lo.MinQuantity > lo.Remaining {
+		return lo.Remaining
+	}
+	return lo.MinQuantity
+}
+
+// FillError is returned when a fill quantity isn't allowed by the order.
+type FillError string
+
+func (e FillError) Error() string {
+	return string(e)
+}
+
+// CheckFill returns a FillError if a taker can't fill the given quantity of the order.
+func (lo *LimitOrder) CheckFill(quantity uint64) error {
+	if quantity == 0 {
+		return FillError("fill quantity must be greater than zero")
+	}
+	if quantity > lo.Remaining {
+		return FillError(fmt.Sprintf("fill quantity %d exceeds remaining quantity %d", quantity, lo.Remaining))
+	}
+	if quantity < lo.MinFill() {
+		return FillError(fmt.Sprintf("fill quantity %d is below the minimum fill of %d", quantity, lo.MinFill()))
+	}
+	return nil
+}
+
 type OrderBook struct {
-	orders map[string]LimitOrder
-	myOrders map[string]LimitOrder
-	lock   sync.Mutex
+	orders          map[string]LimitOrder
+	myOrders        map[string]LimitOrder
+	peerOrders      map[string]int
+	backends        map[swap.Coin]chain.ChainBackend
+	bids            *bookSide
+	asks            *bookSide
+	tombstones      map[string]tombstone
+	unverified      map[string][]unverifiedClose
+	unverifiedFrom  map[peer.ID]int
+	unverifiedCount int
+	// utxos maps the UTXO backing each open order to the order's ID so one
+	// UTXO can't be used to fund more than one order.
+	utxos map[orderUTXO]string
+	// unsigned holds our own orders loaded from the datastore whose signatures
+	// don't check out until ResignOrders signs them again.
+	unsigned  map[string]LimitOrder
+	limits    Limits
+	evicted   uint64
+	rejected  uint64
+	network   swap.Network
+	db        ds.Datastore
+	onPenalty func(p peer.ID, err error)
+	lock      sync.Mutex
 }
 
-func NewOrderBook() *OrderBook {
-	ob := &OrderBook{make(map[string]LimitOrder), make(map[string]LimitOrder),sync.Mutex{}}
+// NewOrderBook returns an order book backed by the datastore. Orders saved by
+// a previous run are loaded back in. Only orders signed for the network are accepted.
+func NewOrderBook(db ds.Datastore, network swap.Network) *OrderBook {
+	ob := &OrderBook{
+		orders:         make(map[string]LimitOrder),
+		myOrders:       make(map[string]LimitOrder),
+		peerOrders:     make(map[string]int),
+		backends:       make(map[swap.Coin]chain.ChainBackend),
+		bids:           newBookSide(true),
+		asks:           newBookSide(false),
+		tombstones:     make(map[string]tombstone),
+		unverified:     make(map[string][]unverifiedClose),
+		unverifiedFrom: make(map[peer.ID]int),
+		utxos:          make(map[orderUTXO]string),
+		unsigned:       make(map[string]LimitOrder),
+		limits:         DefaultLimits,
+		network:        network,
+		db:             db,
+	}
+	if db != nil {
+		if err := ob.loadTombstones(); err != nil {
+			log.Errorf("Error loading tombstones: %s", err)
+		}
+		if err := ob.loadOrders(); err != nil {
+			log.Errorf("Error loading order book: %s", err)
+		}
+	}
 	go ob.removeExpired()
 	return ob
 }
 
+// SetChainBackend sets the backend used to look up the UTXOs backing orders for the coin.
+func (ob *OrderBook) SetChainBackend(coin swap.Coin, backend chain.ChainBackend) {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	ob.backends[coin] = backend
+}
+
+// SetPenaltyHandler sets a function to be called with the peer to blame when a
+// message it sent us turns out to be invalid once we can check it.
+func (ob *OrderBook) SetPenaltyHandler(handler func(p peer.ID, err error)) {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	ob.onPenalty = handler
+}
+
+// penalize passes the peer to the penalty handler. The caller must hold the lock.
+func (ob *OrderBook) penalize(p peer.ID, err error) {
+	if ob.onPenalty == nil || p == "" {
+		return
+	}
+	go ob.onPenalty(p, err)
+}
+
 func (ob *OrderBook) removeExpired() {
 	ticker := time.NewTicker(GarbageCollectionInterval)
 	for range ticker.C {
 		ob.lock.Lock()
-		defer ob.lock.Unlock()
 		for oid, order := range ob.orders {
 			t, err := ptypes.Timestamp(order.Expiry)
 			if err != nil {
 				continue
 			}
 			if t.Before(time.Now()) {
-				delete(ob.orders, oid)
+				ob.removeFromBook(oid)
 			}
 		}
+		ob.removeExpiredTombstones()
+		ob.lock.Unlock()
 	}
 }
 
@@ -92,91 +262,290 @@ func (ob *OrderBook) OpenOrders() []LimitOrder {
 	return orders
 }
 
+// MyOrders returns the open orders we made.
+func (ob *OrderBook) MyOrders() []LimitOrder {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	var orders []LimitOrder
+	for id, o := range ob.myOrders {
+		o.OrderID = id
+		orders = append(orders, o)
+	}
+	return orders
+}
+
 func (ob *OrderBook) GetOrder(orderID string) (LimitOrder, bool, error) {
 	ob.lock.Lock()
 	defer ob.lock.Unlock()
+	order, mine := ob.myOrders[orderID]
+	if mine {
+		return order, true, nil
+	}
 	order, ok := ob.orders[orderID]
-	var mine bool
 	if !ok {
-		order, ok = ob.myOrders[orderID]
-		if !ok {
-			return LimitOrder{}, false, errors.New("not found")
-		}
-		mine = true
+		return LimitOrder{}, false, ErrOrderNotFound
 	}
-	return order, mine, nil
+	return order, false, nil
 }
 
-// Maybe add a new order to our order book
-func (ob *OrderBook) ProcessNewLimitOrder(serializedOrder []byte, myOrder bool) {
-	ob.lock.Lock()
-	defer ob.lock.Unlock()
+// Maybe add a new order to our order book. An error is returned if the order was
+// rejected; orders we already have or which were closed are silently ignored. The
+// update is nil unless the order was added.
+//
+// Looking up the order's UTXO goes out to the chain backend so the lock is released
+// while we do it and the book is checked again before the order is added.
+func (ob *OrderBook) ProcessNewLimitOrder(serializedOrder []byte, myOrder bool) (*BookUpdate, error) {
 	// Deserialized signed order
 	signed := new(pb.SignedLimitOrder)
 	err := proto.Unmarshal(serializedOrder, signed)
 	if err != nil {
 		log.Error(err)
-		return
+		return nil, ErrMalformedMessage
 	}
 	// Deserialize nested limit order
 	limitpb := new(pb.LimitOrder)
 	err = proto.Unmarshal(signed.SerializedLimitOrder, limitpb)
 	if err != nil {
 		log.Error(err)
-		return
+		return nil, ErrMalformedMessage
 	}
 	// Calculate the ID
-	lo := LimitOrder{LimitOrder: limitpb, signature: signed.Signature}
+	lo := LimitOrder{LimitOrder: limitpb, signature: signed.Signature, header: signed.Header, Remaining: limitpb.Quantity, received: time.Now()}
 	id, err := lo.ID()
 	if err != nil {
 		log.Error(err)
-		return
+		return nil, ErrMalformedMessage
 	}
 	// We already have this order, return
-	if _, ok := ob.orders[id.String()]; ok {
-		return
+	ob.lock.Lock()
+	_, ok := ob.orders[id.String()]
+	ob.lock.Unlock()
+	if ok {
+		return nil, nil
 	}
 
 	// Validate signature
-	pid, err := peer.IDB58Decode(lo.PeerID)
-	if err != nil {
-		log.Error(err)
-		return
-	}
-	pubKey, err := pid.ExtractPublicKey()
+	err = Verify(lo.PeerID, pb.Message_LimitOrder, ob.network, signed.Header, signed.SerializedLimitOrder, signed.Signature)
 	if err != nil {
-		log.Error(err)
-		return
-	}
-	valid, err := pubKey.Verify(signed.SerializedLimitOrder, signed.Signature)
-	if !valid || err != nil {
-		log.Error("invalid signature on limit order")
-		return
+		log.Errorf("Invalid signature on limit order: %s", err)
+		return nil, ErrInvalidSignature
 	}
 
 	// Check expiration
 	expirationDate, err := ptypes.Timestamp(lo.Expiry)
 	if err != nil {
 		log.Error(err)
-		return
+		return nil, ErrMalformedMessage
 	}
 	if expirationDate.Before(time.Now()) {
 		log.Error("received expired order")
-		return
+		return nil, ErrExpiredOrder
+	}
+
+	if lo.MinQuantity > lo.Quantity {
+		log.Error("received order with minimum quantity above its quantity")
+		return nil, ErrInvalidOrder
+	}
+
+	// Check it fits in the book before we go to the trouble of looking up the utxo
+	ob.lock.Lock()
+	ignore, err := ob.admit(id.String(), lo, expirationDate, myOrder)
+	ob.lock.Unlock()
+	if ignore || err != nil {
+		return nil, err
 	}
 
-	// TODO: validate signed UTXO
+	// Make sure the order is funded. Our own orders we trust.
+	if !myOrder {
+		if err := ob.validateUTXO(lo); err != nil {
+			log.Errorf("Rejected order %s: %s", id.String(), err)
+			return nil, err
+		}
+	}
+
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+
+	// The book may have changed while we were looking up the utxo
+	ignore, err = ob.admit(id.String(), lo, expirationDate, myOrder)
+	if ignore || err != nil {
+		return nil, err
+	}
 
 	// If we made it this far lets add it to our orderbook
 	log.Infof("Added order: %s to order book", id.String())
-	ob.orders[id.String()] = lo
+	ob.makeRoom()
+	ob.addToBook(id.String(), lo)
 	if myOrder {
 		ob.myOrders[id.String()] = lo
 	}
+	ob.saveOrder(id.String())
+	lo.OrderID = id.String()
+	return &BookUpdate{Type: OrderAdded, Order: lo}, nil
 }
 
-// Maybe remove an order from our orderbook
-func (ob *OrderBook) ProcessCloseOrder(serializedOrder []byte, myOrder bool) {
+// admit checks a new order with a valid signature against the book. It returns true
+// if the order should be ignored because we already have it or it was closed, and
+// an error if it was rejected. The caller must hold the lock.
+func (ob *OrderBook) admit(id string, lo LimitOrder, expiry time.Time, myOrder bool) (bool, error) {
+	if _, ok := ob.orders[id]; ok {
+		return true, nil
+	}
+
+	// Don't let closed orders back in
+	if ob.checkTombstone(id, lo, expiry) {
+		log.Debugf("Ignoring closed order %s", id)
+		return true, nil
+	}
+
+	// A UTXO can only back one open order at a time
+	if u, ok := utxoOf(lo); ok {
+		if other, used := ob.utxos[u]; used {
+			log.Errorf("Rejected order %s: utxo %s already backs order %s", id, u.outpoint, other)
+			return false, ErrUnfundedOrder
+		}
+	}
+
+	if err := ob.checkLimits(lo, myOrder); err != nil {
+		log.Debugf("Rejected order %s: %s", id, err)
+		ob.rejected++
+		return false, err
+	}
+	return false, nil
+}
+
+// orderUTXO identifies the UTXO backing an order. Outpoints from before the fork
+// exist on both chains so the coin is part of the key.
+type orderUTXO struct {
+	coin     swap.Coin
+	outpoint wire.OutPoint
+}
+
+// utxoOf returns the UTXO backing the order, if it has a valid one.
+func utxoOf(lo LimitOrder) (orderUTXO, bool) {
+	if lo.Utxo == nil {
+		return orderUTXO{}, false
+	}
+	op, err := swap.DecodeOutPoint(lo.Utxo.Outpoint)
+	if err != nil {
+		return orderUTXO{}, false
+	}
+	coin, _ := swap.MakerFunds(lo.BuyBTC, lo.Quantity, lo.Price)
+	return orderUTXO{coin, op}, true
+}
+
+// validateUTXO checks the order is backed by an unspent output, signed for by the order's
+// peer, which is large enough to cover the coins the maker will send in the swap. If the
+// order is at fault ErrUnfundedOrder is returned; other errors mean we couldn't check it.
+// It's called without the lock.
+func (ob *OrderBook) validateUTXO(lo LimitOrder) error {
+	if lo.Utxo == nil {
+		log.Debug("order has no utxo")
+		return ErrUnfundedOrder
+	}
+	op, err := swap.DecodeOutPoint(lo.Utxo.Outpoint)
+	if err != nil {
+		log.Debugf("invalid utxo outpoint: %s", err)
+		return ErrUnfundedOrder
+	}
+	coin, amount := swap.MakerFunds(lo.BuyBTC, lo.Quantity, lo.Price)
+	ob.lock.Lock()
+	backend, ok := ob.backends[coin]
+	ob.lock.Unlock()
+	if !ok {
+		return fmt.Errorf("no %s chain backend to look up utxo", coin)
+	}
+	out, _, err := backend.GetTxOut(op)
+	if err == chain.ErrTxOutSpent || err == chain.ErrTxNotFound {
+		log.Debugf("utxo %s: %s", op, err)
+		return ErrUnfundedOrder
+	} else if err != nil {
+		return err
+	}
+	if err := swap.VerifyUTXO(lo.PeerID, op, lo.Utxo.Signature, out.PkScript); err != nil {
+		log.Debugf("utxo %s: %s", op, err)
+		return ErrUnfundedOrder
+	}
+	if out.Value < amount {
+		log.Debugf("utxo value %d does not cover order amount %d", out.Value, amount)
+		return ErrUnfundedOrder
+	}
+	return nil
+}
+
+// Maybe apply a fill or amend update to an order in our orderbook. The update is
+// nil if the book didn't change.
+func (ob *OrderBook) ProcessOrderUpdate(serializedUpdate []byte, myOrder bool) (*BookUpdate, error) {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	// Deserialize signed update
+	signed := new(pb.SignedOrderUpdate)
+	err := proto.Unmarshal(serializedUpdate, signed)
+	if err != nil {
+		log.Error(err)
+		return nil, ErrMalformedMessage
+	}
+	// Deserialize nested update
+	update := new(pb.OrderUpdate)
+	err = proto.Unmarshal(signed.SerializedOrderUpdate, update)
+	if err != nil {
+		log.Error(err)
+		return nil, ErrMalformedMessage
+	}
+
+	// If we don't have this order then we can just return
+	lo, ok := ob.orders[update.OrderID]
+	if !ok {
+		return nil, nil
+	}
+	// Updates can arrive out of order. Only apply ones newer than what we have.
+	if update.Sequence <= lo.Sequence {
+		return nil, nil
+	}
+
+	// Validate signature
+	err = Verify(lo.PeerID, pb.Message_OrderUpdate, ob.network, signed.Header, signed.SerializedOrderUpdate, signed.Signature)
+	if err != nil {
+		log.Errorf("Invalid signature on order update: %s", err)
+		return nil, ErrInvalidSignature
+	}
+
+	// The signed UTXO only covers the original quantity so the order can't grow
+	if update.Remaining > lo.Quantity {
+		log.Errorf("Rejected update to order %s: remaining quantity %d exceeds order quantity %d", update.OrderID, update.Remaining, lo.Quantity)
+		return nil, ErrInvalidOrder
+	}
+
+	lo.Remaining = update.Remaining
+	lo.Sequence = update.Sequence
+	lo.update = serializedUpdate
+	lo.OrderID = update.OrderID
+	u := &BookUpdate{Type: OrderFilled, Order: lo}
+	if update.Type == pb.OrderUpdate_Amend {
+		u.Type = OrderAmended
+	}
+	if lo.Remaining == 0 {
+		// Keep a tombstone so the order can't be replayed while its UTXO is unspent
+		log.Infof("Order %s fully filled, removing from order book", update.OrderID)
+		expiry, err := ptypes.Timestamp(lo.Expiry)
+		if err != nil {
+			expiry = time.Now().Add(UnverifiedCloseTTL)
+		}
+		ob.removeFromBook(update.OrderID)
+		ob.addFilled(update.OrderID, lo, signed, expiry)
+		return u, nil
+	}
+	log.Infof("Updated order %s (%s), remaining quantity %d", update.OrderID, update.Type, lo.Remaining)
+	ob.updateInBook(update.OrderID, lo)
+	if _, ok := ob.myOrders[update.OrderID]; ok || myOrder {
+		ob.myOrders[update.OrderID] = lo
+	}
+	ob.saveOrder(update.OrderID)
+	return u, nil
+}
+
+// Maybe remove an order from our orderbook. The update is nil if the book didn't change.
+func (ob *OrderBook) ProcessCloseOrder(serializedOrder []byte, myOrder bool, from peer.ID) (*BookUpdate, error) {
 	ob.lock.Lock()
 	defer ob.lock.Unlock()
 	// Deserialized signed order
@@ -184,41 +553,50 @@ func (ob *OrderBook) ProcessCloseOrder(serializedOrder []byte, myOrder bool) {
 	err := proto.Unmarshal(serializedOrder, signed)
 	if err != nil {
 		log.Error(err)
-		return
+		return nil, ErrMalformedMessage
 	}
 
-	// If we don't have this order then we can just return
 	id, err := cid.Decode(signed.OrderID)
 	if err != nil {
 		log.Error(err)
-		return
+		return nil, ErrMalformedMessage
+	}
+	// We've already seen this close. If the order was filled we keep the close in
+	// place of the fill as it's what peers syncing with us expect.
+	if t, ok := ob.tombstones[id.String()]; ok {
+		if t.close != nil {
+			return nil, nil
+		}
+		if err := ob.verifyClose(t.peerID, signed); err != nil {
+			log.Errorf("Invalid signature on close order: %s", err)
+			return nil, ErrInvalidSignature
+		}
+		ob.addTombstone(signed, t.expiry)
+		return nil, nil
 	}
+
+	// If we don't have the order yet we can't check the signature. Hold on to the
+	// close in case the order shows up later.
 	lo, ok := ob.orders[id.String()]
 	if !ok {
-		return
+		return nil, ob.addUnverifiedClose(signed, from)
 	}
 
 	// Validate signature
-	pid, err := peer.IDB58Decode(lo.PeerID)
-	if err != nil {
-		log.Error(err)
-		return
+	if err := ob.verifyClose(lo.PeerID, signed); err != nil {
+		log.Errorf("Invalid signature on close order: %s", err)
+		return nil, ErrInvalidSignature
 	}
-	pubKey, err := pid.ExtractPublicKey()
+
+	// If we made it this far we can remove the order from the orderbook and keep
+	// the close until the order expires.
+	expiry, err := ptypes.Timestamp(lo.Expiry)
 	if err != nil {
-		log.Error(err)
-		return
+		expiry = time.Now().Add(UnverifiedCloseTTL)
 	}
-	valid, err := pubKey.Verify([]byte(signed.OrderID), signed.Signature)
-	if !valid || err != nil {
-		log.Error("invalid signature on close order")
-		return
-	}
-
-	// If we made it this far we can remove the order from the orderbook
 	log.Infof("Removed order: %s from order book", id.String())
-	delete(ob.orders, id.String())
-	if myOrder {
-		delete(ob.orders, id.String())
-	}
+	ob.removeFromBook(id.String())
+	ob.addTombstone(signed, expiry)
+	lo.OrderID = id.String()
+	return &BookUpdate{Type: OrderClosed, Order: lo}, nil
 }
@@ -0,0 +1,538 @@
+package orderbook
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/cpacia/atomicswap/chain"
+	"github.com/cpacia/atomicswap/pb"
+	"github.com/cpacia/atomicswap/swap"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p-crypto"
+	"github.com/libp2p/go-libp2p-peer"
+	"github.com/multiformats/go-multihash"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	testQuantity = 1000000
+	testPrice    = 1000000000
+)
+
+func newTestPeer(t *testing.T) (crypto.PrivKey, string) {
+	key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, id.Pretty()
+}
+
+// newTestOrder returns a signed order selling BTC backed by the UTXO.
+func newTestOrder(t *testing.T, key crypto.PrivKey, peerID string, op wire.OutPoint, utxoKey *btcec.PrivateKey) []byte {
+	ts, err := ptypes.TimestampProto(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxoSig, err := swap.SignUTXO(peerID, op, utxoKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lopb := &pb.LimitOrder{
+		PeerID:   peerID,
+		Expiry:   ts,
+		Quantity: testQuantity,
+		Price:    testPrice,
+		Utxo: &pb.LimitOrder_SignedUTXO{
+			Outpoint:  swap.EncodeOutPoint(op),
+			Signature: utxoSig,
+		},
+	}
+	ser, err := proto.Marshal(lopb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, sig, err := Sign(key, pb.Message_LimitOrder, swap.RegTest, ser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := proto.Marshal(&pb.SignedLimitOrder{
+		SerializedLimitOrder: ser,
+		Signature:            sig,
+		Header:               header,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func newTestClose(t *testing.T, key crypto.PrivKey, orderID string) []byte {
+	header, sig, err := Sign(key, pb.Message_OrderClose, swap.RegTest, []byte(orderID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ser, err := proto.Marshal(&pb.SignedRemoveOrder{
+		OrderID:   orderID,
+		Signature: sig,
+		Header:    header,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ser
+}
+
+func newTestUpdate(t *testing.T, key crypto.PrivKey, orderID string, remaining, sequence uint64) []byte {
+	ser, err := proto.Marshal(&pb.OrderUpdate{
+		OrderID:   orderID,
+		Type:      pb.OrderUpdate_Fill,
+		Remaining: remaining,
+		Sequence:  sequence,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, sig, err := Sign(key, pb.Message_OrderUpdate, swap.RegTest, ser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := proto.Marshal(&pb.SignedOrderUpdate{
+		SerializedOrderUpdate: ser,
+		Signature:             sig,
+		Header:                header,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestOrderUTXOReuse(t *testing.T) {
+	c := chain.NewSimChain(swap.BTC)
+	w := chain.NewSimWallet(c)
+	if _, err := w.Deposit(100 * testPrice); err != nil {
+		t.Fatal(err)
+	}
+	c.MineBlocks(1)
+	op, utxoKey, err := w.UTXO(testQuantity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ob := NewOrderBook(nil, swap.RegTest)
+	ob.SetChainBackend(swap.BTC, c)
+
+	key, peerID := newTestPeer(t)
+	u, err := ob.ProcessNewLimitOrder(newTestOrder(t, key, peerID, op, utxoKey), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The owner of the UTXO can sign it over to any number of peer IDs
+	otherKey, otherPeerID := newTestPeer(t)
+	if _, err := ob.ProcessNewLimitOrder(newTestOrder(t, otherKey, otherPeerID, op, utxoKey), false); err != ErrUnfundedOrder {
+		t.Errorf("order reusing a utxo got error %v, want %v", err, ErrUnfundedOrder)
+	}
+	if _, err := ob.ProcessNewLimitOrder(newTestOrder(t, key, peerID, op, utxoKey), true); err != ErrUnfundedOrder {
+		t.Errorf("own order reusing a utxo got error %v, want %v", err, ErrUnfundedOrder)
+	}
+
+	// Once the first order is closed the UTXO can back a new one
+	if _, err := ob.ProcessCloseOrder(newTestClose(t, key, u.Order.OrderID), false, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ob.ProcessNewLimitOrder(newTestOrder(t, otherKey, otherPeerID, op, utxoKey), false); err != nil {
+		t.Errorf("order rejected after the utxo was freed: %s", err)
+	}
+}
+
+func testOrderID(t *testing.T, i int) string {
+	h := sha256.Sum256([]byte(strconv.Itoa(i)))
+	enc, err := multihash.Encode(h[:], multihash.SHA2_256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mh, err := multihash.Cast(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cid.NewCidV1(cid.Raw, mh).String()
+}
+
+func TestForgedCloseBeforeOrder(t *testing.T) {
+	c := chain.NewSimChain(swap.BTC)
+	w := chain.NewSimWallet(c)
+	if _, err := w.Deposit(100 * testPrice); err != nil {
+		t.Fatal(err)
+	}
+	c.MineBlocks(1)
+	op, utxoKey, err := w.UTXO(testQuantity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ob := NewOrderBook(nil, swap.RegTest)
+	ob.SetChainBackend(swap.BTC, c)
+	penalized := make(chan peer.ID, 1)
+	ob.SetPenaltyHandler(func(p peer.ID, err error) {
+		if err == ErrInvalidSignature {
+			penalized <- p
+		}
+	})
+
+	key, peerID := newTestPeer(t)
+	order := newTestOrder(t, key, peerID, op, utxoKey)
+	signed := new(pb.SignedLimitOrder)
+	if err := proto.Unmarshal(order, signed); err != nil {
+		t.Fatal(err)
+	}
+	lopb := new(pb.LimitOrder)
+	if err := proto.Unmarshal(signed.SerializedLimitOrder, lopb); err != nil {
+		t.Fatal(err)
+	}
+	id, err := (&LimitOrder{LimitOrder: lopb}).ID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A forged close arrives first, then the real one, then the order
+	forgerKey, _ := newTestPeer(t)
+	if _, err := ob.ProcessCloseOrder(newTestClose(t, forgerKey, id.String()), false, "forger"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ob.ProcessCloseOrder(newTestClose(t, key, id.String()), false, "relay"); err != nil {
+		t.Fatal(err)
+	}
+	u, err := ob.ProcessNewLimitOrder(order, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != nil {
+		t.Error("closed order was added to the book")
+	}
+	select {
+	case p := <-penalized:
+		if p != "forger" {
+			t.Errorf("penalized %s, want forger", p)
+		}
+	case <-time.After(time.Second):
+		t.Error("peer which sent the forged close wasn't penalized")
+	}
+
+	// Replaying the order doesn't bring it back
+	if u, err := ob.ProcessNewLimitOrder(order, false); u != nil || err != nil {
+		t.Errorf("replayed order got update %v and error %v", u, err)
+	}
+}
+
+func TestUnverifiedCloseLimits(t *testing.T) {
+	ob := NewOrderBook(nil, swap.RegTest)
+	key, _ := newTestPeer(t)
+	for i := 0; i < MaxUnverifiedClosesPerPeer; i++ {
+		if _, err := ob.ProcessCloseOrder(newTestClose(t, key, testOrderID(t, i)), false, "flooder"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := ob.ProcessCloseOrder(newTestClose(t, key, testOrderID(t, -1)), false, "flooder"); err != ErrTooManyCloses {
+		t.Errorf("got error %v, want %v", err, ErrTooManyCloses)
+	}
+	if _, err := ob.ProcessCloseOrder(newTestClose(t, key, testOrderID(t, -1)), false, "other"); err != nil {
+		t.Errorf("close from another peer rejected: %s", err)
+	}
+
+	// Expired closes make room again
+	ob.lock.Lock()
+	for _, closes := range ob.unverified {
+		for i := range closes {
+			closes[i].expiry = time.Now().Add(-time.Second)
+		}
+	}
+	ob.removeExpiredTombstones()
+	ob.lock.Unlock()
+	if _, err := ob.ProcessCloseOrder(newTestClose(t, key, testOrderID(t, -1)), false, "flooder"); err != nil {
+		t.Errorf("close rejected after the others expired: %s", err)
+	}
+}
+
+// slowBackend blocks looking up UTXOs until it's released.
+type slowBackend struct {
+	chain.ChainBackend
+	lookup  chan struct{}
+	release chan struct{}
+}
+
+func (b *slowBackend) GetTxOut(op wire.OutPoint) (*wire.TxOut, int32, error) {
+	b.lookup <- struct{}{}
+	<-b.release
+	return b.ChainBackend.GetTxOut(op)
+}
+
+func TestUTXOLookupWithoutLock(t *testing.T) {
+	c := chain.NewSimChain(swap.BTC)
+	w := chain.NewSimWallet(c)
+	if _, err := w.Deposit(100 * testPrice); err != nil {
+		t.Fatal(err)
+	}
+	c.MineBlocks(1)
+	op, utxoKey, err := w.UTXO(testQuantity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &slowBackend{ChainBackend: c, lookup: make(chan struct{}), release: make(chan struct{})}
+	ob := NewOrderBook(nil, swap.RegTest)
+	ob.SetChainBackend(swap.BTC, backend)
+
+	key, peerID := newTestPeer(t)
+	order := newTestOrder(t, key, peerID, op, utxoKey)
+	type result struct {
+		u   *BookUpdate
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		u, err := ob.ProcessNewLimitOrder(order, false)
+		done <- result{u, err}
+	}()
+	<-backend.lookup
+
+	// The book keeps working while the lookup is in flight and the order gets closed
+	signed := new(pb.SignedLimitOrder)
+	if err := proto.Unmarshal(order, signed); err != nil {
+		t.Fatal(err)
+	}
+	lopb := new(pb.LimitOrder)
+	if err := proto.Unmarshal(signed.SerializedLimitOrder, lopb); err != nil {
+		t.Fatal(err)
+	}
+	id, err := (&LimitOrder{LimitOrder: lopb}).ID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ob.ProcessCloseOrder(newTestClose(t, key, id.String()), false, "relay"); err != nil {
+		t.Fatal(err)
+	}
+	if len(ob.OpenOrders()) != 0 {
+		t.Error("order in the book before its utxo was checked")
+	}
+
+	close(backend.release)
+	r := <-done
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if r.u != nil || len(ob.OpenOrders()) != 0 {
+		t.Error("order closed during the utxo lookup was added to the book")
+	}
+}
+
+func TestFilledOrderReplay(t *testing.T) {
+	c := chain.NewSimChain(swap.BTC)
+	w := chain.NewSimWallet(c)
+	if _, err := w.Deposit(100 * testPrice); err != nil {
+		t.Fatal(err)
+	}
+	c.MineBlocks(1)
+	op, utxoKey, err := w.UTXO(testQuantity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := ds.NewMapDatastore()
+	ob := NewOrderBook(db, swap.RegTest)
+	ob.SetChainBackend(swap.BTC, c)
+
+	key, peerID := newTestPeer(t)
+	order := newTestOrder(t, key, peerID, op, utxoKey)
+	u, err := ob.ProcessNewLimitOrder(order, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := u.Order.OrderID
+
+	// The order is filled from another UTXO so its own stays unspent
+	if _, err := ob.ProcessOrderUpdate(newTestUpdate(t, key, id, 0, 1), false); err != nil {
+		t.Fatal(err)
+	}
+	if len(ob.OpenOrders()) != 0 {
+		t.Fatal("filled order still in the book")
+	}
+	if u, err := ob.ProcessNewLimitOrder(order, false); u != nil || err != nil {
+		t.Errorf("replayed filled order got update %v and error %v", u, err)
+	}
+
+	// Peers syncing with us get the fill
+	if orders := ob.SignedOrders([]string{id}); len(orders.Orders) != 0 || len(orders.Updates) != 1 {
+		t.Errorf("sent %d orders and %d updates for the filled order, want just the update", len(orders.Orders), len(orders.Updates))
+	}
+
+	// It's still there after a restart
+	ob = NewOrderBook(db, swap.RegTest)
+	ob.SetChainBackend(swap.BTC, c)
+	if u, err := ob.ProcessNewLimitOrder(order, false); u != nil || err != nil {
+		t.Errorf("replayed filled order after restart got update %v and error %v", u, err)
+	}
+
+	// Only the maker's close takes the place of the fill
+	forgerKey, _ := newTestPeer(t)
+	if _, err := ob.ProcessCloseOrder(newTestClose(t, forgerKey, id), false, "forger"); err != ErrInvalidSignature {
+		t.Errorf("forged close got error %v, want %v", err, ErrInvalidSignature)
+	}
+	if _, err := ob.ProcessCloseOrder(newTestClose(t, key, id), false, "relay"); err != nil {
+		t.Fatal(err)
+	}
+	if orders := ob.SignedOrders([]string{id}); len(orders.Closes) != 1 {
+		t.Errorf("sent %d closes for the closed order, want 1", len(orders.Closes))
+	}
+}
+
+func TestLoadUnsignedOrders(t *testing.T) {
+	c := chain.NewSimChain(swap.BTC)
+	w := chain.NewSimWallet(c)
+	for i := 0; i < 2; i++ {
+		if _, err := w.Deposit(100 * testPrice); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.MineBlocks(1)
+
+	db := ds.NewMapDatastore()
+	ob := NewOrderBook(db, swap.RegTest)
+	ob.SetChainBackend(swap.BTC, c)
+
+	// One order from another peer and one of ours
+	var ids []string
+	keys := make([]crypto.PrivKey, 2)
+	for i := range keys {
+		op, utxoKey, err := w.UTXO(testQuantity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.LockUTXOs([]wire.OutPoint{op})
+		var peerID string
+		keys[i], peerID = newTestPeer(t)
+		u, err := ob.ProcessNewLimitOrder(newTestOrder(t, keys[i], peerID, op, utxoKey), i == 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, u.Order.OrderID)
+	}
+
+	// Strip the headers as older versions didn't sign over one
+	for _, id := range ids {
+		val, err := db.Get(orderKey(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := new(pb.OrderRecord)
+		if err := proto.Unmarshal(val.([]byte), rec); err != nil {
+			t.Fatal(err)
+		}
+		rec.Order.Header = nil
+		ser, err := proto.Marshal(rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Put(orderKey(id), ser); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ob = NewOrderBook(db, swap.RegTest)
+	if n := len(ob.OpenOrders()); n != 0 {
+		t.Fatalf("loaded %d unsigned orders, want 0", n)
+	}
+	if _, err := db.Get(orderKey(ids[0])); err != ds.ErrNotFound {
+		t.Errorf("unsigned order from another peer wasn't deleted, got error %v", err)
+	}
+
+	// Our own order comes back once it's signed again, with the same ID
+	ob.ResignOrders(keys[1])
+	mine := ob.MyOrders()
+	if len(mine) != 1 || mine[0].OrderID != ids[1] {
+		t.Fatalf("got my orders %v, want %s", mine, ids[1])
+	}
+	if err := ob.verifyOrder(mine[0]); err != nil {
+		t.Errorf("signed again order doesn't verify: %s", err)
+	}
+	if n := len(ob.OpenOrders()); n != 1 {
+		t.Errorf("got %d open orders, want 1", n)
+	}
+}
+
+func TestUnverifiedCloseAccounting(t *testing.T) {
+	c := chain.NewSimChain(swap.BTC)
+	w := chain.NewSimWallet(c)
+	if _, err := w.Deposit(100 * testPrice); err != nil {
+		t.Fatal(err)
+	}
+	c.MineBlocks(1)
+	op, utxoKey, err := w.UTXO(testQuantity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ob := NewOrderBook(nil, swap.RegTest)
+	ob.SetChainBackend(swap.BTC, c)
+
+	key, peerID := newTestPeer(t)
+	order := newTestOrder(t, key, peerID, op, utxoKey)
+	signed := new(pb.SignedLimitOrder)
+	if err := proto.Unmarshal(order, signed); err != nil {
+		t.Fatal(err)
+	}
+	lopb := new(pb.LimitOrder)
+	if err := proto.Unmarshal(signed.SerializedLimitOrder, lopb); err != nil {
+		t.Fatal(err)
+	}
+	id, err := (&LimitOrder{LimitOrder: lopb}).ID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same close relayed twice only counts once
+	valid := newTestClose(t, key, id.String())
+	for i := 0; i < 2; i++ {
+		if _, err := ob.ProcessCloseOrder(valid, false, "relay"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	forgerKey, _ := newTestPeer(t)
+	if _, err := ob.ProcessCloseOrder(newTestClose(t, forgerKey, id.String()), false, "forger"); err != nil {
+		t.Fatal(err)
+	}
+	ob.lock.Lock()
+	count, relayed := ob.unverifiedCount, ob.unverifiedFrom["relay"]
+	ob.lock.Unlock()
+	if count != 2 || relayed != 1 {
+		t.Errorf("holding %d closes with %d from the relay, want 2 and 1", count, relayed)
+	}
+
+	// Once the order shows up they're checked and no longer count
+	if _, err := ob.ProcessNewLimitOrder(order, false); err != nil {
+		t.Fatal(err)
+	}
+	ob.lock.Lock()
+	count, peers := ob.unverifiedCount, len(ob.unverifiedFrom)
+	ob.lock.Unlock()
+	if count != 0 || peers != 0 {
+		t.Errorf("holding %d closes from %d peers after the order arrived, want none", count, peers)
+	}
+
+	// Closes from pubsub, which have no sender, are still held to the total
+	ob.lock.Lock()
+	ob.unverifiedCount = MaxUnverifiedCloses
+	ob.lock.Unlock()
+	if _, err := ob.ProcessCloseOrder(newTestClose(t, key, testOrderID(t, 1)), false, ""); err != ErrTooManyCloses {
+		t.Errorf("close over the total got error %v, want %v", err, ErrTooManyCloses)
+	}
+}
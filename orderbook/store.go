@@ -0,0 +1,223 @@
+package orderbook
+
+import (
+	"github.com/cpacia/atomicswap/pb"
+	"github.com/cpacia/atomicswap/swap"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/libp2p/go-libp2p-crypto"
+	"time"
+)
+
+// Orders in the book are stored in the datastore under this prefix keyed by order ID.
+const ordersPrefix = "/orderbook"
+
+func orderKey(orderID string) ds.Key {
+	return ds.NewKey(ordersPrefix + "/" + orderID)
+}
+
+// saveOrder writes the order to the datastore. The caller must hold the lock.
+func (ob *OrderBook) saveOrder(orderID string) {
+	if ob.db == nil {
+		return
+	}
+	lo, ok := ob.orders[orderID]
+	if !ok {
+		return
+	}
+	signed, err := lo.SignedLimitOrder()
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	_, mine := ob.myOrders[orderID]
+	rec := &pb.OrderRecord{
+		Order:    signed,
+		Update:   lo.update,
+		Mine:     mine,
+		Received: lo.received.UnixNano(),
+	}
+	ser, err := proto.Marshal(rec)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if err := ob.db.Put(orderKey(orderID), ser); err != nil {
+		log.Errorf("Error saving order %s: %s", orderID, err)
+	}
+}
+
+// deleteOrder removes the order from the datastore. The caller must hold the lock.
+func (ob *OrderBook) deleteOrder(orderID string) {
+	if ob.db == nil {
+		return
+	}
+	if err := ob.db.Delete(orderKey(orderID)); err != nil && err != ds.ErrNotFound {
+		log.Errorf("Error deleting order %s: %s", orderID, err)
+	}
+}
+
+// loadOrders reads the book back from the datastore. Orders which expired while
+// we were offline are dropped. UTXOs were checked when we first received the orders
+// so they aren't checked again, but signatures are as orders saved by older versions
+// weren't signed over a header. Other peers' orders which fail are dropped and our
+// own are held back for ResignOrders.
+func (ob *OrderBook) loadOrders() error {
+	results, err := ob.db.Query(query.Query{Prefix: ordersPrefix})
+	if err != nil {
+		return err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return err
+	}
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	for _, e := range entries {
+		ser, ok := e.Value.([]byte)
+		if !ok {
+			continue
+		}
+		rec := new(pb.OrderRecord)
+		if err := proto.Unmarshal(ser, rec); err != nil || rec.Order == nil {
+			log.Errorf("Error unmarshalling order %s: %v", e.Key, err)
+			continue
+		}
+		lo, err := orderFromRecord(rec)
+		if err != nil {
+			log.Errorf("Error loading order %s: %s", e.Key, err)
+			continue
+		}
+		id, err := lo.ID()
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		_, closed := ob.tombstones[id.String()]
+		expiry, err := ptypes.Timestamp(lo.Expiry)
+		if err != nil || expiry.Before(time.Now()) || lo.Remaining == 0 || closed {
+			ob.deleteOrder(id.String())
+			continue
+		}
+		if err := ob.verifyOrder(lo); err != nil {
+			if rec.Mine {
+				log.Warningf("Order %s needs to be signed again: %s", id.String(), err)
+				ob.unsigned[id.String()] = lo
+				continue
+			}
+			log.Warningf("Dropping order %s: %s", id.String(), err)
+			ob.deleteOrder(id.String())
+			continue
+		}
+		if u, ok := utxoOf(lo); ok {
+			if _, used := ob.utxos[u]; used {
+				ob.deleteOrder(id.String())
+				continue
+			}
+		}
+		ob.addToBook(id.String(), lo)
+		if rec.Mine {
+			ob.myOrders[id.String()] = lo
+		}
+	}
+	log.Infof("Loaded %d orders from the datastore", len(ob.orders))
+	return nil
+}
+
+func orderFromRecord(rec *pb.OrderRecord) (LimitOrder, error) {
+	limitpb := new(pb.LimitOrder)
+	if err := proto.Unmarshal(rec.Order.SerializedLimitOrder, limitpb); err != nil {
+		return LimitOrder{}, err
+	}
+	lo := LimitOrder{
+		LimitOrder: limitpb,
+		signature:  rec.Order.Signature,
+		header:     rec.Order.Header,
+		Remaining:  limitpb.Quantity,
+		received:   time.Unix(0, rec.Received),
+	}
+	if rec.Update != nil {
+		lo.update = rec.Update
+		su, err := lo.SignedUpdate()
+		if err != nil {
+			return LimitOrder{}, err
+		}
+		update := new(pb.OrderUpdate)
+		if err := proto.Unmarshal(su.SerializedOrderUpdate, update); err != nil {
+			return LimitOrder{}, err
+		}
+		lo.Remaining = update.Remaining
+		lo.Sequence = update.Sequence
+	}
+	return lo, nil
+}
+
+// verifyOrder checks the signatures on the order and its latest update.
+func (ob *OrderBook) verifyOrder(lo LimitOrder) error {
+	signed, err := lo.SignedLimitOrder()
+	if err != nil {
+		return err
+	}
+	if err := Verify(lo.PeerID, pb.Message_LimitOrder, ob.network, signed.Header, signed.SerializedLimitOrder, signed.Signature); err != nil {
+		return err
+	}
+	su, err := lo.SignedUpdate()
+	if err != nil || su == nil {
+		return err
+	}
+	return Verify(lo.PeerID, pb.Message_OrderUpdate, ob.network, su.Header, su.SerializedOrderUpdate, su.Signature)
+}
+
+// ResignOrders signs the orders loadOrders held back with our key and puts them
+// back in the book. The order IDs don't change as they only cover the order itself.
+// Orders the key didn't make are dropped.
+func (ob *OrderBook) ResignOrders(key crypto.PrivKey) {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	for id, lo := range ob.unsigned {
+		delete(ob.unsigned, id)
+		if err := resign(key, &lo, ob.network); err != nil {
+			log.Errorf("Error signing order %s: %s", id, err)
+			ob.deleteOrder(id)
+			continue
+		}
+		if err := ob.verifyOrder(lo); err != nil {
+			log.Warningf("Dropping order %s: %s", id, err)
+			ob.deleteOrder(id)
+			continue
+		}
+		if u, ok := utxoOf(lo); ok {
+			if _, used := ob.utxos[u]; used {
+				ob.deleteOrder(id)
+				continue
+			}
+		}
+		log.Infof("Signed order %s again", id)
+		ob.addToBook(id, lo)
+		ob.myOrders[id] = lo
+		ob.saveOrder(id)
+	}
+}
+
+func resign(key crypto.PrivKey, lo *LimitOrder, network swap.Network) error {
+	signed, err := lo.SignedLimitOrder()
+	if err != nil {
+		return err
+	}
+	lo.header, lo.signature, err = Sign(key, pb.Message_LimitOrder, network, signed.SerializedLimitOrder)
+	if err != nil {
+		return err
+	}
+	su, err := lo.SignedUpdate()
+	if err != nil || su == nil {
+		return err
+	}
+	su.Header, su.Signature, err = Sign(key, pb.Message_OrderUpdate, network, su.SerializedOrderUpdate)
+	if err != nil {
+		return err
+	}
+	lo.update, err = proto.Marshal(su)
+	return err
+}
@@ -0,0 +1,160 @@
+package orderbook
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"github.com/cpacia/atomicswap/pb"
+	"sort"
+	"strconv"
+)
+
+// SyncBuckets is the number of buckets the order book is split into for the
+// digest. Peers only exchange order IDs for the buckets that differ.
+const SyncBuckets = 64
+
+// bucketFor returns the digest bucket of the order ID.
+func bucketFor(orderID string) uint32 {
+	h := sha256.Sum256([]byte(orderID))
+	return uint32(h[0]) % SyncBuckets
+}
+
+// buckets returns the order IDs and sequence numbers in each bucket, along with
+// the IDs of the orders we hold tombstones for. The caller must hold the lock.
+func (ob *OrderBook) buckets() [SyncBuckets][]*pb.OrderBookInventory_Entry {
+	var buckets [SyncBuckets][]*pb.OrderBookInventory_Entry
+	for id, lo := range ob.orders {
+		b := bucketFor(id)
+		buckets[b] = append(buckets[b], &pb.OrderBookInventory_Entry{OrderID: id, Sequence: lo.Sequence})
+	}
+	for id := range ob.tombstones {
+		b := bucketFor(id)
+		buckets[b] = append(buckets[b], &pb.OrderBookInventory_Entry{OrderID: id, Closed: true})
+	}
+	for _, entries := range buckets {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].OrderID < entries[j].OrderID })
+	}
+	return buckets
+}
+
+func hashBucket(entries []*pb.OrderBookInventory_Entry) []byte {
+	if len(entries) == 0 {
+		return nil
+	}
+	h := sha256.New()
+	for _, e := range entries {
+		if e.Closed {
+			h.Write([]byte(e.OrderID + ":closed\n"))
+			continue
+		}
+		h.Write([]byte(e.OrderID + ":" + strconv.FormatUint(e.Sequence, 10) + "\n"))
+	}
+	return h.Sum(nil)
+}
+
+// Digest returns a hash of each bucket of our order book to send to a peer.
+func (ob *OrderBook) Digest() *pb.OrderBookDigest {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	digest := new(pb.OrderBookDigest)
+	for _, entries := range ob.buckets() {
+		digest.Buckets = append(digest.Buckets, hashBucket(entries))
+	}
+	return digest
+}
+
+// Inventory compares a peer's digest against our book and returns the orders we
+// have in each bucket that doesn't match.
+func (ob *OrderBook) Inventory(digest *pb.OrderBookDigest) *pb.OrderBookInventory {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	inv := new(pb.OrderBookInventory)
+	for i, entries := range ob.buckets() {
+		var theirs []byte
+		if i < len(digest.Buckets) {
+			theirs = digest.Buckets[i]
+		}
+		if bytes.Equal(hashBucket(entries), theirs) {
+			continue
+		}
+		inv.Buckets = append(inv.Buckets, uint32(i))
+		inv.Entries = append(inv.Entries, entries...)
+	}
+	return inv
+}
+
+// newer returns true if entry a is more up to date than b. A close beats any
+// update and later updates beat earlier ones.
+func newer(a, b *pb.OrderBookInventory_Entry) bool {
+	if b.Closed {
+		return false
+	}
+	return a.Closed || a.Sequence > b.Sequence
+}
+
+// Reconcile compares a peer's inventory against our book. It returns the IDs of the
+// orders we should request from the peer because we're missing them, have an older
+// update or haven't seen the close, and the IDs of the orders we should push to the
+// peer for the same reasons.
+func (ob *OrderBook) Reconcile(inv *pb.OrderBookInventory) (want []string, push []string) {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	buckets := ob.buckets()
+	ours := make(map[string]*pb.OrderBookInventory_Entry)
+	for _, entries := range buckets {
+		for _, e := range entries {
+			ours[e.OrderID] = e
+		}
+	}
+	theirs := make(map[string]*pb.OrderBookInventory_Entry)
+	for _, e := range inv.Entries {
+		theirs[e.OrderID] = e
+		if mine, ok := ours[e.OrderID]; !ok || newer(e, mine) {
+			want = append(want, e.OrderID)
+		}
+	}
+	for _, b := range inv.Buckets {
+		if b >= SyncBuckets {
+			continue
+		}
+		for _, e := range buckets[b] {
+			if t, ok := theirs[e.OrderID]; !ok || newer(e, t) {
+				push = append(push, e.OrderID)
+			}
+		}
+	}
+	return want, push
+}
+
+// SignedOrders returns the signed orders, and their latest updates, for the IDs
+// we have in our book and the closes for the ones that were closed. For orders
+// which were filled we send the update which filled them.
+func (ob *OrderBook) SignedOrders(orderIDs []string) *pb.Orders {
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	orders := new(pb.Orders)
+	for _, id := range orderIDs {
+		if t, ok := ob.tombstones[id]; ok {
+			if t.close != nil {
+				orders.Closes = append(orders.Closes, t.close)
+			} else {
+				orders.Updates = append(orders.Updates, t.fill)
+			}
+			continue
+		}
+		lo, ok := ob.orders[id]
+		if !ok {
+			continue
+		}
+		signed, err := lo.SignedLimitOrder()
+		if err != nil {
+			continue
+		}
+		orders.Orders = append(orders.Orders, signed)
+		su, err := lo.SignedUpdate()
+		if err != nil || su == nil {
+			continue
+		}
+		orders.Updates = append(orders.Updates, su)
+	}
+	return orders
+}
@@ -0,0 +1,110 @@
+package orderbook
+
+import (
+	"github.com/cpacia/atomicswap/chain"
+	"github.com/cpacia/atomicswap/pb"
+	"github.com/cpacia/atomicswap/swap"
+	"github.com/golang/protobuf/proto"
+	"testing"
+)
+
+// syncBooks runs the sync protocol between the books the way the wire service does.
+func syncBooks(t *testing.T, a, b *OrderBook) {
+	want, push := a.Reconcile(b.Inventory(a.Digest()))
+	processOrders(t, a, b.SignedOrders(want))
+	processOrders(t, b, a.SignedOrders(push))
+}
+
+// processOrders adds the orders then applies the updates and closes.
+func processOrders(t *testing.T, ob *OrderBook, orders *pb.Orders) {
+	for _, o := range orders.Orders {
+		ser, err := proto.Marshal(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ob.ProcessNewLimitOrder(ser, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, u := range orders.Updates {
+		ser, err := proto.Marshal(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ob.ProcessOrderUpdate(ser, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range orders.Closes {
+		ser, err := proto.Marshal(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ob.ProcessCloseOrder(ser, false, "peer"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSyncUpdates(t *testing.T) {
+	c := chain.NewSimChain(swap.BTC)
+	w := chain.NewSimWallet(c)
+	if _, err := w.Deposit(100 * testPrice); err != nil {
+		t.Fatal(err)
+	}
+	c.MineBlocks(1)
+	op, utxoKey, err := w.UTXO(testQuantity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewOrderBook(nil, swap.RegTest)
+	a.SetChainBackend(swap.BTC, c)
+	b := NewOrderBook(nil, swap.RegTest)
+	b.SetChainBackend(swap.BTC, c)
+
+	key, peerID := newTestPeer(t)
+	order := newTestOrder(t, key, peerID, op, utxoKey)
+	u, err := a.ProcessNewLimitOrder(order, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := u.Order.OrderID
+	if _, err := b.ProcessNewLimitOrder(order, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// b only has the first fill, a has the second too
+	first := newTestUpdate(t, key, id, testQuantity/2, 1)
+	if _, err := a.ProcessOrderUpdate(first, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.ProcessOrderUpdate(first, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ProcessOrderUpdate(newTestUpdate(t, key, id, testQuantity/4, 2), false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The later update wins whichever side starts the sync
+	syncBooks(t, a, b)
+	lo, _, err := b.GetOrder(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lo.Remaining != testQuantity/4 {
+		t.Errorf("got remaining %d after syncing, want %d", lo.Remaining, testQuantity/4)
+	}
+
+	// A fill to zero reaches the other book as a tombstone
+	if _, err := a.ProcessOrderUpdate(newTestUpdate(t, key, id, 0, 3), false); err != nil {
+		t.Fatal(err)
+	}
+	syncBooks(t, b, a)
+	if _, _, err := b.GetOrder(id); err == nil {
+		t.Error("filled order still in the book after syncing")
+	}
+	if want, push := a.Reconcile(b.Inventory(a.Digest())); len(want) != 0 || len(push) != 0 {
+		t.Errorf("books still want %v and push %v after syncing", want, push)
+	}
+}
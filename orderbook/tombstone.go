@@ -0,0 +1,254 @@
+package orderbook
+
+import (
+	"errors"
+	"github.com/cpacia/atomicswap/pb"
+	"github.com/golang/protobuf/proto"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/libp2p/go-libp2p-peer"
+	"time"
+)
+
+// UnverifiedCloseTTL is how long we hold on to a close for an order we haven't
+// seen. If the order doesn't show up by then we drop the close.
+const UnverifiedCloseTTL = time.Hour
+
+// Anyone can send us closes for orders we don't have and we can't check them until
+// the order shows up, so we only hold so many of them.
+const (
+	MaxUnverifiedCloses        = 10000
+	MaxUnverifiedClosesPerPeer = 100
+)
+
+// Tombstones are stored in the datastore under this prefix keyed by order ID.
+const tombstonesPrefix = "/tombstones"
+
+var ErrTooManyCloses = errors.New("too many closes for unknown orders")
+
+// A tombstone is kept for each closed order until the order expires so that
+// replaying the signed order can't put it back in the book. Orders filled without
+// being closed get one too as the maker may have funded the swap from another
+// UTXO, leaving the order's unspent. Those hold the update which filled the order
+// and the maker's peer ID in place of the close.
+type tombstone struct {
+	close  *pb.SignedRemoveOrder
+	fill   *pb.SignedOrderUpdate
+	peerID string
+	expiry time.Time
+}
+
+// An unverifiedClose is a close that arrived before the order it refers to so we
+// can't check the signature yet. We keep every distinct close for the order until
+// it shows up so a forged close can't crowd out the real one. They're only held
+// in memory.
+type unverifiedClose struct {
+	close  *pb.SignedRemoveOrder
+	from   peer.ID
+	expiry time.Time
+}
+
+func tombstoneKey(orderID string) ds.Key {
+	return ds.NewKey(tombstonesPrefix + "/" + orderID)
+}
+
+// verifyClose checks the close was signed by the peer that made the order.
+func (ob *OrderBook) verifyClose(peerID string, signed *pb.SignedRemoveOrder) error {
+	return Verify(peerID, pb.Message_OrderClose, ob.network, signed.Header, []byte(signed.OrderID), signed.Signature)
+}
+
+// addTombstone records the verified close and saves it to the datastore. The caller
+// must hold the lock.
+func (ob *OrderBook) addTombstone(signed *pb.SignedRemoveOrder, expiry time.Time) {
+	ob.saveTombstone(signed.OrderID, tombstone{close: signed, expiry: expiry})
+}
+
+// addFilled records a tombstone for an order the update filled. The caller must
+// hold the lock.
+func (ob *OrderBook) addFilled(orderID string, lo LimitOrder, fill *pb.SignedOrderUpdate, expiry time.Time) {
+	ob.saveTombstone(orderID, tombstone{fill: fill, peerID: lo.PeerID, expiry: expiry})
+}
+
+func (ob *OrderBook) saveTombstone(orderID string, t tombstone) {
+	ob.tombstones[orderID] = t
+	if ob.db == nil {
+		return
+	}
+	ser, err := proto.Marshal(&pb.TombstoneRecord{
+		Close:    t.close,
+		Expiry:   t.expiry.Unix(),
+		Verified: true,
+		Fill:     t.fill,
+		PeerID:   t.peerID,
+	})
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if err := ob.db.Put(tombstoneKey(orderID), ser); err != nil {
+		log.Errorf("Error saving tombstone for order %s: %s", orderID, err)
+	}
+}
+
+// addUnverifiedClose holds on to a close for an order we haven't seen. The caller
+// must hold the lock.
+func (ob *OrderBook) addUnverifiedClose(signed *pb.SignedRemoveOrder, from peer.ID) error {
+	for _, c := range ob.unverified[signed.OrderID] {
+		if proto.Equal(c.close, signed) {
+			return nil
+		}
+	}
+	if ob.unverifiedCount >= MaxUnverifiedCloses || (from != "" && ob.unverifiedFrom[from] >= MaxUnverifiedClosesPerPeer) {
+		return ErrTooManyCloses
+	}
+	ob.unverified[signed.OrderID] = append(ob.unverified[signed.OrderID], unverifiedClose{
+		close:  signed,
+		from:   from,
+		expiry: time.Now().Add(UnverifiedCloseTTL),
+	})
+	ob.unverifiedCount++
+	if from != "" {
+		ob.unverifiedFrom[from]++
+	}
+	return nil
+}
+
+// removeUnverifiedCloses drops the closes we're holding for the order. The caller
+// must hold the lock.
+func (ob *OrderBook) removeUnverifiedCloses(orderID string) {
+	for _, c := range ob.unverified[orderID] {
+		ob.releaseUnverifiedClose(c)
+	}
+	delete(ob.unverified, orderID)
+}
+
+// releaseUnverifiedClose takes a dropped close off the counts we limit. The caller
+// must hold the lock.
+func (ob *OrderBook) releaseUnverifiedClose(c unverifiedClose) {
+	ob.unverifiedCount--
+	if c.from == "" {
+		return
+	}
+	ob.unverifiedFrom[c.from]--
+	if ob.unverifiedFrom[c.from] <= 0 {
+		delete(ob.unverifiedFrom, c.from)
+	}
+}
+
+// removeTombstone deletes the tombstone. The caller must hold the lock.
+func (ob *OrderBook) removeTombstone(orderID string) {
+	delete(ob.tombstones, orderID)
+	if ob.db == nil {
+		return
+	}
+	if err := ob.db.Delete(tombstoneKey(orderID)); err != nil && err != ds.ErrNotFound {
+		log.Errorf("Error deleting tombstone for order %s: %s", orderID, err)
+	}
+}
+
+// checkTombstone is called for a new order with a valid signature. It returns
+// true if the order has been closed. If we're holding unverified closes for the
+// order we can now check them. A valid one becomes the order's tombstone and the
+// peers which sent us forged ones are penalized. The caller must hold the lock.
+func (ob *OrderBook) checkTombstone(orderID string, lo LimitOrder, expiry time.Time) bool {
+	if _, ok := ob.tombstones[orderID]; ok {
+		return true
+	}
+	closes := ob.unverified[orderID]
+	ob.removeUnverifiedCloses(orderID)
+	closed := false
+	for _, c := range closes {
+		if err := ob.verifyClose(lo.PeerID, c.close); err != nil {
+			log.Warningf("Dropping close for order %s: %s", orderID, err)
+			ob.penalize(c.from, ErrInvalidSignature)
+			continue
+		}
+		if !closed {
+			ob.addTombstone(c.close, expiry)
+			closed = true
+		}
+	}
+	return closed
+}
+
+// removeExpiredTombstones drops tombstones for orders which have expired and
+// so can't be added back anyway, and unverified closes whose order never showed
+// up. The caller must hold the lock.
+func (ob *OrderBook) removeExpiredTombstones() {
+	now := time.Now()
+	for id, t := range ob.tombstones {
+		if t.expiry.Before(now) {
+			ob.removeTombstone(id)
+		}
+	}
+	for id, closes := range ob.unverified {
+		var kept []unverifiedClose
+		for _, c := range closes {
+			if c.expiry.Before(now) {
+				ob.releaseUnverifiedClose(c)
+				continue
+			}
+			kept = append(kept, c)
+		}
+		if len(kept) == 0 {
+			delete(ob.unverified, id)
+		} else {
+			ob.unverified[id] = kept
+		}
+	}
+}
+
+// loadTombstones reads the tombstones back from the datastore.
+func (ob *OrderBook) loadTombstones() error {
+	results, err := ob.db.Query(query.Query{Prefix: tombstonesPrefix})
+	if err != nil {
+		return err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return err
+	}
+	ob.lock.Lock()
+	defer ob.lock.Unlock()
+	for _, e := range entries {
+		ser, ok := e.Value.([]byte)
+		if !ok {
+			continue
+		}
+		rec := new(pb.TombstoneRecord)
+		if err := proto.Unmarshal(ser, rec); err != nil || (rec.Close == nil && rec.Fill == nil) {
+			log.Errorf("Error unmarshalling tombstone %s: %v", e.Key, err)
+			continue
+		}
+		orderID, err := tombstoneOrderID(rec)
+		if err != nil {
+			log.Errorf("Error unmarshalling tombstone %s: %s", e.Key, err)
+			continue
+		}
+		// Older versions saved unverified closes too. We don't keep those anymore.
+		if !rec.Verified {
+			ob.removeTombstone(orderID)
+			continue
+		}
+		ob.tombstones[orderID] = tombstone{
+			close:  rec.Close,
+			fill:   rec.Fill,
+			peerID: rec.PeerID,
+			expiry: time.Unix(rec.Expiry, 0),
+		}
+	}
+	ob.removeExpiredTombstones()
+	return nil
+}
+
+// tombstoneOrderID returns the ID of the order the record is for.
+func tombstoneOrderID(rec *pb.TombstoneRecord) (string, error) {
+	if rec.Close != nil {
+		return rec.Close.OrderID, nil
+	}
+	update := new(pb.OrderUpdate)
+	if err := proto.Unmarshal(rec.Fill.SerializedOrderUpdate, update); err != nil {
+		return "", err
+	}
+	return update.OrderID, nil
+}
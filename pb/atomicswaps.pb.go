@@ -1,19 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // source: atomicswaps.proto
 
-/*
-Package pb is a generated protocol buffer package.
-
-It is generated from these files:
-	atomicswaps.proto
-	message.proto
-
-It has these top-level messages:
-	SignedLimitOrder
-	LimitOrder
-	SignedRemoveOrder
-	Message
-*/
 package pb
 
 import proto "github.com/golang/protobuf/proto"
@@ -26,21 +13,72 @@ var _ = proto.Marshal
 var _ = fmt.Errorf
 var _ = math.Inf
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the proto package it is being compiled against.
-// A compilation error at this line likely means your copy of the
-// proto package needs to be updated.
-const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+type OrderUpdate_UpdateType int32
+
+const (
+	OrderUpdate_Fill  OrderUpdate_UpdateType = 0
+	OrderUpdate_Amend OrderUpdate_UpdateType = 1
+)
+
+var OrderUpdate_UpdateType_name = map[int32]string{
+	0: "Fill",
+	1: "Amend",
+}
+var OrderUpdate_UpdateType_value = map[string]int32{
+	"Fill":  0,
+	"Amend": 1,
+}
+
+func (x OrderUpdate_UpdateType) String() string {
+	return proto.EnumName(OrderUpdate_UpdateType_name, int32(x))
+}
+func (OrderUpdate_UpdateType) EnumDescriptor() ([]byte, []int) { return fileDescriptor1, []int{3, 0} }
+
+// SignatureHeader is signed along with the message. The signature also commits to
+// a domain tag and the type of message so it can't be replayed as another kind of
+// message or on another network.
+type SignatureHeader struct {
+	Version   uint32                     `protobuf:"varint,1,opt,name=version" json:"version,omitempty"`
+	Network   string                     `protobuf:"bytes,2,opt,name=network" json:"network,omitempty"`
+	Timestamp *google_protobuf.Timestamp `protobuf:"bytes,3,opt,name=timestamp" json:"timestamp,omitempty"`
+}
+
+func (m *SignatureHeader) Reset()                    { *m = SignatureHeader{} }
+func (m *SignatureHeader) String() string            { return proto.CompactTextString(m) }
+func (*SignatureHeader) ProtoMessage()               {}
+func (*SignatureHeader) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{0} }
+
+func (m *SignatureHeader) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *SignatureHeader) GetNetwork() string {
+	if m != nil {
+		return m.Network
+	}
+	return ""
+}
+
+func (m *SignatureHeader) GetTimestamp() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
 
 type SignedLimitOrder struct {
-	SerializedLimitOrder []byte `protobuf:"bytes,1,opt,name=serializedLimitOrder,proto3" json:"serializedLimitOrder,omitempty"`
-	Signature            []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	SerializedLimitOrder []byte           `protobuf:"bytes,1,opt,name=serializedLimitOrder,proto3" json:"serializedLimitOrder,omitempty"`
+	Signature            []byte           `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	Header               *SignatureHeader `protobuf:"bytes,3,opt,name=header" json:"header,omitempty"`
 }
 
 func (m *SignedLimitOrder) Reset()                    { *m = SignedLimitOrder{} }
 func (m *SignedLimitOrder) String() string            { return proto.CompactTextString(m) }
 func (*SignedLimitOrder) ProtoMessage()               {}
-func (*SignedLimitOrder) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+func (*SignedLimitOrder) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{1} }
 
 func (m *SignedLimitOrder) GetSerializedLimitOrder() []byte {
 	if m != nil {
@@ -56,19 +94,27 @@ func (m *SignedLimitOrder) GetSignature() []byte {
 	return nil
 }
 
+func (m *SignedLimitOrder) GetHeader() *SignatureHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
 type LimitOrder struct {
-	PeerID   string                     `protobuf:"bytes,1,opt,name=peerID" json:"peerID,omitempty"`
-	BuyBTC   bool                       `protobuf:"varint,2,opt,name=buyBTC" json:"buyBTC,omitempty"`
-	Quantity uint64                     `protobuf:"varint,3,opt,name=quantity" json:"quantity,omitempty"`
-	Price    uint64                     `protobuf:"varint,4,opt,name=price" json:"price,omitempty"`
-	Utxo     *LimitOrder_SignedUTXO     `protobuf:"bytes,5,opt,name=utxo" json:"utxo,omitempty"`
-	Expiry   *google_protobuf.Timestamp `protobuf:"bytes,6,opt,name=expiry" json:"expiry,omitempty"`
+	PeerID      string                     `protobuf:"bytes,1,opt,name=peerID" json:"peerID,omitempty"`
+	BuyBTC      bool                       `protobuf:"varint,2,opt,name=buyBTC" json:"buyBTC,omitempty"`
+	Quantity    uint64                     `protobuf:"varint,3,opt,name=quantity" json:"quantity,omitempty"`
+	Price       uint64                     `protobuf:"varint,4,opt,name=price" json:"price,omitempty"`
+	Utxo        *LimitOrder_SignedUTXO     `protobuf:"bytes,5,opt,name=utxo" json:"utxo,omitempty"`
+	Expiry      *google_protobuf.Timestamp `protobuf:"bytes,6,opt,name=expiry" json:"expiry,omitempty"`
+	MinQuantity uint64                     `protobuf:"varint,7,opt,name=minQuantity" json:"minQuantity,omitempty"`
 }
 
 func (m *LimitOrder) Reset()                    { *m = LimitOrder{} }
 func (m *LimitOrder) String() string            { return proto.CompactTextString(m) }
 func (*LimitOrder) ProtoMessage()               {}
-func (*LimitOrder) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*LimitOrder) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{2} }
 
 func (m *LimitOrder) GetPeerID() string {
 	if m != nil {
@@ -112,6 +158,13 @@ func (m *LimitOrder) GetExpiry() *google_protobuf.Timestamp {
 	return nil
 }
 
+func (m *LimitOrder) GetMinQuantity() uint64 {
+	if m != nil {
+		return m.MinQuantity
+	}
+	return 0
+}
+
 type LimitOrder_SignedUTXO struct {
 	Outpoint  []byte `protobuf:"bytes,1,opt,name=outpoint,proto3" json:"outpoint,omitempty"`
 	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
@@ -120,7 +173,7 @@ type LimitOrder_SignedUTXO struct {
 func (m *LimitOrder_SignedUTXO) Reset()                    { *m = LimitOrder_SignedUTXO{} }
 func (m *LimitOrder_SignedUTXO) String() string            { return proto.CompactTextString(m) }
 func (*LimitOrder_SignedUTXO) ProtoMessage()               {}
-func (*LimitOrder_SignedUTXO) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1, 0} }
+func (*LimitOrder_SignedUTXO) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{2, 0} }
 
 func (m *LimitOrder_SignedUTXO) GetOutpoint() []byte {
 	if m != nil {
@@ -136,15 +189,139 @@ func (m *LimitOrder_SignedUTXO) GetSignature() []byte {
 	return nil
 }
 
+// OrderUpdate is published by the maker whenever the remaining quantity of an order
+// changes, either because a swap against it completed or the maker amended it.
+type OrderUpdate struct {
+	OrderID   string                 `protobuf:"bytes,1,opt,name=orderID" json:"orderID,omitempty"`
+	Type      OrderUpdate_UpdateType `protobuf:"varint,2,opt,name=type,enum=OrderUpdate_UpdateType" json:"type,omitempty"`
+	Remaining uint64                 `protobuf:"varint,3,opt,name=remaining" json:"remaining,omitempty"`
+	Sequence  uint64                 `protobuf:"varint,4,opt,name=sequence" json:"sequence,omitempty"`
+	SwapID    string                 `protobuf:"bytes,5,opt,name=swapID" json:"swapID,omitempty"`
+}
+
+func (m *OrderUpdate) Reset()                    { *m = OrderUpdate{} }
+func (m *OrderUpdate) String() string            { return proto.CompactTextString(m) }
+func (*OrderUpdate) ProtoMessage()               {}
+func (*OrderUpdate) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{3} }
+
+func (m *OrderUpdate) GetOrderID() string {
+	if m != nil {
+		return m.OrderID
+	}
+	return ""
+}
+
+func (m *OrderUpdate) GetType() OrderUpdate_UpdateType {
+	if m != nil {
+		return m.Type
+	}
+	return OrderUpdate_Fill
+}
+
+func (m *OrderUpdate) GetRemaining() uint64 {
+	if m != nil {
+		return m.Remaining
+	}
+	return 0
+}
+
+func (m *OrderUpdate) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *OrderUpdate) GetSwapID() string {
+	if m != nil {
+		return m.SwapID
+	}
+	return ""
+}
+
+type SignedOrderUpdate struct {
+	SerializedOrderUpdate []byte           `protobuf:"bytes,1,opt,name=serializedOrderUpdate,proto3" json:"serializedOrderUpdate,omitempty"`
+	Signature             []byte           `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	Header                *SignatureHeader `protobuf:"bytes,3,opt,name=header" json:"header,omitempty"`
+}
+
+func (m *SignedOrderUpdate) Reset()                    { *m = SignedOrderUpdate{} }
+func (m *SignedOrderUpdate) String() string            { return proto.CompactTextString(m) }
+func (*SignedOrderUpdate) ProtoMessage()               {}
+func (*SignedOrderUpdate) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{4} }
+
+func (m *SignedOrderUpdate) GetSerializedOrderUpdate() []byte {
+	if m != nil {
+		return m.SerializedOrderUpdate
+	}
+	return nil
+}
+
+func (m *SignedOrderUpdate) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *SignedOrderUpdate) GetHeader() *SignatureHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+// OrderRecord is how an order in our order book is stored in the datastore.
+type OrderRecord struct {
+	Order    *SignedLimitOrder `protobuf:"bytes,1,opt,name=order" json:"order,omitempty"`
+	Update   []byte            `protobuf:"bytes,2,opt,name=update,proto3" json:"update,omitempty"`
+	Mine     bool              `protobuf:"varint,3,opt,name=mine" json:"mine,omitempty"`
+	Received int64             `protobuf:"varint,4,opt,name=received" json:"received,omitempty"`
+}
+
+func (m *OrderRecord) Reset()                    { *m = OrderRecord{} }
+func (m *OrderRecord) String() string            { return proto.CompactTextString(m) }
+func (*OrderRecord) ProtoMessage()               {}
+func (*OrderRecord) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{5} }
+
+func (m *OrderRecord) GetOrder() *SignedLimitOrder {
+	if m != nil {
+		return m.Order
+	}
+	return nil
+}
+
+func (m *OrderRecord) GetUpdate() []byte {
+	if m != nil {
+		return m.Update
+	}
+	return nil
+}
+
+func (m *OrderRecord) GetMine() bool {
+	if m != nil {
+		return m.Mine
+	}
+	return false
+}
+
+func (m *OrderRecord) GetReceived() int64 {
+	if m != nil {
+		return m.Received
+	}
+	return 0
+}
+
 type SignedRemoveOrder struct {
-	OrderID   string `protobuf:"bytes,1,opt,name=orderID" json:"orderID,omitempty"`
-	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	OrderID   string           `protobuf:"bytes,1,opt,name=orderID" json:"orderID,omitempty"`
+	Signature []byte           `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	Header    *SignatureHeader `protobuf:"bytes,3,opt,name=header" json:"header,omitempty"`
 }
 
 func (m *SignedRemoveOrder) Reset()                    { *m = SignedRemoveOrder{} }
 func (m *SignedRemoveOrder) String() string            { return proto.CompactTextString(m) }
 func (*SignedRemoveOrder) ProtoMessage()               {}
-func (*SignedRemoveOrder) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*SignedRemoveOrder) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{6} }
 
 func (m *SignedRemoveOrder) GetOrderID() string {
 	if m != nil {
@@ -160,35 +337,118 @@ func (m *SignedRemoveOrder) GetSignature() []byte {
 	return nil
 }
 
+func (m *SignedRemoveOrder) GetHeader() *SignatureHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+// TombstoneRecord is how a close is stored in the datastore. We keep closes until
+// the order expires so the order can't be added back. Orders which were filled
+// without being closed keep the update that filled them instead.
+type TombstoneRecord struct {
+	Close    *SignedRemoveOrder `protobuf:"bytes,1,opt,name=close" json:"close,omitempty"`
+	Expiry   int64              `protobuf:"varint,2,opt,name=expiry" json:"expiry,omitempty"`
+	Verified bool               `protobuf:"varint,3,opt,name=verified" json:"verified,omitempty"`
+	Fill     *SignedOrderUpdate `protobuf:"bytes,4,opt,name=fill" json:"fill,omitempty"`
+	PeerID   string             `protobuf:"bytes,5,opt,name=peerID" json:"peerID,omitempty"`
+}
+
+func (m *TombstoneRecord) Reset()                    { *m = TombstoneRecord{} }
+func (m *TombstoneRecord) String() string            { return proto.CompactTextString(m) }
+func (*TombstoneRecord) ProtoMessage()               {}
+func (*TombstoneRecord) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{7} }
+
+func (m *TombstoneRecord) GetClose() *SignedRemoveOrder {
+	if m != nil {
+		return m.Close
+	}
+	return nil
+}
+
+func (m *TombstoneRecord) GetExpiry() int64 {
+	if m != nil {
+		return m.Expiry
+	}
+	return 0
+}
+
+func (m *TombstoneRecord) GetVerified() bool {
+	if m != nil {
+		return m.Verified
+	}
+	return false
+}
+
+func (m *TombstoneRecord) GetFill() *SignedOrderUpdate {
+	if m != nil {
+		return m.Fill
+	}
+	return nil
+}
+
+func (m *TombstoneRecord) GetPeerID() string {
+	if m != nil {
+		return m.PeerID
+	}
+	return ""
+}
+
 func init() {
+	proto.RegisterType((*SignatureHeader)(nil), "SignatureHeader")
 	proto.RegisterType((*SignedLimitOrder)(nil), "SignedLimitOrder")
 	proto.RegisterType((*LimitOrder)(nil), "LimitOrder")
 	proto.RegisterType((*LimitOrder_SignedUTXO)(nil), "LimitOrder.SignedUTXO")
+	proto.RegisterType((*OrderUpdate)(nil), "OrderUpdate")
+	proto.RegisterType((*SignedOrderUpdate)(nil), "SignedOrderUpdate")
+	proto.RegisterType((*OrderRecord)(nil), "OrderRecord")
 	proto.RegisterType((*SignedRemoveOrder)(nil), "SignedRemoveOrder")
+	proto.RegisterType((*TombstoneRecord)(nil), "TombstoneRecord")
+	proto.RegisterEnum("OrderUpdate_UpdateType", OrderUpdate_UpdateType_name, OrderUpdate_UpdateType_value)
 }
 
-func init() { proto.RegisterFile("atomicswaps.proto", fileDescriptor0) }
-
-var fileDescriptor0 = []byte{
-	// 306 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x90, 0x41, 0x4b, 0xf3, 0x40,
-	0x10, 0x86, 0x49, 0xbf, 0x34, 0x5f, 0x3b, 0x7a, 0xb0, 0xa1, 0x94, 0x25, 0x08, 0x96, 0x9e, 0x8a,
-	0x87, 0x14, 0xea, 0x3f, 0xa8, 0x22, 0x88, 0x42, 0x61, 0xad, 0x20, 0xde, 0x36, 0xed, 0x18, 0x06,
-	0x9a, 0xec, 0xba, 0xd9, 0xd5, 0xd6, 0x1f, 0xe2, 0xef, 0x95, 0xee, 0xa6, 0x4d, 0x0f, 0xa2, 0xb7,
-	0x3c, 0x33, 0x2f, 0x6f, 0x66, 0x1f, 0xe8, 0x09, 0x23, 0x0b, 0x5a, 0x56, 0x1f, 0x42, 0x55, 0xa9,
-	0xd2, 0xd2, 0xc8, 0xe4, 0x22, 0x97, 0x32, 0x5f, 0xe3, 0xc4, 0x51, 0x66, 0x5f, 0x27, 0x86, 0x0a,
-	0xac, 0x8c, 0x28, 0x94, 0x0f, 0x8c, 0x56, 0x70, 0xf6, 0x48, 0x79, 0x89, 0xab, 0x07, 0x2a, 0xc8,
-	0xcc, 0xf5, 0x0a, 0x75, 0x3c, 0x85, 0x7e, 0x85, 0x9a, 0xc4, 0x9a, 0x3e, 0x8f, 0xe7, 0x2c, 0x18,
-	0x06, 0xe3, 0x53, 0xfe, 0xe3, 0x2e, 0x3e, 0x87, 0x6e, 0x45, 0x79, 0x29, 0x8c, 0xd5, 0xc8, 0x5a,
-	0x2e, 0xd8, 0x0c, 0x46, 0x5f, 0x2d, 0x80, 0xa3, 0xf0, 0x00, 0x22, 0x85, 0xa8, 0xef, 0x6e, 0x5c,
-	0x65, 0x97, 0xd7, 0xb4, 0x9b, 0x67, 0x76, 0x3b, 0x5b, 0x5c, 0xbb, 0x86, 0x0e, 0xaf, 0x29, 0x4e,
-	0xa0, 0xf3, 0x66, 0x45, 0x69, 0xc8, 0x6c, 0xd9, 0xbf, 0x61, 0x30, 0x0e, 0xf9, 0x81, 0xe3, 0x3e,
-	0xb4, 0x95, 0xa6, 0x25, 0xb2, 0xd0, 0x2d, 0x3c, 0xc4, 0x97, 0x10, 0x5a, 0xb3, 0x91, 0xac, 0x3d,
-	0x0c, 0xc6, 0x27, 0xd3, 0x41, 0xda, 0xfc, 0x3c, 0xf5, 0xcf, 0x7d, 0x5a, 0x3c, 0xcf, 0xb9, 0xcb,
-	0xc4, 0x53, 0x88, 0x70, 0xa3, 0x48, 0x6f, 0x59, 0xe4, 0xd2, 0x49, 0xea, 0xa5, 0xa5, 0x7b, 0x69,
-	0xe9, 0x62, 0x2f, 0x8d, 0xd7, 0xc9, 0xe4, 0x16, 0xa0, 0xe9, 0xd9, 0xdd, 0x27, 0xad, 0x51, 0x92,
-	0x4a, 0x53, 0x4b, 0x3a, 0xf0, 0x1f, 0x62, 0xee, 0xa1, 0xe7, 0x7b, 0x38, 0x16, 0xf2, 0x1d, 0xbd,
-	0x1e, 0x06, 0xff, 0xe5, 0xee, 0xe3, 0xe0, 0x67, 0x8f, 0xbf, 0x97, 0xcd, 0xc2, 0x97, 0x96, 0xca,
-	0xb2, 0xc8, 0x9d, 0x7d, 0xf5, 0x1d, 0x00, 0x00, 0xff, 0xff, 0x47, 0x2a, 0x08, 0x9c, 0x0e, 0x02,
-	0x00, 0x00,
+func init() { proto.RegisterFile("atomicswaps.proto", fileDescriptor1) }
+
+var fileDescriptor1 = []byte{
+	// 618 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x54, 0xcb, 0x6e, 0xd3, 0x4c,
+	0x14, 0xfe, 0x9d, 0x3a, 0x69, 0x7c, 0xd2, 0x9f, 0xa6, 0xa3, 0x52, 0xac, 0x08, 0x89, 0xe0, 0x05,
+	0x44, 0x20, 0xb9, 0x52, 0x60, 0xc1, 0x96, 0x52, 0x55, 0x54, 0x42, 0xaa, 0x18, 0x52, 0x09, 0xb1,
+	0x73, 0xe2, 0xd3, 0x30, 0xc2, 0xf6, 0xb8, 0xe3, 0x71, 0xda, 0xb0, 0x80, 0x37, 0x40, 0xe2, 0x49,
+	0xd8, 0xf2, 0x0a, 0xbc, 0x15, 0x9a, 0x8b, 0x2f, 0x94, 0x02, 0x9b, 0xae, 0xda, 0xef, 0x9c, 0x93,
+	0xf9, 0x2e, 0x33, 0xc7, 0xb0, 0x13, 0x49, 0x9e, 0xb2, 0x45, 0x71, 0x11, 0xe5, 0x45, 0x98, 0x0b,
+	0x2e, 0xf9, 0xe8, 0xde, 0x92, 0xf3, 0x65, 0x82, 0xfb, 0x1a, 0xcd, 0xcb, 0xb3, 0x7d, 0xc9, 0x52,
+	0x2c, 0x64, 0x94, 0xe6, 0x66, 0x20, 0xf8, 0x0c, 0xdb, 0x6f, 0xd8, 0x32, 0x8b, 0x64, 0x29, 0xf0,
+	0x25, 0x46, 0x31, 0x0a, 0xe2, 0xc3, 0xe6, 0x0a, 0x45, 0xc1, 0x78, 0xe6, 0x3b, 0x63, 0x67, 0xf2,
+	0x3f, 0xad, 0xa0, 0xea, 0x64, 0x28, 0x2f, 0xb8, 0xf8, 0xe0, 0x77, 0xc6, 0xce, 0xc4, 0xa3, 0x15,
+	0x24, 0xcf, 0xc0, 0xab, 0x4f, 0xf6, 0x37, 0xc6, 0xce, 0x64, 0x30, 0x1d, 0x85, 0x86, 0x3b, 0xac,
+	0xb8, 0xc3, 0x59, 0x35, 0x41, 0x9b, 0xe1, 0xe0, 0x8b, 0x03, 0x43, 0xa5, 0x00, 0xe3, 0x57, 0x2c,
+	0x65, 0xf2, 0x44, 0x28, 0x09, 0x53, 0xd8, 0x2d, 0x50, 0xb0, 0x28, 0x61, 0x1f, 0xdb, 0x75, 0xad,
+	0x67, 0x8b, 0x5e, 0xdb, 0x23, 0x77, 0xc1, 0x2b, 0x2a, 0x27, 0x5a, 0xde, 0x16, 0x6d, 0x0a, 0x64,
+	0x02, 0xbd, 0xf7, 0xda, 0x9e, 0x55, 0x37, 0x0c, 0xaf, 0xd8, 0xa6, 0xb6, 0x1f, 0x7c, 0xef, 0x00,
+	0xb4, 0x8e, 0xdd, 0x83, 0x5e, 0x8e, 0x28, 0x8e, 0x0f, 0x35, 0xb9, 0x47, 0x2d, 0x52, 0xf5, 0x79,
+	0xb9, 0x3e, 0x98, 0xbd, 0xd0, 0x5c, 0x7d, 0x6a, 0x11, 0x19, 0x41, 0xff, 0xbc, 0x8c, 0x32, 0xc9,
+	0xe4, 0x5a, 0x53, 0xb9, 0xb4, 0xc6, 0x64, 0x17, 0xba, 0xb9, 0x60, 0x0b, 0xf4, 0x5d, 0xdd, 0x30,
+	0x80, 0x3c, 0x02, 0xb7, 0x94, 0x97, 0xdc, 0xef, 0x6a, 0x61, 0x7b, 0x61, 0x43, 0x1e, 0x9a, 0x60,
+	0x4e, 0x67, 0x6f, 0x4f, 0xa8, 0x9e, 0x21, 0x53, 0xe8, 0xe1, 0x65, 0xce, 0xc4, 0xda, 0xef, 0xfd,
+	0x33, 0x64, 0x3b, 0x49, 0xc6, 0x30, 0x48, 0x59, 0xf6, 0xba, 0x12, 0xb5, 0xa9, 0xb9, 0xdb, 0xa5,
+	0xd1, 0x11, 0x40, 0xc3, 0xa4, 0x1c, 0xf0, 0x52, 0xe6, 0x9c, 0x65, 0xd2, 0x06, 0x5e, 0xe3, 0xbf,
+	0x87, 0x1c, 0xfc, 0x70, 0x60, 0xa0, 0x85, 0x9f, 0xe6, 0x71, 0x24, 0x51, 0xbd, 0x17, 0x2e, 0xe2,
+	0x56, 0x78, 0x15, 0x24, 0x8f, 0xc1, 0x95, 0xeb, 0xdc, 0x1c, 0x71, 0x6b, 0x7a, 0x27, 0x6c, 0xfd,
+	0x2a, 0x34, 0x7f, 0x66, 0xeb, 0x1c, 0xa9, 0x1e, 0x52, 0xa4, 0x02, 0xd3, 0x88, 0x65, 0x2c, 0x5b,
+	0xda, 0x4c, 0x9b, 0x82, 0x92, 0x5b, 0xe0, 0x79, 0x89, 0x59, 0x9d, 0x6b, 0x8d, 0xd5, 0x25, 0xa9,
+	0x6d, 0x38, 0x3e, 0xd4, 0xe1, 0x7a, 0xd4, 0xa2, 0xe0, 0x3e, 0x40, 0xc3, 0x42, 0xfa, 0xe0, 0x1e,
+	0xb1, 0x24, 0x19, 0xfe, 0x47, 0x3c, 0xe8, 0x3e, 0x4f, 0x31, 0x8b, 0x87, 0x4e, 0xf0, 0xd5, 0x81,
+	0x1d, 0x13, 0x4a, 0xdb, 0xd1, 0x53, 0xb8, 0xdd, 0x3c, 0xbe, 0x56, 0xc3, 0x06, 0x75, 0x7d, 0xf3,
+	0xc6, 0x9e, 0xe6, 0x27, 0x1b, 0x2f, 0xc5, 0x05, 0x17, 0x31, 0x79, 0x08, 0x5d, 0x5e, 0xaf, 0xc5,
+	0x60, 0xba, 0x13, 0x5e, 0xdd, 0x23, 0x6a, 0xfa, 0x2a, 0x86, 0xd2, 0xc8, 0x34, 0xe4, 0x16, 0x11,
+	0x02, 0x6e, 0xca, 0x32, 0xd4, 0xbc, 0x7d, 0xaa, 0xff, 0x57, 0x71, 0x0a, 0x5c, 0x20, 0x5b, 0x61,
+	0xac, 0xe3, 0xdc, 0xa0, 0x35, 0x0e, 0xca, 0x2a, 0x12, 0x8a, 0x29, 0x5f, 0xa1, 0x59, 0x90, 0x3f,
+	0x5f, 0xf2, 0x4d, 0xd9, 0xfe, 0xe6, 0xc0, 0xf6, 0x8c, 0xa7, 0xf3, 0x42, 0xf2, 0x0c, 0xad, 0xf7,
+	0x09, 0x74, 0x17, 0x09, 0x2f, 0xd0, 0x7a, 0x27, 0xe1, 0x6f, 0xc2, 0xa8, 0x19, 0x50, 0xe6, 0xed,
+	0xca, 0x74, 0xb4, 0x1d, 0x8b, 0x94, 0xd1, 0x15, 0x0a, 0x76, 0xc6, 0x30, 0xb6, 0x01, 0xd4, 0x98,
+	0x3c, 0x00, 0xf7, 0x8c, 0x25, 0x89, 0xef, 0xfe, 0x72, 0x78, 0xeb, 0x4a, 0xa9, 0xee, 0xb7, 0x3e,
+	0x0e, 0xdd, 0xf6, 0xc7, 0xe1, 0xc0, 0x7d, 0xd7, 0xc9, 0xe7, 0xf3, 0x9e, 0x5e, 0xca, 0x27, 0x3f,
+	0x07, 0x00, 0x44, 0xa5, 0x55, 0x4c, 0x98, 0x05, 0x00, 0x00,
 }
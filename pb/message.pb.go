@@ -16,29 +16,59 @@ var _ = math.Inf
 type Message_MessageType int32
 
 const (
-	Message_LimitOrder   Message_MessageType = 0
-	Message_OrderClose   Message_MessageType = 1
-	Message_MarketOrder  Message_MessageType = 2
-	Message_GetOrderBook Message_MessageType = 3
+	Message_LimitOrder      Message_MessageType = 0
+	Message_OrderClose      Message_MessageType = 1
+	Message_MarketOrder     Message_MessageType = 2
+	Message_GetOrderBook    Message_MessageType = 3
+	Message_SwapAccept      Message_MessageType = 4
+	Message_SwapReject      Message_MessageType = 5
+	Message_InitiateSwap    Message_MessageType = 6
+	Message_ParticipateSwap Message_MessageType = 7
+	Message_RedeemSwap      Message_MessageType = 8
+	Message_OrderUpdate     Message_MessageType = 9
+	Message_SyncDigest      Message_MessageType = 10
+	Message_SyncInventory   Message_MessageType = 11
+	Message_GetOrders       Message_MessageType = 12
+	Message_Orders          Message_MessageType = 13
 )
 
 var Message_MessageType_name = map[int32]string{
-	0: "LimitOrder",
-	1: "OrderClose",
-	2: "MarketOrder",
-	3: "GetOrderBook",
+	0:  "LimitOrder",
+	1:  "OrderClose",
+	2:  "MarketOrder",
+	3:  "GetOrderBook",
+	4:  "SwapAccept",
+	5:  "SwapReject",
+	6:  "InitiateSwap",
+	7:  "ParticipateSwap",
+	8:  "RedeemSwap",
+	9:  "OrderUpdate",
+	10: "SyncDigest",
+	11: "SyncInventory",
+	12: "GetOrders",
+	13: "Orders",
 }
 var Message_MessageType_value = map[string]int32{
-	"LimitOrder":   0,
-	"OrderClose":   1,
-	"MarketOrder":  2,
-	"GetOrderBook": 3,
+	"LimitOrder":      0,
+	"OrderClose":      1,
+	"MarketOrder":     2,
+	"GetOrderBook":    3,
+	"SwapAccept":      4,
+	"SwapReject":      5,
+	"InitiateSwap":    6,
+	"ParticipateSwap": 7,
+	"RedeemSwap":      8,
+	"OrderUpdate":     9,
+	"SyncDigest":      10,
+	"SyncInventory":   11,
+	"GetOrders":       12,
+	"Orders":          13,
 }
 
 func (x Message_MessageType) String() string {
 	return proto.EnumName(Message_MessageType_name, int32(x))
 }
-func (Message_MessageType) EnumDescriptor() ([]byte, []int) { return fileDescriptor1, []int{0, 0} }
+func (Message_MessageType) EnumDescriptor() ([]byte, []int) { return fileDescriptor2, []int{0, 0} }
 
 type Message struct {
 	MessageType Message_MessageType   `protobuf:"varint,1,opt,name=messageType,enum=Message_MessageType" json:"messageType,omitempty"`
@@ -48,7 +78,7 @@ type Message struct {
 func (m *Message) Reset()                    { *m = Message{} }
 func (m *Message) String() string            { return proto.CompactTextString(m) }
 func (*Message) ProtoMessage()               {}
-func (*Message) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{0} }
+func (*Message) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{0} }
 
 func (m *Message) GetMessageType() Message_MessageType {
 	if m != nil {
@@ -69,21 +99,27 @@ func init() {
 	proto.RegisterEnum("Message_MessageType", Message_MessageType_name, Message_MessageType_value)
 }
 
-func init() { proto.RegisterFile("message.proto", fileDescriptor1) }
+func init() { proto.RegisterFile("message.proto", fileDescriptor2) }
 
-var fileDescriptor1 = []byte{
-	// 195 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0xcd, 0x4d, 0x2d, 0x2e,
-	0x4e, 0x4c, 0x4f, 0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x97, 0x92, 0x4c, 0xcf, 0xcf, 0x4f, 0xcf,
-	0x49, 0xd5, 0x07, 0xf3, 0x92, 0x4a, 0xd3, 0xf4, 0x13, 0xf3, 0x2a, 0x21, 0x52, 0x4a, 0x87, 0x19,
-	0xb9, 0xd8, 0x7d, 0x21, 0x8a, 0x85, 0xcc, 0xb8, 0xb8, 0xa1, 0xfa, 0x42, 0x2a, 0x0b, 0x52, 0x25,
-	0x18, 0x15, 0x18, 0x35, 0xf8, 0x8c, 0x44, 0xf4, 0xa0, 0xd2, 0x30, 0x1a, 0x24, 0x17, 0x84, 0xac,
-	0x50, 0x48, 0x8f, 0x8b, 0xbd, 0x20, 0xb1, 0x32, 0x27, 0x3f, 0x31, 0x45, 0x82, 0x49, 0x81, 0x51,
-	0x83, 0xdb, 0x48, 0x44, 0x0f, 0x62, 0xa1, 0x1e, 0xcc, 0x42, 0x3d, 0xc7, 0xbc, 0xca, 0x20, 0x98,
-	0x22, 0xa5, 0x00, 0x2e, 0x6e, 0x24, 0xb3, 0x84, 0xf8, 0xb8, 0xb8, 0x7c, 0x32, 0x73, 0x33, 0x4b,
-	0xfc, 0x8b, 0x52, 0x52, 0x8b, 0x04, 0x18, 0x40, 0x7c, 0x30, 0xd3, 0x39, 0x27, 0xbf, 0x38, 0x55,
-	0x80, 0x51, 0x88, 0x9f, 0x8b, 0xdb, 0x37, 0xb1, 0x28, 0x3b, 0x15, 0xaa, 0x80, 0x49, 0x48, 0x80,
-	0x8b, 0xc7, 0x1d, 0xca, 0x73, 0xca, 0xcf, 0xcf, 0x16, 0x60, 0x76, 0x62, 0x89, 0x62, 0x2a, 0x48,
-	0x4a, 0x62, 0x03, 0x5b, 0x67, 0x0c, 0x08, 0x00, 0x00, 0xff, 0xff, 0x14, 0xfa, 0xe3, 0x7b, 0xfe,
-	0x00, 0x00, 0x00,
+var fileDescriptor2 = []byte{
+	// 296 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x90, 0x41, 0x4f, 0x3a, 0x31,
+	0x10, 0xc5, 0xff, 0xbb, 0x7f, 0x04, 0x99, 0x65, 0xa1, 0x56, 0x0e, 0xe8, 0x89, 0x70, 0xe2, 0x54,
+	0x12, 0x4c, 0xbc, 0x83, 0x26, 0x86, 0x44, 0xa2, 0x59, 0xf5, 0xe2, 0xad, 0xec, 0x8e, 0x9b, 0x0a,
+	0xb4, 0x4d, 0xb7, 0x6a, 0xfa, 0x95, 0xfd, 0x00, 0x9e, 0x4d, 0xcb, 0x6e, 0xe4, 0x36, 0xbf, 0x79,
+	0xef, 0xcd, 0x6b, 0x0a, 0xe9, 0x1e, 0xab, 0x8a, 0x97, 0xc8, 0xb4, 0x51, 0x56, 0x5d, 0x5e, 0x94,
+	0x4a, 0x95, 0x3b, 0x9c, 0x05, 0xda, 0x7c, 0xbc, 0xcd, 0xb8, 0x74, 0x07, 0x69, 0xf2, 0x1d, 0x43,
+	0x67, 0x7d, 0x30, 0xd3, 0x6b, 0x48, 0xea, 0xdc, 0xb3, 0xd3, 0x38, 0x8a, 0xc6, 0xd1, 0xb4, 0x3f,
+	0x1f, 0xb2, 0x5a, 0x66, 0xeb, 0x3f, 0x2d, 0x3b, 0x36, 0x52, 0x06, 0x1d, 0xcd, 0xdd, 0x4e, 0xf1,
+	0x62, 0x14, 0x8f, 0xa3, 0x69, 0x32, 0x1f, 0xb2, 0x43, 0x21, 0x6b, 0x0a, 0xd9, 0x42, 0xba, 0xac,
+	0x31, 0x4d, 0x7e, 0x22, 0x48, 0x8e, 0x8e, 0xd1, 0x3e, 0xc0, 0xbd, 0xd8, 0x0b, 0xfb, 0x60, 0x0a,
+	0x34, 0xe4, 0x9f, 0xe7, 0x30, 0xde, 0xec, 0x54, 0x85, 0x24, 0xa2, 0x03, 0x48, 0xd6, 0xdc, 0x6c,
+	0xb1, 0x36, 0xc4, 0x94, 0x40, 0xef, 0xae, 0xa6, 0xa5, 0x52, 0x5b, 0xf2, 0xdf, 0x47, 0x9e, 0xbe,
+	0xb8, 0x5e, 0xe4, 0x39, 0x6a, 0x4b, 0x5a, 0x0d, 0x67, 0xf8, 0x8e, 0xb9, 0x25, 0x27, 0x3e, 0xb1,
+	0x92, 0xc2, 0x0a, 0x6e, 0xd1, 0xef, 0x49, 0x9b, 0x9e, 0xc3, 0xe0, 0x91, 0x1b, 0x2b, 0x72, 0xa1,
+	0x9b, 0x65, 0xc7, 0xc7, 0x32, 0x2c, 0x10, 0xf7, 0x81, 0x4f, 0x7d, 0x73, 0x68, 0x79, 0xd1, 0x05,
+	0xb7, 0x48, 0xba, 0xe1, 0xae, 0x93, 0xf9, 0xad, 0x28, 0xb1, 0xb2, 0x04, 0xe8, 0x19, 0xa4, 0x9e,
+	0x57, 0xf2, 0x13, 0xa5, 0x55, 0xc6, 0x91, 0x84, 0xa6, 0xd0, 0x6d, 0x1e, 0x57, 0x91, 0x1e, 0x05,
+	0x68, 0xd7, 0x73, 0xba, 0x6c, 0xbd, 0xc6, 0x7a, 0xb3, 0x69, 0x87, 0x5f, 0xb9, 0xfa, 0x1d, 0x00,
+	0xfd, 0x01, 0xf9, 0x85, 0xa5, 0x01, 0x00, 0x00,
 }
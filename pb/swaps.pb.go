@@ -0,0 +1,404 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: swaps.proto
+
+package pb
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+type MarketOrder struct {
+	OrderID    string `protobuf:"bytes,1,opt,name=orderID" json:"orderID,omitempty"`
+	SecretHash []byte `protobuf:"bytes,2,opt,name=secretHash,proto3" json:"secretHash,omitempty"`
+	RedeemHash []byte `protobuf:"bytes,3,opt,name=redeemHash,proto3" json:"redeemHash,omitempty"`
+	Quantity   uint64 `protobuf:"varint,4,opt,name=quantity" json:"quantity,omitempty"`
+}
+
+func (m *MarketOrder) Reset()                    { *m = MarketOrder{} }
+func (m *MarketOrder) String() string            { return proto.CompactTextString(m) }
+func (*MarketOrder) ProtoMessage()               {}
+func (*MarketOrder) Descriptor() ([]byte, []int) { return fileDescriptor4, []int{0} }
+
+func (m *MarketOrder) GetOrderID() string {
+	if m != nil {
+		return m.OrderID
+	}
+	return ""
+}
+
+func (m *MarketOrder) GetSecretHash() []byte {
+	if m != nil {
+		return m.SecretHash
+	}
+	return nil
+}
+
+func (m *MarketOrder) GetRedeemHash() []byte {
+	if m != nil {
+		return m.RedeemHash
+	}
+	return nil
+}
+
+func (m *MarketOrder) GetQuantity() uint64 {
+	if m != nil {
+		return m.Quantity
+	}
+	return 0
+}
+
+type SwapAccept struct {
+	SwapID     string `protobuf:"bytes,1,opt,name=swapID" json:"swapID,omitempty"`
+	RedeemHash []byte `protobuf:"bytes,2,opt,name=redeemHash,proto3" json:"redeemHash,omitempty"`
+}
+
+func (m *SwapAccept) Reset()                    { *m = SwapAccept{} }
+func (m *SwapAccept) String() string            { return proto.CompactTextString(m) }
+func (*SwapAccept) ProtoMessage()               {}
+func (*SwapAccept) Descriptor() ([]byte, []int) { return fileDescriptor4, []int{1} }
+
+func (m *SwapAccept) GetSwapID() string {
+	if m != nil {
+		return m.SwapID
+	}
+	return ""
+}
+
+func (m *SwapAccept) GetRedeemHash() []byte {
+	if m != nil {
+		return m.RedeemHash
+	}
+	return nil
+}
+
+type SwapReject struct {
+	SwapID string `protobuf:"bytes,1,opt,name=swapID" json:"swapID,omitempty"`
+	Reason string `protobuf:"bytes,2,opt,name=reason" json:"reason,omitempty"`
+}
+
+func (m *SwapReject) Reset()                    { *m = SwapReject{} }
+func (m *SwapReject) String() string            { return proto.CompactTextString(m) }
+func (*SwapReject) ProtoMessage()               {}
+func (*SwapReject) Descriptor() ([]byte, []int) { return fileDescriptor4, []int{2} }
+
+func (m *SwapReject) GetSwapID() string {
+	if m != nil {
+		return m.SwapID
+	}
+	return ""
+}
+
+func (m *SwapReject) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+type SwapContract struct {
+	SwapID     string `protobuf:"bytes,1,opt,name=swapID" json:"swapID,omitempty"`
+	Contract   []byte `protobuf:"bytes,2,opt,name=contract,proto3" json:"contract,omitempty"`
+	ContractTx []byte `protobuf:"bytes,3,opt,name=contractTx,proto3" json:"contractTx,omitempty"`
+}
+
+func (m *SwapContract) Reset()                    { *m = SwapContract{} }
+func (m *SwapContract) String() string            { return proto.CompactTextString(m) }
+func (*SwapContract) ProtoMessage()               {}
+func (*SwapContract) Descriptor() ([]byte, []int) { return fileDescriptor4, []int{3} }
+
+func (m *SwapContract) GetSwapID() string {
+	if m != nil {
+		return m.SwapID
+	}
+	return ""
+}
+
+func (m *SwapContract) GetContract() []byte {
+	if m != nil {
+		return m.Contract
+	}
+	return nil
+}
+
+func (m *SwapContract) GetContractTx() []byte {
+	if m != nil {
+		return m.ContractTx
+	}
+	return nil
+}
+
+type SwapRedeem struct {
+	SwapID   string `protobuf:"bytes,1,opt,name=swapID" json:"swapID,omitempty"`
+	RedeemTx []byte `protobuf:"bytes,2,opt,name=redeemTx,proto3" json:"redeemTx,omitempty"`
+}
+
+func (m *SwapRedeem) Reset()                    { *m = SwapRedeem{} }
+func (m *SwapRedeem) String() string            { return proto.CompactTextString(m) }
+func (*SwapRedeem) ProtoMessage()               {}
+func (*SwapRedeem) Descriptor() ([]byte, []int) { return fileDescriptor4, []int{4} }
+
+func (m *SwapRedeem) GetSwapID() string {
+	if m != nil {
+		return m.SwapID
+	}
+	return ""
+}
+
+func (m *SwapRedeem) GetRedeemTx() []byte {
+	if m != nil {
+		return m.RedeemTx
+	}
+	return nil
+}
+
+type SwapRecord struct {
+	SwapID                 string `protobuf:"bytes,1,opt,name=swapID" json:"swapID,omitempty"`
+	OrderID                string `protobuf:"bytes,2,opt,name=orderID" json:"orderID,omitempty"`
+	Role                   uint32 `protobuf:"varint,3,opt,name=role" json:"role,omitempty"`
+	State                  uint32 `protobuf:"varint,4,opt,name=state" json:"state,omitempty"`
+	Counterparty           string `protobuf:"bytes,5,opt,name=counterparty" json:"counterparty,omitempty"`
+	SendCoin               uint32 `protobuf:"varint,6,opt,name=sendCoin" json:"sendCoin,omitempty"`
+	ReceiveCoin            uint32 `protobuf:"varint,7,opt,name=receiveCoin" json:"receiveCoin,omitempty"`
+	SendAmount             int64  `protobuf:"varint,8,opt,name=sendAmount" json:"sendAmount,omitempty"`
+	ReceiveAmount          int64  `protobuf:"varint,9,opt,name=receiveAmount" json:"receiveAmount,omitempty"`
+	Secret                 []byte `protobuf:"bytes,10,opt,name=secret,proto3" json:"secret,omitempty"`
+	SecretHash             []byte `protobuf:"bytes,11,opt,name=secretHash,proto3" json:"secretHash,omitempty"`
+	RedeemKey              []byte `protobuf:"bytes,12,opt,name=redeemKey,proto3" json:"redeemKey,omitempty"`
+	RefundKey              []byte `protobuf:"bytes,13,opt,name=refundKey,proto3" json:"refundKey,omitempty"`
+	CounterpartyRedeemHash []byte `protobuf:"bytes,14,opt,name=counterpartyRedeemHash,proto3" json:"counterpartyRedeemHash,omitempty"`
+	Contract               []byte `protobuf:"bytes,15,opt,name=contract,proto3" json:"contract,omitempty"`
+	ContractTx             []byte `protobuf:"bytes,16,opt,name=contractTx,proto3" json:"contractTx,omitempty"`
+	LockTime               int64  `protobuf:"varint,17,opt,name=lockTime" json:"lockTime,omitempty"`
+	CounterpartyContract   []byte `protobuf:"bytes,18,opt,name=counterpartyContract,proto3" json:"counterpartyContract,omitempty"`
+	CounterpartyContractTx []byte `protobuf:"bytes,19,opt,name=counterpartyContractTx,proto3" json:"counterpartyContractTx,omitempty"`
+	CounterpartyLockTime   int64  `protobuf:"varint,20,opt,name=counterpartyLockTime" json:"counterpartyLockTime,omitempty"`
+	RedeemTx               []byte `protobuf:"bytes,21,opt,name=redeemTx,proto3" json:"redeemTx,omitempty"`
+	RefundTx               []byte `protobuf:"bytes,22,opt,name=refundTx,proto3" json:"refundTx,omitempty"`
+	RefundError            string `protobuf:"bytes,23,opt,name=refundError" json:"refundError,omitempty"`
+	Created                int64  `protobuf:"varint,24,opt,name=created" json:"created,omitempty"`
+}
+
+func (m *SwapRecord) Reset()                    { *m = SwapRecord{} }
+func (m *SwapRecord) String() string            { return proto.CompactTextString(m) }
+func (*SwapRecord) ProtoMessage()               {}
+func (*SwapRecord) Descriptor() ([]byte, []int) { return fileDescriptor4, []int{5} }
+
+func (m *SwapRecord) GetSwapID() string {
+	if m != nil {
+		return m.SwapID
+	}
+	return ""
+}
+
+func (m *SwapRecord) GetOrderID() string {
+	if m != nil {
+		return m.OrderID
+	}
+	return ""
+}
+
+func (m *SwapRecord) GetRole() uint32 {
+	if m != nil {
+		return m.Role
+	}
+	return 0
+}
+
+func (m *SwapRecord) GetState() uint32 {
+	if m != nil {
+		return m.State
+	}
+	return 0
+}
+
+func (m *SwapRecord) GetCounterparty() string {
+	if m != nil {
+		return m.Counterparty
+	}
+	return ""
+}
+
+func (m *SwapRecord) GetSendCoin() uint32 {
+	if m != nil {
+		return m.SendCoin
+	}
+	return 0
+}
+
+func (m *SwapRecord) GetReceiveCoin() uint32 {
+	if m != nil {
+		return m.ReceiveCoin
+	}
+	return 0
+}
+
+func (m *SwapRecord) GetSendAmount() int64 {
+	if m != nil {
+		return m.SendAmount
+	}
+	return 0
+}
+
+func (m *SwapRecord) GetReceiveAmount() int64 {
+	if m != nil {
+		return m.ReceiveAmount
+	}
+	return 0
+}
+
+func (m *SwapRecord) GetSecret() []byte {
+	if m != nil {
+		return m.Secret
+	}
+	return nil
+}
+
+func (m *SwapRecord) GetSecretHash() []byte {
+	if m != nil {
+		return m.SecretHash
+	}
+	return nil
+}
+
+func (m *SwapRecord) GetRedeemKey() []byte {
+	if m != nil {
+		return m.RedeemKey
+	}
+	return nil
+}
+
+func (m *SwapRecord) GetRefundKey() []byte {
+	if m != nil {
+		return m.RefundKey
+	}
+	return nil
+}
+
+func (m *SwapRecord) GetCounterpartyRedeemHash() []byte {
+	if m != nil {
+		return m.CounterpartyRedeemHash
+	}
+	return nil
+}
+
+func (m *SwapRecord) GetContract() []byte {
+	if m != nil {
+		return m.Contract
+	}
+	return nil
+}
+
+func (m *SwapRecord) GetContractTx() []byte {
+	if m != nil {
+		return m.ContractTx
+	}
+	return nil
+}
+
+func (m *SwapRecord) GetLockTime() int64 {
+	if m != nil {
+		return m.LockTime
+	}
+	return 0
+}
+
+func (m *SwapRecord) GetCounterpartyContract() []byte {
+	if m != nil {
+		return m.CounterpartyContract
+	}
+	return nil
+}
+
+func (m *SwapRecord) GetCounterpartyContractTx() []byte {
+	if m != nil {
+		return m.CounterpartyContractTx
+	}
+	return nil
+}
+
+func (m *SwapRecord) GetCounterpartyLockTime() int64 {
+	if m != nil {
+		return m.CounterpartyLockTime
+	}
+	return 0
+}
+
+func (m *SwapRecord) GetRedeemTx() []byte {
+	if m != nil {
+		return m.RedeemTx
+	}
+	return nil
+}
+
+func (m *SwapRecord) GetRefundTx() []byte {
+	if m != nil {
+		return m.RefundTx
+	}
+	return nil
+}
+
+func (m *SwapRecord) GetRefundError() string {
+	if m != nil {
+		return m.RefundError
+	}
+	return ""
+}
+
+func (m *SwapRecord) GetCreated() int64 {
+	if m != nil {
+		return m.Created
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*MarketOrder)(nil), "MarketOrder")
+	proto.RegisterType((*SwapAccept)(nil), "SwapAccept")
+	proto.RegisterType((*SwapReject)(nil), "SwapReject")
+	proto.RegisterType((*SwapContract)(nil), "SwapContract")
+	proto.RegisterType((*SwapRedeem)(nil), "SwapRedeem")
+	proto.RegisterType((*SwapRecord)(nil), "SwapRecord")
+}
+
+func init() { proto.RegisterFile("swaps.proto", fileDescriptor4) }
+
+var fileDescriptor4 = []byte{
+	// 500 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x54, 0x4d, 0x8f, 0xd3, 0x30,
+	0x10, 0x55, 0xba, 0xdd, 0x6c, 0x3b, 0x6d, 0xf9, 0x30, 0xa5, 0x58, 0x2b, 0x84, 0xa2, 0x88, 0x43,
+	0x4f, 0x1c, 0x40, 0xe2, 0xc4, 0x81, 0xa5, 0x8b, 0x04, 0x02, 0x84, 0x14, 0x7a, 0xe2, 0xe6, 0x3a,
+	0x83, 0x28, 0xbb, 0x8d, 0x83, 0xe3, 0xb2, 0xed, 0x99, 0x3f, 0xc3, 0xcf, 0x44, 0x1e, 0xc7, 0xf9,
+	0xda, 0x6d, 0x6f, 0x7e, 0xef, 0x8d, 0x67, 0x5e, 0xc6, 0x4f, 0x81, 0x51, 0x71, 0x23, 0xf2, 0xe2,
+	0x45, 0xae, 0x95, 0x51, 0xf1, 0xdf, 0x00, 0x46, 0x5f, 0x84, 0xbe, 0x42, 0xf3, 0x55, 0xa7, 0xa8,
+	0x19, 0x87, 0x33, 0x65, 0x0f, 0x1f, 0x2f, 0x79, 0x10, 0x05, 0xf3, 0x61, 0xe2, 0x21, 0x7b, 0x06,
+	0x50, 0xa0, 0xd4, 0x68, 0x3e, 0x88, 0xe2, 0x27, 0xef, 0x45, 0xc1, 0x7c, 0x9c, 0x34, 0x18, 0xab,
+	0x6b, 0x4c, 0x11, 0x37, 0xa4, 0x9f, 0x38, 0xbd, 0x66, 0xd8, 0x39, 0x0c, 0x7e, 0x6f, 0x45, 0x66,
+	0xd6, 0x66, 0xcf, 0xfb, 0x51, 0x30, 0xef, 0x27, 0x15, 0x8e, 0x2f, 0x01, 0xbe, 0xdd, 0x88, 0xfc,
+	0x42, 0x4a, 0xcc, 0x0d, 0x9b, 0x41, 0x68, 0x2d, 0x56, 0x16, 0x4a, 0xd4, 0x99, 0xd0, 0xeb, 0x4e,
+	0x88, 0xdf, 0xb8, 0x2e, 0x09, 0xfe, 0x42, 0x79, 0xb8, 0xcb, 0x0c, 0x42, 0x8d, 0xa2, 0x50, 0x19,
+	0x75, 0x18, 0x26, 0x25, 0x8a, 0x57, 0x30, 0xb6, 0xb7, 0x17, 0x2a, 0x33, 0x5a, 0x1c, 0xb9, 0x7f,
+	0x0e, 0x03, 0x59, 0xd6, 0x94, 0x1e, 0x2a, 0x6c, 0x1d, 0xfa, 0xf3, 0x72, 0xe7, 0x77, 0x50, 0x33,
+	0xf1, 0x5b, 0xef, 0xd0, 0x7a, 0x3e, 0x36, 0xc1, 0x7d, 0xd5, 0x72, 0xe7, 0x27, 0x78, 0x1c, 0xff,
+	0x0b, 0x7d, 0x0b, 0xa9, 0x74, 0x7a, 0xb0, 0x45, 0xe3, 0x19, 0x7b, 0xed, 0x67, 0x64, 0xd0, 0xd7,
+	0xea, 0x1a, 0xc9, 0xdc, 0x24, 0xa1, 0x33, 0x9b, 0xc2, 0x69, 0x61, 0x84, 0x41, 0x7a, 0x97, 0x49,
+	0xe2, 0x00, 0x8b, 0x61, 0x2c, 0xd5, 0x36, 0x33, 0xa8, 0x73, 0xa1, 0xcd, 0x9e, 0x9f, 0x52, 0xa3,
+	0x16, 0x67, 0xad, 0x16, 0x98, 0xa5, 0x0b, 0xb5, 0xce, 0x78, 0x48, 0x97, 0x2b, 0xcc, 0x22, 0x18,
+	0x69, 0x94, 0xb8, 0xfe, 0x83, 0x24, 0x9f, 0x91, 0xdc, 0xa4, 0x5c, 0xa4, 0xb2, 0xf4, 0x62, 0x63,
+	0x5b, 0xf2, 0x41, 0x14, 0xcc, 0x4f, 0x92, 0x06, 0xc3, 0x9e, 0xc3, 0xa4, 0x2c, 0x2f, 0x4b, 0x86,
+	0x54, 0xd2, 0x26, 0x69, 0x07, 0x14, 0x43, 0x0e, 0xb4, 0xac, 0x12, 0x75, 0x02, 0x3b, 0xba, 0x15,
+	0xd8, 0xa7, 0x30, 0x74, 0x6b, 0xfd, 0x84, 0x7b, 0x3e, 0x26, 0xb9, 0x26, 0x9c, 0xfa, 0x63, 0x9b,
+	0xa5, 0x56, 0x9d, 0x78, 0xb5, 0x24, 0xd8, 0x6b, 0x98, 0x35, 0xf7, 0x90, 0xd4, 0xb1, 0xbc, 0x47,
+	0xa5, 0x07, 0xd4, 0x56, 0x78, 0xee, 0x1f, 0x0d, 0xcf, 0x83, 0x6e, 0x78, 0xec, 0xdd, 0x6b, 0x25,
+	0xaf, 0x96, 0xeb, 0x0d, 0xf2, 0x87, 0xb4, 0x88, 0x0a, 0xb3, 0x97, 0x30, 0x6d, 0x4e, 0xf4, 0x21,
+	0xe6, 0x8c, 0xba, 0xdc, 0xa9, 0x75, 0xbf, 0x61, 0x51, 0xcf, 0x7e, 0x74, 0xfb, 0x1b, 0x6a, 0xb5,
+	0x3b, 0xeb, 0xb3, 0xf7, 0x34, 0x25, 0x4f, 0x77, 0x6a, 0xad, 0x48, 0x3f, 0x6e, 0x47, 0xda, 0x69,
+	0x76, 0xb1, 0xcb, 0x1d, 0x9f, 0x79, 0xcd, 0x61, 0x97, 0x21, 0x7b, 0x7e, 0xaf, 0xb5, 0xd2, 0xfc,
+	0x09, 0x45, 0xb0, 0x49, 0xd9, 0xa4, 0x4b, 0x8d, 0xc2, 0x60, 0xca, 0x39, 0x19, 0xf0, 0xf0, 0x5d,
+	0xff, 0x7b, 0x2f, 0x5f, 0xad, 0x42, 0xfa, 0xcf, 0xbd, 0xfa, 0x3f, 0x00, 0x0d, 0xc3, 0xac, 0x2d,
+	0xf6, 0x04, 0x00, 0x00,
+}
@@ -0,0 +1,133 @@
+package repo
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// CookieFileName holds the bearer token for the API. Anything that can read the
+	// data directory can use the API.
+	CookieFileName = ".cookie"
+
+	// The self-signed certificate and key the API uses when TLS is turned on.
+	TLSCertFileName = "api.cert"
+	TLSKeyFileName  = "api.key"
+)
+
+// How long a generated API certificate is good for.
+const tlsCertValidity = time.Hour * 24 * 365 * 10
+
+// APICookie returns the API bearer token, creating the cookie file the first time it's called.
+func (r *Repo) APICookie() (string, error) {
+	cookieLocation := path.Join(r.pth, CookieFileName)
+	if _, err := os.Stat(cookieLocation); os.IsNotExist(err) {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		token := hex.EncodeToString(b)
+		if err := ioutil.WriteFile(cookieLocation, []byte(token), 0600); err != nil {
+			return "", err
+		}
+		return token, nil
+	}
+	return ReadAPICookie(r.pth)
+}
+
+// ReadAPICookie reads the API bearer token from the data directory, or the default
+// data directory if pth is empty. It's used by clients of a running node.
+func ReadAPICookie(pth string) (string, error) {
+	pth, err := repoPath(pth)
+	if err != nil {
+		return "", err
+	}
+	b, err := ioutil.ReadFile(path.Join(pth, CookieFileName))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// ReadAPICert reads the API's PEM encoded TLS certificate from the data directory, or
+// the default data directory if pth is empty, so clients can trust it.
+func ReadAPICert(pth string) ([]byte, error) {
+	pth, err := repoPath(pth)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(path.Join(pth, TLSCertFileName))
+}
+
+// APICert returns the paths to the API's TLS certificate and key. If they don't exist
+// a self-signed certificate is generated for localhost and the given hosts.
+func (r *Repo) APICert(hosts ...string) (certFile, keyFile string, err error) {
+	certFile = path.Join(r.pth, TLSCertFileName)
+	keyFile = path.Join(r.pth, TLSKeyFileName)
+	_, certErr := os.Stat(certFile)
+	_, keyErr := os.Stat(keyFile)
+	if certErr == nil && keyErr == nil {
+		return certFile, keyFile, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", err
+	}
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"atomicswap"}, CommonName: "localhost"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(tlsCertValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")},
+	}
+	for _, h := range hosts {
+		if h == "" {
+			continue
+		}
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return "", "", err
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	if err != nil {
+		return "", "", err
+	}
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0600)
+	if err != nil {
+		return "", "", err
+	}
+	return certFile, keyFile, nil
+}
@@ -1,16 +1,158 @@
 package repo
 
 import (
+	"encoding/json"
+	"fmt"
+	"github.com/cpacia/atomicswap/chain"
 	iaddr "github.com/ipfs/go-ipfs-addr"
 	pstore "github.com/libp2p/go-libp2p-peerstore"
+	"io/ioutil"
+	"os"
+	"path"
 )
 
+const ConfigFileName = "config.json"
+
 var defaultBoostrapPeers = []string{
 	"/ip4/127.0.0.1/tcp/9000/ipfs/12D3KooWGqzvbKZJVhRRCv7mJ4KvKQ9TESLemSgDopzoL1YtdhsE",
 	"/ip4/127.0.0.1/tcp/9001/ipfs/12D3KooWNWb7URKbZwM6ZZGzjWYHGS97ofd5A8LoBw78jpmzzzN2",
 	"/ip4/127.0.0.1/tcp/9002/ipfs/12D3KooWFkzX4DRP9iMDEd4TrQfuNcrMXu8KzXk3NqQM9SgaLSKg",
 }
 
+// RPCConfig holds the connection settings for a full node's JSON-RPC server.
+type RPCConfig struct {
+	Host     string `json:"host"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// ChainConfig holds the full node used as the chain backend for each coin. A coin
+// with no host set has no backend and can't be swapped.
+type ChainConfig struct {
+	BTC RPCConfig `json:"btc"`
+	BCH RPCConfig `json:"bch"`
+}
+
+// DefaultAPIHost is the address the API listens on if none is set. Only processes on
+// this machine can reach it.
+const DefaultAPIHost = "127.0.0.1"
+
+// APIConfig holds the settings for the JSON and gRPC APIs. Requests must carry the
+// bearer token from the cookie file or, if a username is set, the username and password.
+type APIConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	GRPCPort int    `json:"grpcPort"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// TLS serves the API over TLS with a self-signed certificate generated in the data directory.
+	TLS bool `json:"tls"`
+}
+
+// FeeConfig is the fee policy for the transactions we make, in satoshis per byte.
+// The chain backend's estimate is used when it has one, kept between Min and Max;
+// Default is used when it doesn't. Zero leaves a bound off.
+type FeeConfig struct {
+	Default int64 `json:"default"`
+	Min     int64 `json:"min"`
+	Max     int64 `json:"max"`
+}
+
+// Policy returns the fee policy for the chain backends.
+func (c FeeConfig) Policy() chain.FeePolicy {
+	return chain.FeePolicy{
+		Default: c.Default,
+		Min:     c.Min,
+		Max:     c.Max,
+	}
+}
+
+// LimitsConfig holds the order book limits. See orderbook.Limits.
+type LimitsConfig struct {
+	MaxOrders        int    `json:"maxOrders"`
+	MaxOrdersPerPeer int    `json:"maxOrdersPerPeer"`
+	MinQuantity      uint64 `json:"minQuantity"`
+}
+
+// LoggingConfig sets the log level, one of CRITICAL, ERROR, WARNING, NOTICE, INFO or
+// DEBUG. Modules overrides the level for single loggers, such as "orderbook".
+type LoggingConfig struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules,omitempty"`
+}
+
+// Config is read from the config file in the data directory. Settings given on the
+// command line override it.
+type Config struct {
+	BootstrapPeers []string      `json:"bootstrapPeers"`
+	ListenAddrs    []string      `json:"listenAddrs"`
+	API            APIConfig     `json:"api"`
+	Chain          ChainConfig   `json:"chain"`
+	Fees           FeeConfig     `json:"fees"`
+	Limits         LimitsConfig  `json:"limits"`
+	Logging        LoggingConfig `json:"logging"`
+}
+
+// DefaultConfig returns the config written to a new data directory. There's no
+// default listen address or API port; they have to be set here or on the command line.
+func DefaultConfig() *Config {
+	return &Config{
+		BootstrapPeers: append([]string{}, defaultBoostrapPeers...),
+		ListenAddrs:    []string{},
+		API: APIConfig{
+			Host: DefaultAPIHost,
+		},
+		Fees: FeeConfig{
+			Default: 20,
+			Min:     1,
+			Max:     1000,
+		},
+		Limits: LimitsConfig{
+			MaxOrders:        10000,
+			MaxOrdersPerPeer: 50,
+			MinQuantity:      10000,
+		},
+		Logging: LoggingConfig{
+			Level: "DEBUG",
+		},
+	}
+}
+
+// LoadConfig reads the config file from the data directory, or the default data
+// directory if pth is empty. Settings missing from the file, or the whole file if
+// there isn't one, take their default values. Only the config file is read so it's
+// safe to use while a node has the repo open.
+func LoadConfig(pth string) (*Config, error) {
+	pth, err := repoPath(pth)
+	if err != nil {
+		return nil, err
+	}
+	cfg := DefaultConfig()
+	ser, err := ioutil.ReadFile(path.Join(pth, ConfigFileName))
+	if os.IsNotExist(err) {
+		return cfg, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(ser, cfg); err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", ConfigFileName, err)
+	}
+	return cfg, nil
+}
+
+// initConfig writes the default config file to the data directory if there isn't one.
+func initConfig(pth string) error {
+	configLocation := path.Join(pth, ConfigFileName)
+	if _, err := os.Stat(configLocation); !os.IsNotExist(err) {
+		return err
+	}
+	ser, err := json.MarshalIndent(DefaultConfig(), "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(configLocation, ser, 0600)
+}
+
 func ParseBootstrapPeer(addr string) (iaddr.IPFSAddr, error) {
 	ia, err := iaddr.ParseString(addr)
 	if err != nil {
@@ -27,6 +169,9 @@ func ParseBootstrapPeers(addrs []string) ([]pstore.PeerInfo, error) {
 			return nil, err
 		}
 		pi, err := pstore.InfoFromP2pAddr(ia.Multiaddr())
+		if err != nil {
+			return nil, err
+		}
 		peers = append(peers, *pi)
 	}
 	return peers, nil
@@ -0,0 +1,118 @@
+package repo
+
+import (
+	"github.com/cpacia/atomicswap/chain"
+	"github.com/cpacia/atomicswap/swap"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func writeConfig(t *testing.T, dir, config string) {
+	if err := ioutil.WriteFile(path.Join(dir, ConfigFileName), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomicswap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// No config file at all
+	cfg, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, DefaultConfig()) {
+		t.Errorf("got config %+v, want the default %+v", cfg, DefaultConfig())
+	}
+
+	// An empty one
+	writeConfig(t, dir, "{}")
+	cfg, err = LoadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, DefaultConfig()) {
+		t.Errorf("got config %+v, want the default %+v", cfg, DefaultConfig())
+	}
+	want := chain.FeePolicy{Default: 20, Min: 1, Max: 1000}
+	if p := cfg.Fees.Policy(); p != want {
+		t.Errorf("got fee policy %+v, want %+v", p, want)
+	}
+}
+
+func TestLoadConfigOverrides(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomicswap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeConfig(t, dir, `{
+    "listenAddrs": ["/ip4/0.0.0.0/tcp/4001"],
+    "api": {"port": 5000},
+    "chain": {
+        "btc": {"host": "127.0.0.1:8332", "user": "btcuser", "password": "btcpass"}
+    },
+    "fees": {"min": 15, "max": 0},
+    "limits": {"maxOrdersPerPeer": 5},
+    "logging": {"level": "INFO"}
+}`)
+	cfg, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := DefaultConfig()
+	want.ListenAddrs = []string{"/ip4/0.0.0.0/tcp/4001"}
+	want.API.Port = 5000
+	want.Chain.BTC = RPCConfig{Host: "127.0.0.1:8332", User: "btcuser", Password: "btcpass"}
+	want.Fees.Min = 15
+	want.Fees.Max = 0
+	want.Limits.MaxOrdersPerPeer = 5
+	want.Logging.Level = "INFO"
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("got config %+v, want %+v", cfg, want)
+	}
+
+	// The default fee rate is kept, the minimum raised and the maximum turned off
+	policy := cfg.Fees.Policy()
+	if want := (chain.FeePolicy{Default: 20, Min: 15, Max: 0}); policy != want {
+		t.Errorf("got fee policy %+v, want %+v", policy, want)
+	}
+	fee, err := chain.WithFeePolicy(chain.NewSimChain(swap.BTC), policy).EstimateFee()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee != 15 {
+		t.Errorf("got fee rate %d, want 15", fee)
+	}
+
+	// The repo picks up the RPC credentials
+	r, err := NewRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*r.ChainConfig(), want.Chain) {
+		t.Errorf("got chain config %+v, want %+v", *r.ChainConfig(), want.Chain)
+	}
+}
+
+func TestLoadConfigMalformed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomicswap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeConfig(t, dir, `{"fees": {"min": "fifteen"}}`)
+	if _, err := LoadConfig(dir); err == nil {
+		t.Error("expected an error loading a malformed config")
+	}
+}
@@ -17,6 +17,7 @@ type Repo struct {
 	dstore         ds.Batching
 	privKey        crypto.PrivKey
 	bootstrapPeers []pstore.PeerInfo
+	config         *Config
 }
 
 // Init a new repo. This will create the data directory and leveldb database if it doesn't exist.
@@ -52,7 +53,16 @@ func NewRepo(pth string) (*Repo, error) {
 		return nil, err
 	}
 
-	bootstrapPeers, err := ParseBootstrapPeers(defaultBoostrapPeers)
+	// Write out the default config file if there isn't one and load it
+	if err := initConfig(pth); err != nil {
+		return nil, err
+	}
+	config, err := LoadConfig(pth)
+	if err != nil {
+		return nil, err
+	}
+
+	bootstrapPeers, err := ParseBootstrapPeers(config.BootstrapPeers)
 	if err != nil {
 		return nil, err
 	}
@@ -62,6 +72,7 @@ func NewRepo(pth string) (*Repo, error) {
 		privKey:        privkey,
 		dstore:         dstore,
 		bootstrapPeers: bootstrapPeers,
+		config:         config,
 	}, nil
 }
 
@@ -81,6 +92,32 @@ func (r *Repo) BootstrapPeers() []pstore.PeerInfo {
 	return r.bootstrapPeers
 }
 
+// SetBootstrapPeers replaces the bootstrap peers from the config file.
+func (r *Repo) SetBootstrapPeers(addrs []string) error {
+	peers, err := ParseBootstrapPeers(addrs)
+	if err != nil {
+		return err
+	}
+	r.bootstrapPeers = peers
+	return nil
+}
+
+func (r *Repo) ChainConfig() *ChainConfig {
+	return &r.config.Chain
+}
+
+func (r *Repo) Config() *Config {
+	return r.config
+}
+
+// repoPath returns pth, or the default data directory if it's empty.
+func repoPath(pth string) (string, error) {
+	if pth == "" {
+		return defaultRepoPath()
+	}
+	return pth, nil
+}
+
 // Get the default data directory location
 func defaultRepoPath() (string, error) {
 	path := "~"
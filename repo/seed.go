@@ -0,0 +1,118 @@
+package repo
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+const SeedFileName = "wallet.seed"
+
+var (
+	ErrWrongPassword = errors.New("wrong wallet password")
+	ErrNoPassword    = errors.New("a wallet password is required")
+)
+
+// The mnemonic is encrypted with a key derived from the wallet password using scrypt.
+type encryptedSeed struct {
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Attempt to load and decrypt the wallet's BIP39 mnemonic. If the seed file doesn't exist
+// we'll create a new mnemonic and save it encrypted with the password. The bool returned is
+// true if a new mnemonic was created. The password can't be empty.
+func (r *Repo) Mnemonic(password string) (string, bool, error) {
+	if password == "" {
+		return "", false, ErrNoPassword
+	}
+	seedLocation := path.Join(r.pth, SeedFileName)
+	if _, err := os.Stat(seedLocation); os.IsNotExist(err) {
+		entropy, err := bip39.NewEntropy(256)
+		if err != nil {
+			return "", false, err
+		}
+		mnemonic, err := bip39.NewMnemonic(entropy)
+		if err != nil {
+			return "", false, err
+		}
+		es, err := encryptMnemonic(mnemonic, password)
+		if err != nil {
+			return "", false, err
+		}
+		ser, err := json.MarshalIndent(es, "", "    ")
+		if err != nil {
+			return "", false, err
+		}
+		if err := ioutil.WriteFile(seedLocation, ser, 0600); err != nil {
+			return "", false, err
+		}
+		return mnemonic, true, nil
+	}
+
+	ser, err := ioutil.ReadFile(seedLocation)
+	if err != nil {
+		return "", false, err
+	}
+	es := new(encryptedSeed)
+	if err := json.Unmarshal(ser, es); err != nil {
+		return "", false, err
+	}
+	mnemonic, err := decryptMnemonic(es, password)
+	if err != nil {
+		return "", false, err
+	}
+	return mnemonic, false, nil
+}
+
+func encryptMnemonic(mnemonic, password string) (*encryptedSeed, error) {
+	es := &encryptedSeed{
+		Salt:  make([]byte, 32),
+		Nonce: make([]byte, 24),
+	}
+	if _, err := rand.Read(es.Salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(es.Nonce); err != nil {
+		return nil, err
+	}
+	key, err := seedKey(password, es.Salt)
+	if err != nil {
+		return nil, err
+	}
+	var nonce [24]byte
+	copy(nonce[:], es.Nonce)
+	es.Ciphertext = secretbox.Seal(nil, []byte(mnemonic), &nonce, key)
+	return es, nil
+}
+
+func decryptMnemonic(es *encryptedSeed, password string) (string, error) {
+	key, err := seedKey(password, es.Salt)
+	if err != nil {
+		return "", err
+	}
+	var nonce [24]byte
+	copy(nonce[:], es.Nonce)
+	mnemonic, ok := secretbox.Open(nil, es.Ciphertext, &nonce, key)
+	if !ok {
+		return "", ErrWrongPassword
+	}
+	return string(mnemonic), nil
+}
+
+func seedKey(password string, salt []byte) (*[32]byte, error) {
+	k, err := scrypt.Key([]byte(password), salt, 32768, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	var key [32]byte
+	copy(key[:], k)
+	return &key, nil
+}
@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"github.com/tyler-smith/go-bip39"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestMnemonic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomicswap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	r, err := NewRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := r.Mnemonic(""); err != ErrNoPassword {
+		t.Errorf("empty password got error %v, want %v", err, ErrNoPassword)
+	}
+
+	mnemonic, created, err := r.Mnemonic("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Error("new mnemonic not reported as created")
+	}
+	if !bip39.IsMnemonicValid(mnemonic) {
+		t.Errorf("invalid mnemonic %q", mnemonic)
+	}
+
+	// The same password decrypts it
+	loaded, created, err := r.Mnemonic("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created {
+		t.Error("existing mnemonic reported as created")
+	}
+	if loaded != mnemonic {
+		t.Errorf("got mnemonic %q, want %q", loaded, mnemonic)
+	}
+
+	// A different one doesn't
+	if _, _, err := r.Mnemonic("hunter3"); err != ErrWrongPassword {
+		t.Errorf("wrong password got error %v, want %v", err, ErrWrongPassword)
+	}
+}
+
+func TestMnemonicTampered(t *testing.T) {
+	es, err := encryptMnemonic("abandon ability", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.Ciphertext[0] ^= 0xff
+	if _, err := decryptMnemonic(es, "password"); err != ErrWrongPassword {
+		t.Errorf("tampered seed got error %v, want %v", err, ErrWrongPassword)
+	}
+}
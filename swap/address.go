@@ -0,0 +1,122 @@
+package swap
+
+import (
+	"errors"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	bchchaincfg "github.com/gcash/bchd/chaincfg"
+	"github.com/gcash/bchutil"
+	"strings"
+)
+
+// Coin identifies one of the two chains we swap between.
+type Coin int
+
+const (
+	BTC Coin = iota
+	BCH
+)
+
+// Coins lists every coin we support.
+var Coins = []Coin{BTC, BCH}
+
+func (c Coin) String() string {
+	switch c {
+	case BTC:
+		return "BTC"
+	case BCH:
+		return "BCH"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseCoin returns the coin with the ticker symbol.
+func ParseCoin(s string) (Coin, error) {
+	for _, c := range Coins {
+		if strings.EqualFold(s, c.String()) {
+			return c, nil
+		}
+	}
+	return 0, ErrUnknownCoin
+}
+
+// Network selects which set of chain params to use for each coin.
+type Network int
+
+const (
+	MainNet Network = iota
+	TestNet
+	RegTest
+)
+
+func (n Network) String() string {
+	switch n {
+	case MainNet:
+		return "mainnet"
+	case TestNet:
+		return "testnet"
+	case RegTest:
+		return "regtest"
+	default:
+		return "unknown"
+	}
+}
+
+var ErrUnknownCoin = errors.New("unknown coin")
+
+// BTCParams returns the bitcoin chain params for the network.
+func (n Network) BTCParams() *chaincfg.Params {
+	switch n {
+	case TestNet:
+		return &chaincfg.TestNet3Params
+	case RegTest:
+		return &chaincfg.RegressionNetParams
+	default:
+		return &chaincfg.MainNetParams
+	}
+}
+
+// BCHParams returns the bitcoin cash chain params for the network.
+func (n Network) BCHParams() *bchchaincfg.Params {
+	switch n {
+	case TestNet:
+		return &bchchaincfg.TestNet3Params
+	case RegTest:
+		return &bchchaincfg.RegressionNetParams
+	default:
+		return &bchchaincfg.MainNetParams
+	}
+}
+
+// BTCContractAddress returns the P2SH address of the contract on the bitcoin network.
+func BTCContractAddress(contract []byte, params *chaincfg.Params) (*btcutil.AddressScriptHash, error) {
+	return btcutil.NewAddressScriptHash(contract, params)
+}
+
+// BCHContractAddress returns the P2SH (cashaddr) address of the contract on the
+// bitcoin cash network.
+func BCHContractAddress(contract []byte, params *bchchaincfg.Params) (*bchutil.AddressScriptHash, error) {
+	return bchutil.NewAddressScriptHash(contract, params)
+}
+
+// ContractAddress returns the encoded P2SH address of the contract for the given
+// coin and network.
+func ContractAddress(coin Coin, contract []byte, net Network) (string, error) {
+	switch coin {
+	case BTC:
+		addr, err := BTCContractAddress(contract, net.BTCParams())
+		if err != nil {
+			return "", err
+		}
+		return addr.EncodeAddress(), nil
+	case BCH:
+		addr, err := BCHContractAddress(contract, net.BCHParams())
+		if err != nil {
+			return "", err
+		}
+		return addr.EncodeAddress(), nil
+	default:
+		return "", ErrUnknownCoin
+	}
+}
@@ -0,0 +1,242 @@
+package swap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+)
+
+// SecretSize is the size in bytes of the secret preimage used in our contracts.
+const SecretSize = 32
+
+var ErrNotAtomicSwapContract = errors.New("script is not an atomic swap contract")
+
+// Contract holds the components of a hash-time-locked atomic swap contract.
+// The script itself is identical on BTC and BCH as both chains support the
+// same opcodes.
+type Contract struct {
+	// SecretHash is the sha256 hash of the secret. The recipient can claim the
+	// coins by revealing the preimage.
+	SecretHash [32]byte
+
+	// RecipientHash is the hash160 of the pubkey which can redeem the contract
+	// using the secret.
+	RecipientHash [20]byte
+
+	// RefundHash is the hash160 of the pubkey which can refund the contract
+	// after the locktime.
+	RefundHash [20]byte
+
+	// LockTime is the absolute unix timestamp after which the refund path
+	// becomes spendable.
+	LockTime int64
+}
+
+// Script builds the redeem script for the contract. The script looks like:
+//
+//	OP_IF
+//	    OP_SIZE <secret size> OP_EQUALVERIFY
+//	    OP_SHA256 <secret hash> OP_EQUALVERIFY
+//	    OP_DUP OP_HASH160 <recipient pubkey hash>
+//	OP_ELSE
+//	    <locktime> OP_CHECKLOCKTIMEVERIFY OP_DROP
+//	    OP_DUP OP_HASH160 <refund pubkey hash>
+//	OP_ENDIF
+//	OP_EQUALVERIFY
+//	OP_CHECKSIG
+func (c *Contract) Script() ([]byte, error) {
+	b := txscript.NewScriptBuilder()
+
+	b.AddOp(txscript.OP_IF)
+	{
+		// Require the secret to be exactly SecretSize bytes. Without this the
+		// initiator could use a secret which is too large to be redeemed on
+		// the other chain.
+		b.AddOp(txscript.OP_SIZE)
+		b.AddInt64(SecretSize)
+		b.AddOp(txscript.OP_EQUALVERIFY)
+
+		b.AddOp(txscript.OP_SHA256)
+		b.AddData(c.SecretHash[:])
+		b.AddOp(txscript.OP_EQUALVERIFY)
+
+		b.AddOp(txscript.OP_DUP)
+		b.AddOp(txscript.OP_HASH160)
+		b.AddData(c.RecipientHash[:])
+	}
+	b.AddOp(txscript.OP_ELSE)
+	{
+		b.AddInt64(c.LockTime)
+		b.AddOp(txscript.OP_CHECKLOCKTIMEVERIFY)
+		b.AddOp(txscript.OP_DROP)
+
+		b.AddOp(txscript.OP_DUP)
+		b.AddOp(txscript.OP_HASH160)
+		b.AddData(c.RefundHash[:])
+	}
+	b.AddOp(txscript.OP_ENDIF)
+	b.AddOp(txscript.OP_EQUALVERIFY)
+	b.AddOp(txscript.OP_CHECKSIG)
+
+	return b.Script()
+}
+
+// PkScript returns the P2SH output script which pays to the contract. This
+// is the same on both chains.
+func (c *Contract) PkScript() ([]byte, error) {
+	script, err := c.Script()
+	if err != nil {
+		return nil, err
+	}
+	return P2SHScript(script)
+}
+
+// P2SHScript returns the pay-to-script-hash output script for a redeem script.
+func P2SHScript(redeemScript []byte) ([]byte, error) {
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_HASH160).
+		AddData(btcutil.Hash160(redeemScript)).
+		AddOp(txscript.OP_EQUAL).
+		Script()
+}
+
+// ParseContract parses a counterparty's contract script back into its components
+// so that it can be audited before we fund our side of the swap. It will return
+// ErrNotAtomicSwapContract if the script does not exactly match our template.
+func ParseContract(script []byte) (*Contract, error) {
+	ops, err := parseScript(script)
+	if err != nil {
+		return nil, err
+	}
+	if len(ops) != 20 {
+		return nil, ErrNotAtomicSwapContract
+	}
+
+	expected := []struct {
+		index  int
+		opcode byte
+	}{
+		{0, txscript.OP_IF},
+		{1, txscript.OP_SIZE},
+		{3, txscript.OP_EQUALVERIFY},
+		{4, txscript.OP_SHA256},
+		{6, txscript.OP_EQUALVERIFY},
+		{7, txscript.OP_DUP},
+		{8, txscript.OP_HASH160},
+		{10, txscript.OP_ELSE},
+		{12, txscript.OP_CHECKLOCKTIMEVERIFY},
+		{13, txscript.OP_DROP},
+		{14, txscript.OP_DUP},
+		{15, txscript.OP_HASH160},
+		{17, txscript.OP_ENDIF},
+		{18, txscript.OP_EQUALVERIFY},
+		{19, txscript.OP_CHECKSIG},
+	}
+	for _, e := range expected {
+		if ops[e.index].opcode != e.opcode {
+			return nil, ErrNotAtomicSwapContract
+		}
+	}
+
+	secretSize, err := ops[2].number()
+	if err != nil || secretSize != SecretSize {
+		return nil, ErrNotAtomicSwapContract
+	}
+	if len(ops[5].data) != 32 || len(ops[9].data) != 20 || len(ops[16].data) != 20 {
+		return nil, ErrNotAtomicSwapContract
+	}
+	lockTime, err := ops[11].number()
+	if err != nil || lockTime <= 0 {
+		return nil, ErrNotAtomicSwapContract
+	}
+
+	c := &Contract{LockTime: lockTime}
+	copy(c.SecretHash[:], ops[5].data)
+	copy(c.RecipientHash[:], ops[9].data)
+	copy(c.RefundHash[:], ops[16].data)
+
+	// Finally make sure the script is canonical. If the counterparty used non-minimal
+	// pushes the script hash would not match the one we'd compute for these components.
+	canonical, err := c.Script()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(canonical, script) {
+		return nil, ErrNotAtomicSwapContract
+	}
+	return c, nil
+}
+
+type parsedOp struct {
+	opcode byte
+	data   []byte
+}
+
+// number decodes the op as a script number. It handles both the small integer
+// opcodes and minimally encoded data pushes of up to five bytes.
+func (op parsedOp) number() (int64, error) {
+	if op.opcode == txscript.OP_0 {
+		return 0, nil
+	}
+	if op.opcode >= txscript.OP_1 && op.opcode <= txscript.OP_16 {
+		return int64(op.opcode-txscript.OP_1) + 1, nil
+	}
+	if len(op.data) == 0 || len(op.data) > 5 {
+		return 0, errors.New("invalid script number")
+	}
+	var n int64
+	for i, b := range op.data {
+		n |= int64(b) << uint(8*i)
+	}
+	// The most significant bit of the last byte is the sign bit
+	if op.data[len(op.data)-1]&0x80 != 0 {
+		n &= ^(int64(0x80) << uint(8*(len(op.data)-1)))
+		n = -n
+	}
+	return n, nil
+}
+
+// parseScript splits a script into its opcodes and data pushes.
+func parseScript(script []byte) ([]parsedOp, error) {
+	var ops []parsedOp
+	for i := 0; i < len(script); {
+		op := parsedOp{opcode: script[i]}
+		i++
+
+		var size int
+		switch {
+		case op.opcode >= txscript.OP_DATA_1 && op.opcode <= txscript.OP_DATA_75:
+			size = int(op.opcode)
+		case op.opcode == txscript.OP_PUSHDATA1:
+			if i+1 > len(script) {
+				return nil, errors.New("malformed push")
+			}
+			size = int(script[i])
+			i++
+		case op.opcode == txscript.OP_PUSHDATA2:
+			if i+2 > len(script) {
+				return nil, errors.New("malformed push")
+			}
+			size = int(binary.LittleEndian.Uint16(script[i:]))
+			i += 2
+		case op.opcode == txscript.OP_PUSHDATA4:
+			if i+4 > len(script) {
+				return nil, errors.New("malformed push")
+			}
+			size = int(binary.LittleEndian.Uint32(script[i:]))
+			i += 4
+		default:
+			ops = append(ops, op)
+			continue
+		}
+		if size < 0 || i+size > len(script) {
+			return nil, errors.New("malformed push")
+		}
+		op.data = script[i : i+size]
+		i += size
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
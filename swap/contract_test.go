@@ -0,0 +1,120 @@
+package swap
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/btcsuite/btcd/txscript"
+	"testing"
+)
+
+func testContract() *Contract {
+	c := &Contract{LockTime: 1530000000}
+	for i := range c.SecretHash {
+		c.SecretHash[i] = byte(i)
+	}
+	for i := range c.RecipientHash {
+		c.RecipientHash[i] = 0xaa
+		c.RefundHash[i] = 0xbb
+	}
+	return c
+}
+
+func TestParseContract(t *testing.T) {
+	c := testContract()
+	script, err := c.Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseContract(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *parsed != *c {
+		t.Errorf("parsed contract %+v, want %+v", parsed, c)
+	}
+}
+
+func TestParseContractMalformed(t *testing.T) {
+	script, err := testContract().Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Swap the secret size for an empty OP_PUSHDATA1 push
+	emptyPush := append([]byte{}, script[:2]...)
+	emptyPush = append(emptyPush, txscript.OP_PUSHDATA1, 0x00)
+	emptyPush = append(emptyPush, script[3:]...)
+
+	// Swap the locktime for an empty OP_PUSHDATA1 push
+	lockTimeOffset := bytes.Index(script, []byte{txscript.OP_ELSE}) + 1
+	emptyLockTime := append([]byte{}, script[:lockTimeOffset]...)
+	emptyLockTime = append(emptyLockTime, txscript.OP_PUSHDATA1, 0x00)
+	emptyLockTime = append(emptyLockTime, script[lockTimeOffset+1+int(script[lockTimeOffset]):]...)
+
+	// Swap the recipient hash for an empty OP_0 push
+	hashOffset := bytes.Index(script, c20(0xaa)) - 1
+	emptyHash := append([]byte{}, script[:hashOffset]...)
+	emptyHash = append(emptyHash, txscript.OP_0)
+	emptyHash = append(emptyHash, script[hashOffset+21:]...)
+
+	// Non-minimal push of the secret hash
+	nonMinimal := append([]byte{}, script[:5]...)
+	nonMinimal = append(nonMinimal, txscript.OP_PUSHDATA1)
+	nonMinimal = append(nonMinimal, script[5:]...)
+
+	tests := map[string][]byte{
+		"empty":             {},
+		"nil":               nil,
+		"single opcode":     {txscript.OP_IF},
+		"empty number":      emptyPush,
+		"empty locktime":    emptyLockTime,
+		"empty hash":        emptyHash,
+		"non-minimal push":  nonMinimal,
+		"trailing opcode":   append(append([]byte{}, script...), txscript.OP_NOP),
+		"missing checksig":  script[:len(script)-1],
+		"pushdata1 no size": {txscript.OP_PUSHDATA1},
+		"pushdata2 no size": {txscript.OP_PUSHDATA2, 0x01},
+		"pushdata4 no size": {txscript.OP_PUSHDATA4, 0x01, 0x00, 0x00},
+		"pushdata4 huge":    {txscript.OP_PUSHDATA4, 0xff, 0xff, 0xff, 0xff, 0x00},
+		"push past end":     {txscript.OP_DATA_20, 0x01, 0x02},
+	}
+	for i := 1; i < len(script); i++ {
+		tests[fmt.Sprintf("truncated to %d bytes", i)] = script[:i]
+	}
+	for name, s := range tests {
+		if _, err := ParseContract(s); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParsedOpNumber(t *testing.T) {
+	tests := []struct {
+		op      parsedOp
+		want    int64
+		wantErr bool
+	}{
+		{parsedOp{opcode: txscript.OP_0}, 0, false},
+		{parsedOp{opcode: txscript.OP_16}, 16, false},
+		{parsedOp{opcode: txscript.OP_DATA_1, data: []byte{0x20}}, 32, false},
+		{parsedOp{opcode: txscript.OP_DATA_1, data: []byte{0x81}}, -1, false},
+		{parsedOp{opcode: txscript.OP_DATA_2, data: []byte{0xff, 0x00}}, 255, false},
+		{parsedOp{opcode: txscript.OP_PUSHDATA1, data: []byte{}}, 0, true},
+		{parsedOp{opcode: txscript.OP_PUSHDATA1}, 0, true},
+		{parsedOp{opcode: txscript.OP_DATA_6, data: make([]byte, 6)}, 0, true},
+	}
+	for i, test := range tests {
+		n, err := test.op.number()
+		if (err != nil) != test.wantErr {
+			t.Errorf("%d: got error %v, want error %v", i, err, test.wantErr)
+			continue
+		}
+		if n != test.want {
+			t.Errorf("%d: got %d, want %d", i, n, test.want)
+		}
+	}
+}
+
+func c20(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 20)
+}
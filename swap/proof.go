@@ -0,0 +1,97 @@
+package swap
+
+import (
+	"bytes"
+	"errors"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// utxoProofMagic is prepended to everything we sign to prove ownership of a
+// UTXO so the signature can't be replayed as a transaction signature.
+const utxoProofMagic = "atomicswap utxo proof:\n"
+
+var ErrInvalidUTXOProof = errors.New("invalid utxo proof")
+
+// OrderAmounts returns the BTC and BCH amounts, in satoshis, traded by an order.
+// Quantities are denominated in BTC satoshis and prices in BCH satoshis per whole BTC.
+func OrderAmounts(quantity, price uint64) (btcAmount, bchAmount int64) {
+	bch := new(big.Int).Mul(new(big.Int).SetUint64(quantity), new(big.Int).SetUint64(price))
+	bch.Div(bch, big.NewInt(btcutil.SatoshiPerBitcoin))
+	return int64(quantity), bch.Int64()
+}
+
+// MakerFunds returns the coin and amount the maker of an order sends in the swap.
+// A BuyBTC order means the maker sends BCH and receives BTC.
+func MakerFunds(buyBTC bool, quantity, price uint64) (Coin, int64) {
+	btcAmount, bchAmount := OrderAmounts(quantity, price)
+	if buyBTC {
+		return BCH, bchAmount
+	}
+	return BTC, btcAmount
+}
+
+// EncodeOutPoint serializes an outpoint as hash:index.
+func EncodeOutPoint(op wire.OutPoint) []byte {
+	return []byte(op.String())
+}
+
+// DecodeOutPoint parses an outpoint serialized with EncodeOutPoint.
+func DecodeOutPoint(b []byte) (wire.OutPoint, error) {
+	parts := strings.Split(string(b), ":")
+	if len(parts) != 2 {
+		return wire.OutPoint{}, errors.New("invalid outpoint")
+	}
+	hash, err := chainhash.NewHashFromStr(parts[0])
+	if err != nil {
+		return wire.OutPoint{}, err
+	}
+	index, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return wire.OutPoint{}, err
+	}
+	return *wire.NewOutPoint(hash, uint32(index)), nil
+}
+
+// utxoProofHash is the hash signed to prove the peer controls the outpoint.
+func utxoProofHash(peerID string, op wire.OutPoint) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(utxoProofMagic)
+	buf.WriteString(peerID)
+	buf.Write(EncodeOutPoint(op))
+	return chainhash.DoubleHashB(buf.Bytes())
+}
+
+// SignUTXO signs the outpoint and peer ID with the key controlling the output.
+// It returns a compact signature from which the pubkey can be recovered.
+func SignUTXO(peerID string, op wire.OutPoint, key *btcec.PrivateKey) ([]byte, error) {
+	return btcec.SignCompact(btcec.S256(), key, utxoProofHash(peerID, op), true)
+}
+
+// VerifyUTXO checks the signature was made by the key which the output script
+// pays to. Only P2PKH outputs are supported.
+func VerifyUTXO(peerID string, op wire.OutPoint, sig []byte, pkScript []byte) error {
+	pubkey, compressed, err := btcec.RecoverCompact(btcec.S256(), sig, utxoProofHash(peerID, op))
+	if err != nil {
+		return ErrInvalidUTXOProof
+	}
+	var ser []byte
+	if compressed {
+		ser = pubkey.SerializeCompressed()
+	} else {
+		ser = pubkey.SerializeUncompressed()
+	}
+	expected, err := P2PKHScript(btcutil.Hash160(ser))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(expected, pkScript) {
+		return ErrInvalidUTXOProof
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package swap
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/gcash/bchd/bchec"
+	bchtxscript "github.com/gcash/bchd/txscript"
+	bchwire "github.com/gcash/bchd/wire"
+)
+
+const (
+	// FeePerByte is the fee rate in satoshis per byte used for redeem and refund
+	// transactions.
+	FeePerByte = 20
+
+	// DustLimit is the smallest output we will create when spending a contract.
+	DustLimit = 546
+)
+
+var ErrSecretNotFound = errors.New("secret not found in transaction")
+
+// NewSecret generates a random secret and returns it along with its sha256 hash.
+func NewSecret() (secret [SecretSize]byte, secretHash [32]byte, err error) {
+	if _, err = rand.Read(secret[:]); err != nil {
+		return
+	}
+	secretHash = sha256.Sum256(secret[:])
+	return
+}
+
+// ContractOutput returns the index and output of the transaction which pays to
+// the contract's P2SH script.
+func ContractOutput(tx *wire.MsgTx, contract []byte) (uint32, *wire.TxOut, error) {
+	pkScript, err := P2SHScript(contract)
+	if err != nil {
+		return 0, nil, err
+	}
+	for i, out := range tx.TxOut {
+		if bytes.Equal(out.PkScript, pkScript) {
+			return uint32(i), out, nil
+		}
+	}
+	return 0, nil, errors.New("transaction does not pay to contract")
+}
+
+// RedeemSigScript returns the signature script which spends the contract using the secret:
+// <sig> <pubkey> <secret> OP_TRUE <contract>
+func RedeemSigScript(sig, pubkey, secret, contract []byte) ([]byte, error) {
+	b := txscript.NewScriptBuilder()
+	b.AddData(sig)
+	b.AddData(pubkey)
+	b.AddData(secret)
+	b.AddInt64(1)
+	b.AddData(contract)
+	return b.Script()
+}
+
+// RefundSigScript returns the signature script which spends the contract after the locktime:
+// <sig> <pubkey> OP_FALSE <contract>
+func RefundSigScript(sig, pubkey, contract []byte) ([]byte, error) {
+	b := txscript.NewScriptBuilder()
+	b.AddData(sig)
+	b.AddData(pubkey)
+	b.AddInt64(0)
+	b.AddData(contract)
+	return b.Script()
+}
+
+// ExtractSecret searches the inputs of a transaction redeeming a contract for
+// a push whose sha256 hash matches secretHash.
+func ExtractSecret(tx *wire.MsgTx, secretHash [32]byte) ([]byte, error) {
+	for _, in := range tx.TxIn {
+		ops, err := parseScript(in.SignatureScript)
+		if err != nil {
+			continue
+		}
+		for _, op := range ops {
+			if len(op.data) != SecretSize {
+				continue
+			}
+			if sha256.Sum256(op.data) == secretHash {
+				return op.data, nil
+			}
+		}
+	}
+	return nil, ErrSecretNotFound
+}
+
+// NewRedeemTx builds and signs a transaction which spends the contract output of
+// contractTx using the secret. The coins are sent to the P2PKH address of the
+// redeem key.
+func NewRedeemTx(coin Coin, contractTx *wire.MsgTx, contract, secret []byte, key *btcec.PrivateKey, feePerByte int64) (*wire.MsgTx, error) {
+	return spendContract(coin, contractTx, contract, key, feePerByte, 0, func(sig, pubkey []byte) ([]byte, error) {
+		return RedeemSigScript(sig, pubkey, secret, contract)
+	})
+}
+
+// NewRefundTx builds and signs a transaction which refunds the contract output of
+// contractTx back to the P2PKH address of the refund key. The transaction will not be
+// valid until the contract's locktime has passed.
+func NewRefundTx(coin Coin, contractTx *wire.MsgTx, contract []byte, key *btcec.PrivateKey, feePerByte int64) (*wire.MsgTx, error) {
+	c, err := ParseContract(contract)
+	if err != nil {
+		return nil, err
+	}
+	return spendContract(coin, contractTx, contract, key, feePerByte, uint32(c.LockTime), func(sig, pubkey []byte) ([]byte, error) {
+		return RefundSigScript(sig, pubkey, contract)
+	})
+}
+
+func spendContract(coin Coin, contractTx *wire.MsgTx, contract []byte, key *btcec.PrivateKey, feePerByte int64,
+	lockTime uint32, sigScript func(sig, pubkey []byte) ([]byte, error)) (*wire.MsgTx, error) {
+
+	idx, out, err := ContractOutput(contractTx, contract)
+	if err != nil {
+		return nil, err
+	}
+	pubkey := key.PubKey().SerializeCompressed()
+	payTo, err := P2PKHScript(btcutil.Hash160(pubkey))
+	if err != nil {
+		return nil, err
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.LockTime = lockTime
+	contractHash := contractTx.TxHash()
+	txIn := wire.NewTxIn(wire.NewOutPoint(&contractHash, idx), nil, nil)
+	if lockTime != 0 {
+		// The sequence must be non-final for the locktime to be enforced
+		txIn.Sequence = wire.MaxTxInSequenceNum - 1
+	}
+	tx.AddTxIn(txIn)
+	tx.AddTxOut(wire.NewTxOut(out.Value, payTo))
+
+	// We sign once to learn the size of the transaction, then deduct the fee
+	// from the output and sign again.
+	for i := 0; i < 2; i++ {
+		sig, err := signInput(coin, tx, 0, contract, out.Value, key)
+		if err != nil {
+			return nil, err
+		}
+		script, err := sigScript(sig, pubkey)
+		if err != nil {
+			return nil, err
+		}
+		tx.TxIn[0].SignatureScript = script
+		if i == 0 {
+			fee := int64(tx.SerializeSize()) * feePerByte
+			if out.Value-fee < DustLimit {
+				return nil, fmt.Errorf("contract value %d too small to cover fee %d", out.Value, fee)
+			}
+			tx.TxOut[0].Value = out.Value - fee
+		}
+	}
+	return tx, nil
+}
+
+// P2PKHScript returns the pay-to-pubkey-hash output script for the hash.
+func P2PKHScript(pubkeyHash []byte) ([]byte, error) {
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_DUP).
+		AddOp(txscript.OP_HASH160).
+		AddData(pubkeyHash).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+}
+
+// SignP2PKHInput signs the input spending a P2PKH output and sets its signature script.
+func SignP2PKHInput(coin Coin, tx *wire.MsgTx, idx int, pkScript []byte, amount int64, key *btcec.PrivateKey) error {
+	sig, err := signInput(coin, tx, idx, pkScript, amount, key)
+	if err != nil {
+		return err
+	}
+	sigScript, err := txscript.NewScriptBuilder().
+		AddData(sig).
+		AddData(key.PubKey().SerializeCompressed()).
+		Script()
+	if err != nil {
+		return err
+	}
+	tx.TxIn[idx].SignatureScript = sigScript
+	return nil
+}
+
+// signInput returns the signature for the input. Transactions on both chains serialize
+// the same, but bitcoin cash uses a different signature hash algorithm (BIP143 with
+// the fork ID) so we have to sign those with bchd.
+func signInput(coin Coin, tx *wire.MsgTx, idx int, subScript []byte, amount int64, key *btcec.PrivateKey) ([]byte, error) {
+	switch coin {
+	case BTC:
+		return txscript.RawTxInSignature(tx, idx, subScript, txscript.SigHashAll, key)
+	case BCH:
+		bchTx, err := toBCHTx(tx)
+		if err != nil {
+			return nil, err
+		}
+		bchKey, _ := bchec.PrivKeyFromBytes(bchec.S256(), key.Serialize())
+		return bchtxscript.RawTxInECDSASignature(bchTx, idx, subScript, bchtxscript.SigHashAll|bchtxscript.SigHashForkID, bchKey, amount)
+	default:
+		return nil, ErrUnknownCoin
+	}
+}
+
+// VerifyInput runs the input's signature script against the output script it spends
+// using the coin's script engine.
+func VerifyInput(coin Coin, tx *wire.MsgTx, idx int, pkScript []byte, amount int64) error {
+	switch coin {
+	case BTC:
+		vm, err := txscript.NewEngine(pkScript, tx, idx, txscript.StandardVerifyFlags, nil, nil, amount)
+		if err != nil {
+			return err
+		}
+		return vm.Execute()
+	case BCH:
+		bchTx, err := toBCHTx(tx)
+		if err != nil {
+			return err
+		}
+		vm, err := bchtxscript.NewEngine(pkScript, bchTx, idx, bchtxscript.StandardVerifyFlags, nil, nil, amount)
+		if err != nil {
+			return err
+		}
+		return vm.Execute()
+	default:
+		return ErrUnknownCoin
+	}
+}
+
+func toBCHTx(tx *wire.MsgTx) (*bchwire.MsgTx, error) {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	bchTx := new(bchwire.MsgTx)
+	if err := bchTx.Deserialize(&buf); err != nil {
+		return nil, err
+	}
+	return bchTx, nil
+}
@@ -0,0 +1,32 @@
+package swap
+
+import (
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Wallet is the interface the swap engine uses to hand out keys and fund
+// contracts on a single chain.
+type Wallet interface {
+	// NewKey returns a fresh private key controlled by the wallet. Its pubkey
+	// hash is used as the redeem or refund destination in our contracts.
+	NewKey() (*btcec.PrivateKey, error)
+
+	// FundContract builds and signs a transaction paying value to the given
+	// output script. The transaction is not broadcast, that's left to the chain
+	// backend once the swap has been journaled.
+	FundContract(pkScript []byte, value int64) (*wire.MsgTx, error)
+
+	// Unreserve frees the inputs of a transaction from FundContract which
+	// couldn't be broadcast so they can fund something else.
+	Unreserve(tx *wire.MsgTx)
+
+	// UTXO returns an unspent P2PKH output worth at least value along with the
+	// key which controls it. It's used to prove to other peers our orders are funded.
+	UTXO(value int64) (wire.OutPoint, *btcec.PrivateKey, error)
+
+	// LockUTXOs replaces the set of outputs FundContract and UTXO must leave
+	// alone. We lock the UTXOs backing our open orders so they're still unspent
+	// when a taker comes along.
+	LockUTXOs(ops []wire.OutPoint)
+}
@@ -0,0 +1,546 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/cpacia/atomicswap/chain"
+	"github.com/cpacia/atomicswap/pb"
+	"github.com/cpacia/atomicswap/swap"
+	"github.com/gcash/bchutil"
+	"github.com/golang/protobuf/proto"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/op/go-logging"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// LookAhead is the number of unused keys we derive and watch past the last
+	// used one on each chain. This is the BIP44 gap limit.
+	LookAhead = 20
+
+	externalChain = 0
+	internalChain = 1
+
+	// Estimated sizes used to calculate the fee when funding a contract.
+	txOverheadSize = 10
+	p2pkhInputSize = 148
+	outputSize     = 34
+)
+
+var log = logging.MustGetLogger("wallet")
+
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// Wallet is a BIP44 HD wallet for a single coin. It hands out keys for swap redeem
+// and refund destinations, tracks the UTXOs paying to its keys through the chain
+// backend and funds swap contracts from them.
+//
+// Both coins are derived from the same BIP39 seed. On mainnet they use their own
+// BIP44 coin types (0 for BTC and 145 for BCH). On the test networks both share
+// coin type 1 so BCH uses account 1 to keep the keys separate.
+type Wallet struct {
+	coin    swap.Coin
+	net     swap.Network
+	backend chain.ChainBackend
+	db      ds.Datastore
+
+	// chains holds the external and internal (change) chain keys of the account.
+	chains    [2]*hdkeychain.ExtendedKey
+	nextIndex [2]uint32
+	derived   [2]uint32
+
+	keys     map[string]keyPath
+	utxos    map[wire.OutPoint]*utxo
+	reserved map[wire.OutPoint]bool
+	// locked holds the UTXOs backing our open orders.
+	locked map[wire.OutPoint]bool
+
+	lock sync.Mutex
+}
+
+type keyPath struct {
+	chain uint32
+	index uint32
+}
+
+type utxo struct {
+	outpoint wire.OutPoint
+	value    int64
+	pkScript []byte
+	height   int32
+	sub      *chain.Subscription
+}
+
+// NewWallet derives the account for the coin from the seed and loads the wallet's
+// state from the datastore. Call Start to begin tracking UTXOs.
+func NewWallet(coin swap.Coin, net swap.Network, seed []byte, backend chain.ChainBackend, db ds.Datastore) (*Wallet, error) {
+	master, err := hdkeychain.NewMaster(seed, net.BTCParams())
+	if err != nil {
+		return nil, err
+	}
+	coinType, account := bip44Path(coin, net)
+	path := []uint32{
+		hdkeychain.HardenedKeyStart + 44,
+		hdkeychain.HardenedKeyStart + coinType,
+		hdkeychain.HardenedKeyStart + account,
+	}
+	key := master
+	for _, i := range path {
+		key, err = key.Child(i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	w := &Wallet{
+		coin:     coin,
+		net:      net,
+		backend:  backend,
+		db:       db,
+		keys:     make(map[string]keyPath),
+		utxos:    make(map[wire.OutPoint]*utxo),
+		reserved: make(map[wire.OutPoint]bool),
+		locked:   make(map[wire.OutPoint]bool),
+	}
+	for _, c := range []uint32{externalChain, internalChain} {
+		w.chains[c], err = key.Child(c)
+		if err != nil {
+			return nil, err
+		}
+		w.nextIndex[c], err = w.loadIndex(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := w.loadUTXOs(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func bip44Path(coin swap.Coin, net swap.Network) (coinType, account uint32) {
+	if net != swap.MainNet {
+		if coin == swap.BCH {
+			return 1, 1
+		}
+		return 1, 0
+	}
+	if coin == swap.BCH {
+		return 145, 0
+	}
+	return 0, 0
+}
+
+// Start watches our keys and UTXOs on the chain backend so the wallet picks up
+// payments to it and notices when its UTXOs are spent.
+func (w *Wallet) Start() error {
+	w.lock.Lock()
+	for _, c := range []uint32{externalChain, internalChain} {
+		if err := w.extend(c); err != nil {
+			w.lock.Unlock()
+			return err
+		}
+	}
+	var utxos []*utxo
+	for _, u := range w.utxos {
+		utxos = append(utxos, u)
+	}
+	w.lock.Unlock()
+
+	for _, u := range utxos {
+		if err := w.watchUTXO(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewKey returns the next unused key on the external chain.
+func (w *Wallet) NewKey() (*btcec.PrivateKey, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.nextKey(externalChain)
+}
+
+// NewAddress returns the P2PKH address of the next unused key on the external
+// chain. Use it to deposit coins into the wallet.
+func (w *Wallet) NewAddress() (string, error) {
+	key, err := w.NewKey()
+	if err != nil {
+		return "", err
+	}
+	hash := btcutil.Hash160(key.PubKey().SerializeCompressed())
+	switch w.coin {
+	case swap.BTC:
+		addr, err := btcutil.NewAddressPubKeyHash(hash, w.net.BTCParams())
+		if err != nil {
+			return "", err
+		}
+		return addr.EncodeAddress(), nil
+	case swap.BCH:
+		addr, err := bchutil.NewAddressPubKeyHash(hash, w.net.BCHParams())
+		if err != nil {
+			return "", err
+		}
+		return addr.EncodeAddress(), nil
+	default:
+		return "", swap.ErrUnknownCoin
+	}
+}
+
+// Balance returns the total value of our confirmed and unconfirmed UTXOs.
+func (w *Wallet) Balance() (confirmed, unconfirmed int64) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, u := range w.utxos {
+		if u.height > 0 {
+			confirmed += u.value
+		} else {
+			unconfirmed += u.value
+		}
+	}
+	return confirmed, unconfirmed
+}
+
+// FundContract builds and signs a transaction paying value to the contract's output
+// script. Coins are selected largest first with any change sent to the internal chain.
+// The inputs are reserved so they won't be selected again while the transaction is
+// waiting to be broadcast.
+func (w *Wallet) FundContract(pkScript []byte, value int64) (*wire.MsgTx, error) {
+	feePerByte, err := w.backend.EstimateFee()
+	if err != nil || feePerByte <= 0 {
+		feePerByte = swap.FeePerByte
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var candidates []*utxo
+	for op, u := range w.utxos {
+		if !w.reserved[op] && !w.locked[op] {
+			candidates = append(candidates, u)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].value > candidates[j].value })
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxOut(wire.NewTxOut(value, pkScript))
+
+	// We assume there will be a change output when sizing the fee. If there isn't
+	// the difference goes to the miners.
+	size := int64(txOverheadSize + outputSize*2)
+	var selected []*utxo
+	var total int64
+	for _, u := range candidates {
+		if total >= value+size*feePerByte {
+			break
+		}
+		op := u.outpoint
+		tx.AddTxIn(wire.NewTxIn(&op, nil, nil))
+		selected = append(selected, u)
+		total += u.value
+		size += p2pkhInputSize
+	}
+	fee := size * feePerByte
+	if total < value+fee {
+		return nil, ErrInsufficientFunds
+	}
+	if change := total - value - fee; change >= swap.DustLimit {
+		key, err := w.nextKey(internalChain)
+		if err != nil {
+			return nil, err
+		}
+		changeScript, err := swap.P2PKHScript(btcutil.Hash160(key.PubKey().SerializeCompressed()))
+		if err != nil {
+			return nil, err
+		}
+		tx.AddTxOut(wire.NewTxOut(change, changeScript))
+	}
+	for i, u := range selected {
+		key, err := w.keyForScript(u.pkScript)
+		if err != nil {
+			return nil, err
+		}
+		if err := swap.SignP2PKHInput(w.coin, tx, i, u.pkScript, u.value, key); err != nil {
+			return nil, err
+		}
+	}
+	for _, u := range selected {
+		w.reserved[u.outpoint] = true
+	}
+	return tx, nil
+}
+
+// Unreserve releases the inputs of a transaction from FundContract which
+// failed to broadcast.
+func (w *Wallet) Unreserve(tx *wire.MsgTx) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, txIn := range tx.TxIn {
+		delete(w.reserved, txIn.PreviousOutPoint)
+	}
+}
+
+// UTXO returns the smallest unreserved UTXO worth at least value along with its key.
+func (w *Wallet) UTXO(value int64) (wire.OutPoint, *btcec.PrivateKey, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	var best *utxo
+	for op, u := range w.utxos {
+		if w.reserved[op] || w.locked[op] || u.value < value {
+			continue
+		}
+		if best == nil || u.value < best.value {
+			best = u
+		}
+	}
+	if best == nil {
+		return wire.OutPoint{}, nil, ErrInsufficientFunds
+	}
+	key, err := w.keyForScript(best.pkScript)
+	if err != nil {
+		return wire.OutPoint{}, nil, err
+	}
+	return best.outpoint, key, nil
+}
+
+// LockUTXOs replaces the set of UTXOs FundContract and UTXO skip over.
+func (w *Wallet) LockUTXOs(ops []wire.OutPoint) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.locked = make(map[wire.OutPoint]bool)
+	for _, op := range ops {
+		w.locked[op] = true
+	}
+}
+
+// nextKey returns the next unused key on the chain and marks it used.
+// The caller must hold the lock.
+func (w *Wallet) nextKey(c uint32) (*btcec.PrivateKey, error) {
+	index := w.nextIndex[c]
+	key, err := w.deriveKey(keyPath{c, index})
+	if err != nil {
+		return nil, err
+	}
+	if err := w.setIndex(c, index+1); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (w *Wallet) deriveKey(path keyPath) (*btcec.PrivateKey, error) {
+	child, err := w.chains[path.chain].Child(path.index)
+	if err != nil {
+		return nil, err
+	}
+	return child.ECPrivKey()
+}
+
+func (w *Wallet) keyForScript(pkScript []byte) (*btcec.PrivateKey, error) {
+	path, ok := w.keys[string(pkScript)]
+	if !ok {
+		return nil, errors.New("no key for script")
+	}
+	return w.deriveKey(path)
+}
+
+// setIndex moves the next unused index on the chain forward, saves it and makes
+// sure we're watching LookAhead keys past it. The caller must hold the lock.
+func (w *Wallet) setIndex(c uint32, index uint32) error {
+	if index <= w.nextIndex[c] {
+		return nil
+	}
+	w.nextIndex[c] = index
+	if err := w.db.Put(w.indexKey(c), []byte(strconv.FormatUint(uint64(index), 10))); err != nil {
+		return err
+	}
+	return w.extend(c)
+}
+
+// extend derives and watches keys on the chain up to LookAhead past the next
+// unused index. Watching a script doesn't wait on the backend, the RPC backend
+// scans the utxo set for a batch of scripts at once in the background. The caller
+// must hold the lock.
+func (w *Wallet) extend(c uint32) error {
+	for ; w.derived[c] < w.nextIndex[c]+LookAhead; w.derived[c]++ {
+		path := keyPath{c, w.derived[c]}
+		key, err := w.deriveKey(path)
+		if err != nil {
+			return err
+		}
+		pkScript, err := swap.P2PKHScript(btcutil.Hash160(key.PubKey().SerializeCompressed()))
+		if err != nil {
+			return err
+		}
+		w.keys[string(pkScript)] = path
+		sub, err := w.backend.WatchScript(pkScript)
+		if err != nil {
+			return err
+		}
+		go w.handleNotifications(sub)
+	}
+	return nil
+}
+
+// watchUTXO watches for the UTXO being spent. The backend may have to look the
+// UTXO up so it's called without the lock.
+func (w *Wallet) watchUTXO(u *utxo) error {
+	sub, err := w.backend.WatchOutpoint(u.outpoint)
+	if err != nil {
+		return err
+	}
+	w.lock.Lock()
+	if _, ok := w.utxos[u.outpoint]; !ok {
+		// It was spent while we were looking it up
+		w.lock.Unlock()
+		sub.Close()
+		return nil
+	}
+	u.sub = sub
+	w.lock.Unlock()
+	go w.handleNotifications(sub)
+	return nil
+}
+
+func (w *Wallet) handleNotifications(sub *chain.Subscription) {
+	for n := range sub.C {
+		w.processTransaction(n.Tx, n.Height)
+	}
+}
+
+// processTransaction updates our UTXOs with a transaction which pays to one of
+// our keys or spends one of our UTXOs.
+func (w *Wallet) processTransaction(tx *wire.MsgTx, height int32) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for _, txIn := range tx.TxIn {
+		u, ok := w.utxos[txIn.PreviousOutPoint]
+		if !ok {
+			continue
+		}
+		if u.sub != nil {
+			u.sub.Close()
+		}
+		delete(w.utxos, u.outpoint)
+		delete(w.reserved, u.outpoint)
+		if err := w.db.Delete(w.utxoKey(u.outpoint)); err != nil {
+			log.Errorf("Error deleting %s utxo %s: %s", w.coin, u.outpoint, err)
+		}
+	}
+
+	txid := tx.TxHash()
+	for i, txOut := range tx.TxOut {
+		path, ok := w.keys[string(txOut.PkScript)]
+		if !ok {
+			continue
+		}
+		op := *wire.NewOutPoint(&txid, uint32(i))
+		u, ok := w.utxos[op]
+		if !ok {
+			u = &utxo{
+				outpoint: op,
+				value:    txOut.Value,
+				pkScript: txOut.PkScript,
+			}
+			w.utxos[op] = u
+			go func(u *utxo) {
+				if err := w.watchUTXO(u); err != nil {
+					log.Errorf("Error watching %s utxo %s: %s", w.coin, u.outpoint, err)
+				}
+			}(u)
+			log.Infof("Received %d satoshis to %s wallet in %s", txOut.Value, w.coin, txid.String())
+		}
+		if height > 0 {
+			u.height = height
+		}
+		if err := w.saveUTXO(u); err != nil {
+			log.Errorf("Error saving %s utxo %s: %s", w.coin, op, err)
+		}
+		if err := w.setIndex(path.chain, path.index+1); err != nil {
+			log.Errorf("Error updating %s wallet index: %s", w.coin, err)
+		}
+	}
+}
+
+func (w *Wallet) prefix() string {
+	return "/wallet/" + strings.ToLower(w.coin.String())
+}
+
+func (w *Wallet) indexKey(c uint32) ds.Key {
+	return ds.NewKey(fmt.Sprintf("%s/index/%d", w.prefix(), c))
+}
+
+func (w *Wallet) utxoKey(op wire.OutPoint) ds.Key {
+	return ds.NewKey(w.prefix() + "/utxos/" + op.String())
+}
+
+func (w *Wallet) loadIndex(c uint32) (uint32, error) {
+	val, err := w.db.Get(w.indexKey(c))
+	if err == ds.ErrNotFound {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	ser, ok := val.([]byte)
+	if !ok {
+		return 0, errors.New("invalid wallet index")
+	}
+	index, err := strconv.ParseUint(string(ser), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(index), nil
+}
+
+func (w *Wallet) saveUTXO(u *utxo) error {
+	ser, err := proto.Marshal(&pb.UTXORecord{
+		Outpoint: swap.EncodeOutPoint(u.outpoint),
+		Value:    u.value,
+		PkScript: u.pkScript,
+		Height:   u.height,
+	})
+	if err != nil {
+		return err
+	}
+	return w.db.Put(w.utxoKey(u.outpoint), ser)
+}
+
+func (w *Wallet) loadUTXOs() error {
+	results, err := w.db.Query(query.Query{Prefix: w.prefix() + "/utxos"})
+	if err != nil {
+		return err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		ser, ok := e.Value.([]byte)
+		if !ok {
+			continue
+		}
+		rec := new(pb.UTXORecord)
+		if err := proto.Unmarshal(ser, rec); err != nil {
+			return err
+		}
+		op, err := swap.DecodeOutPoint(rec.Outpoint)
+		if err != nil {
+			return err
+		}
+		w.utxos[op] = &utxo{
+			outpoint: op,
+			value:    rec.Value,
+			pkScript: rec.PkScript,
+			height:   rec.Height,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package wallet
+
+import (
+	"bytes"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/cpacia/atomicswap/chain"
+	"github.com/cpacia/atomicswap/swap"
+	ds "github.com/ipfs/go-datastore"
+	"testing"
+	"time"
+)
+
+func newTestWallet(t *testing.T) (*Wallet, *chain.SimChain) {
+	c := chain.NewSimChain(swap.BTC)
+	w, err := NewWallet(swap.BTC, swap.RegTest, bytes.Repeat([]byte{0x01}, 32), c, ds.NewMapDatastore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return w, c
+}
+
+// deposit pays value to a new key in the wallet and mines it.
+func deposit(t *testing.T, w *Wallet, c *chain.SimChain, value int64) {
+	key, err := w.NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkScript, err := swap.P2PKHScript(btcutil.Hash160(key.PubKey().SerializeCompressed()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirmed, _ := w.Balance()
+	c.Fund(pkScript, value)
+	c.MineBlocks(1)
+	waitFor(t, func() bool {
+		c, _ := w.Balance()
+		return c == confirmed+value
+	})
+}
+
+func waitFor(t *testing.T, f func() bool) {
+	for i := 0; i < 100; i++ {
+		if f() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for the wallet")
+}
+
+func TestFundContractReserves(t *testing.T) {
+	w, c := newTestWallet(t)
+	deposit(t, w, c, 1000000)
+
+	// The only UTXO is reserved for the first contract
+	tx, err := w.FundContract([]byte{0x51}, 500000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.FundContract([]byte{0x51}, 100000); err != ErrInsufficientFunds {
+		t.Errorf("funding with a reserved utxo got error %v, want %v", err, ErrInsufficientFunds)
+	}
+	if _, _, err := w.UTXO(100000); err != ErrInsufficientFunds {
+		t.Errorf("got reserved utxo with error %v, want %v", err, ErrInsufficientFunds)
+	}
+
+	// It's released if the contract can't be broadcast
+	w.Unreserve(tx)
+	tx, err = w.FundContract([]byte{0x51}, 500000)
+	if err != nil {
+		t.Fatalf("funding after unreserving: %s", err)
+	}
+
+	// Once it's broadcast the change can be spent
+	if err := c.Broadcast(tx); err != nil {
+		t.Fatal(err)
+	}
+	c.MineBlocks(1)
+	waitFor(t, func() bool {
+		confirmed, _ := w.Balance()
+		return confirmed > 0 && confirmed < 500000
+	})
+	if _, err := w.FundContract([]byte{0x51}, 100000); err != nil {
+		t.Errorf("funding from change: %s", err)
+	}
+}
+
+func TestLockedUTXOs(t *testing.T) {
+	w, c := newTestWallet(t)
+	deposit(t, w, c, 1000000)
+
+	op, _, err := w.UTXO(1000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.LockUTXOs([]wire.OutPoint{op})
+	if _, err := w.FundContract([]byte{0x51}, 100000); err != ErrInsufficientFunds {
+		t.Errorf("funding with a locked utxo got error %v, want %v", err, ErrInsufficientFunds)
+	}
+	w.LockUTXOs(nil)
+	if _, err := w.FundContract([]byte{0x51}, 100000); err != nil {
+		t.Errorf("funding after unlocking: %s", err)
+	}
+}